@@ -1,18 +1,59 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"opo_admin_server/internal/config"
 	httpapi "opo_admin_server/internal/http"
+	"opo_admin_server/internal/lifecycle"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/services"
+	"opo_admin_server/internal/tracing"
 )
 
+// jobRequeueTimeout es lo que se espera, una vez agotado SHUTDOWN_TIMEOUT, a que los trabajos
+// de la cola interrumpidos vuelvan a quedar pendientes
+const jobRequeueTimeout = time.Second
+
 func main() {
+	// Subcomando "config check": valida la configuración sin arrancar el servidor
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
 	// Cargar configuración
 	cfg := config.Load()
 
+	// Logs estructurados (JSON) con redacción de secretos
+	logging.Setup(cfg)
+
+	// Trazas (OpenTelemetry); los spans pendientes se envían al parar el servidor
+	shutdownTracing := tracing.Setup(cfg)
+
+	// SIGTERM (Cloud Run, docker stop) o Ctrl+C inician el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Iniciar scheduler de publicación programada de topics
+	lifecycle.Go("topic-scheduler", func() {
+		services.NewTopicScheduler(cfg).Start(ctx)
+	})
+
+	// Workers de la cola de trabajos (vectorización de documentos). Con la señal de parada dejan
+	// de reservar trabajos, pero los que están en curso siguen hasta el límite del apagado
+	jobQueue := services.NewJobQueue(cfg)
+	lifecycle.Go("job-queue", func() {
+		jobQueue.Start(ctx)
+	})
+
 	// Crear router
 	router := httpapi.NewRouter(cfg)
 
@@ -27,12 +68,74 @@ func main() {
 		MaxHeaderBytes: 1 << 20,         // 1MB para headers (suficiente para multipart)
 	}
 
-	log.Printf("🚀 Iniciando servidor de administración en puerto %s", cfg.Port)
-	log.Printf("📡 API Base Path: %s", cfg.APIBasePath)
-	log.Printf("🌐 CORS Origins: %v", cfg.CORSAllowedOrigins)
-	log.Printf("📦 Límite de request: 100MB (configurado en handlers)")
+	slog.Info("🚀 Iniciando servidor de administración", "port", cfg.Port)
+	slog.Info("📡 API Base Path", "path", cfg.APIBasePath)
+	slog.Info("🌐 CORS Origins", "origins", cfg.CORSAllowedOrigins)
+	slog.Info("📦 Límite de request: 100MB (configurado en handlers)")
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("❌ Error al iniciar servidor", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("🛑 Señal de parada recibida, apagando...", "timeout", cfg.ShutdownTimeout.String())
+
+	// /readyz responde 503 desde ahora para que no lleguen peticiones nuevas
+	lifecycle.BeginShutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Deja de aceptar conexiones y espera a las peticiones en curso (subidas, descargas de la BD...)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("⚠️ Quedaban peticiones en curso al agotar el límite, se cierran", "error", err)
+		server.Close()
+	}
+
+	// Espera a los trabajos en segundo plano (scheduler, cola de trabajos, emails...). Cada uno cierra su cliente de MongoDB al terminar
+	if err := lifecycle.Wait(shutdownCtx); err != nil {
+		// Límite agotado: los trabajos de la cola en curso se interrumpen y vuelven a quedar pendientes
+		slog.Warn("⚠️ Límite de apagado agotado, se interrumpen los trabajos de la cola en curso")
+		jobQueue.Abort()
+
+		requeueCtx, cancelRequeue := context.WithTimeout(context.Background(), jobRequeueTimeout)
+		defer cancelRequeue()
+		if err := lifecycle.Wait(requeueCtx); err != nil {
+			slog.Warn("⚠️ Trabajos en segundo plano sin terminar", "pending", lifecycle.Pending())
+		}
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("⚠️ No se pudieron enviar todas las trazas pendientes", "error", err)
+	}
+
+	slog.Info("👋 Servidor detenido")
+}
+
+// configCommand muestra la configuración efectiva con los secretos ocultos y termina con
+// código 1 si no es válida (útil antes de desplegar o en un initContainer)
+func configCommand(args []string) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "Uso: admin-server config check")
+		return 2
+	}
+
+	cfg, err := config.Parse()
+	out := config.NewRedactingWriter(os.Stdout, cfg.Secrets())
+	cfg.Print(out)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("❌ Error al iniciar servidor: %v", err)
+	if err != nil {
+		fmt.Fprintln(out)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(out, "❌ %s\n", line)
+		}
+		return 1
 	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "✅ Configuración válida")
+	return 0
 }
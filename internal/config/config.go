@@ -1,132 +1,275 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Entornos de ejecución (APP_ENV)
+const (
+	EnvDevelopment = "development"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// defaultJWTSecret solo es aceptable en desarrollo
+const defaultJWTSecret = "admin-secret-key"
+
+// Config es la configuración tipada del servidor. La etiqueta env indica la variable de entorno
+// (y la clave del fichero de configuración) de cada campo; secret marca los valores que se
+// ocultan al mostrarla
 type Config struct {
-	Port               string
-	APIBasePath        string
-	JWTSecret          string
-	CORSAllowedOrigins []string
-	DBURL              string
-	DBName             string
-	PineconeAPIKey     string
+	Environment        string   `env:"APP_ENV"` // development | staging | production
+	Port               string   `env:"PORT"`
+	APIBasePath        string   `env:"API_BASE_PATH"`
+	JWTSecret          string   `env:"JWT_SECRET" secret:"true"`
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS"`
+	DBURL              string   `env:"DB_URL" secret:"url"`
+	DBName             string   `env:"DB_NAME"`
+	PineconeAPIKey     string   `env:"PINECONE_API_KEY" secret:"true"`
 	// SMTP Configuration
-	SMTPHost           string
-	SMTPPort           string
-	SMTPUser           string
-	SMTPPassword       string
-	SMTPFrom           string
-	AppBaseURL         string // URL base de la aplicación para enlaces en emails
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT"`
+	SMTPUser     string `env:"SMTP_USER"`
+	SMTPPassword string `env:"SMTP_PASSWORD" secret:"true"`
+	SMTPFrom     string `env:"SMTP_FROM"`
+	AppBaseURL   string `env:"APP_BASE_URL"` // URL base de la aplicación para enlaces en emails
+	// Publicación programada de topics
+	TopicSchedulerInterval time.Duration `env:"TOPIC_SCHEDULER_INTERVAL"`
+	// Sesiones de administradores
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL"`  // Validez del token de acceso (JWT)
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL"` // Validez del refresh token (rotativo, guardado en servidor)
+	TOTPIssuer      string        `env:"TOTP_ISSUER"`       // Nombre mostrado en la app de autenticación (2FA)
+	// Protección contra fuerza bruta en el login
+	LoginMaxAttempts      int           `env:"LOGIN_MAX_ATTEMPTS"`        // Fallos por email antes de bloquear la cuenta
+	LoginMaxAttemptsPerIP int           `env:"LOGIN_MAX_ATTEMPTS_PER_IP"` // Fallos por IP antes de bloquear la IP
+	LoginLockoutDuration  time.Duration `env:"LOGIN_LOCKOUT_DURATION"`    // Duración del bloqueo temporal
+	LoginBackoffBase      time.Duration `env:"LOGIN_BACKOFF_BASE"`        // Espera tras el primer fallo (se duplica en cada fallo)
+	// Política de contraseñas de administradores
+	PasswordMinLength      int    `env:"PASSWORD_MIN_LENGTH"`       // Longitud mínima
+	PasswordMinClasses     int    `env:"PASSWORD_MIN_CLASSES"`      // Tipos de carácter distintos exigidos (1-4)
+	PasswordBreachListFile string `env:"PASSWORD_BREACH_LIST_FILE"` // Fichero local de contraseñas comunes (una por línea)
+	PasswordHistorySize    int    `env:"PASSWORD_HISTORY_SIZE"`     // Contraseñas anteriores que no se pueden reutilizar
+	PasswordHashAlgorithm  string `env:"PASSWORD_HASH_ALGORITHM"`   // bcrypt | argon2id
+	PasswordBcryptCost     int    `env:"PASSWORD_BCRYPT_COST"`      // Coste de bcrypt
+	// Inicio de sesión con OpenID Connect (SSO); desactivado si OIDCIssuerURL está vacío
+	OIDCIssuerURL           string   `env:"OIDC_ISSUER_URL"`                  // Issuer del proveedor (p. ej. https://accounts.google.com)
+	OIDCClientID            string   `env:"OIDC_CLIENT_ID"`                   // Client ID registrado en el proveedor
+	OIDCClientSecret        string   `env:"OIDC_CLIENT_SECRET" secret:"true"` // Client secret (opcional con PKCE en clientes públicos)
+	OIDCRedirectURL         string   `env:"OIDC_REDIRECT_URL"`                // URL de callback registrada en el proveedor
+	OIDCScopes              []string `env:"OIDC_SCOPES"`                      // Scopes solicitados
+	OIDCAllowedDomains      []string `env:"OIDC_ALLOWED_DOMAINS"`             // Dominios de email permitidos (vacío = cualquiera)
+	OIDCFrontendRedirectURL string   `env:"OIDC_FRONTEND_REDIRECT_URL"`       // URL del panel a la que se devuelven los tokens tras el login
+	// Logs estructurados
+	LogLevel  string `env:"LOG_LEVEL"`  // debug | info | warn | error (modificable en caliente)
+	LogFormat string `env:"LOG_FORMAT"` // json | text
+	// Métricas de Prometheus
+	MetricsToken string `env:"METRICS_TOKEN" secret:"true"` // Bearer token exigido en /metrics (vacío = sin autenticación)
+	// Trazas (OpenTelemetry)
+	OTelTracesExporter   string   `env:"OTEL_TRACES_EXPORTER"`                        // none | otlp | stdout
+	OTelExporterEndpoint string   `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`                 // URL base del colector OTLP/HTTP (se añade /v1/traces)
+	OTelExporterHeaders  []string `env:"OTEL_EXPORTER_OTLP_HEADERS" secret:"headers"` // Cabeceras "clave=valor" para el colector
+	OTelServiceName      string   `env:"OTEL_SERVICE_NAME"`                           // service.name de los spans
+	// Sondas de disponibilidad
+	ReadinessOptionalChecks []string      `env:"READYZ_OPTIONAL_CHECKS"` // Comprobaciones no críticas de /readyz: smtp, vectorstore
+	ReadinessTimeout        time.Duration `env:"READYZ_TIMEOUT"`         // Tiempo máximo de cada comprobación
+	// Apagado ordenado
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT"` // Espera máxima a peticiones y trabajos en curso tras SIGTERM
+	// Proxies inversos delante del servidor
+	TrustedProxyHops int `env:"TRUSTED_PROXY_HOPS"` // Proxies de confianza que añaden su entrada a X-Forwarded-For (0 = se ignora la cabecera)
+	// Cola de trabajos (vectorización de documentos)
+	JobWorkers      int           `env:"JOB_WORKERS"`                  // Trabajos en paralelo por instancia
+	JobMaxAttempts  int           `env:"JOB_MAX_ATTEMPTS"`             // Intentos antes de marcar un trabajo como fallido
+	JobRetryBackoff time.Duration `env:"JOB_RETRY_BACKOFF"`            // Espera antes del primer reintento (se duplica en cada intento)
+	JobPollInterval time.Duration `env:"JOB_POLL_INTERVAL"`            // Cada cuánto se buscan trabajos pendientes
+	OpenAIAPIKey    string        `env:"OPENAI_API_KEY" secret:"true"` // Clave de OpenAI si la petición no incluye openaiApiKey
+	// Vector store de los documentos de ia-works
+	VectorStore       string `env:"VECTOR_STORE"`            // pinecone | mongo (búsqueda exacta en MongoDB, para desarrollo y pruebas)
+	PineconeIndex     string `env:"PINECONE_INDEX"`          // Nombre del índice de Pinecone
+	PineconeIndexHost string `env:"PINECONE_INDEX_HOST"`     // Host de datos del índice (vacío = se consulta a la API de control)
+	VectorCollection  string `env:"VECTOR_STORE_COLLECTION"` // Colección de MongoDB del vector store mongo
+
+	// Origen de cada valor (env, fichero...) para config check
+	sources map[string]string
 }
 
+// Load carga la configuración (variables de entorno, secretos en ficheros y CONFIG_FILE) y
+// detiene el arranque si algún valor no es válido
 func Load() Config {
-	// Cargar archivo .env si existe
-	if err := godotenv.Load(); err != nil {
-		log.Println("No se encontró archivo .env, usando variables de entorno del sistema")
+	config, err := Parse()
+
+	// A partir de aquí ningún secreto de la configuración puede aparecer en los logs
+	log.SetOutput(NewRedactingWriter(os.Stderr, config.Secrets()))
+
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			slog.Error("❌ Configuración inválida", "error", line)
+		}
+		slog.Error("❌ Corrige la configuración (puedes comprobarla con: admin-server config check)")
+		os.Exit(1)
 	}
 
-	// Logs de debug para variables de entorno
-	log.Println("=== CONFIGURACIÓN ADMIN SERVER ===")
-	log.Printf("PORT: %s", os.Getenv("PORT"))
-	log.Printf("API_BASE_PATH: %s", os.Getenv("API_BASE_PATH"))
-	log.Printf("JWT_SECRET: %s", os.Getenv("JWT_SECRET"))
-	log.Printf("CORS_ALLOWED_ORIGINS: %s", os.Getenv("CORS_ALLOWED_ORIGINS"))
-	log.Printf("DB_NAME: %s", os.Getenv("DB_NAME"))
-	log.Printf("DB_URL: %s", os.Getenv("DB_URL"))
-	log.Printf("MONGO_URL: %s", os.Getenv("MONGO_URL"))
-	log.Println("=== FIN CONFIGURACIÓN ===")
-
-	// Log específico para MongoDB
-	log.Println("=== MONGO CONFIGURATION DEBUG ===")
-	log.Printf("🔍 DB_URL from env: %s", os.Getenv("DB_URL"))
-	log.Printf("🔍 MONGO_URL from env: %s", os.Getenv("MONGO_URL"))
-	log.Printf("🔍 DB_NAME from env: %s", os.Getenv("DB_NAME"))
-	log.Println("=== END MONGO DEBUG ===")
-
-	dbURL := getenv("DB_URL", "")
-	if dbURL == "" {
-		dbURL = getenv("MONGO_URL", "")
+	if config.JWTSecret == defaultJWTSecret {
+		slog.Warn("⚠️ JWT_SECRET no configurado, usando el secreto por defecto (solo válido en desarrollo)")
 	}
-	if dbURL == "" {
-		log.Fatal("No se encontró DB_URL ni MONGO_URL en las variables de entorno")
+
+	// Log de la configuración final
+	slog.Info("=== CONFIGURACIÓN FINAL ADMIN ===")
+	var buf strings.Builder
+	config.Print(&buf)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		slog.Info(line)
 	}
+	slog.Info("=== FIN CONFIGURACIÓN FINAL ===")
 
-	// Log final de la URL de MongoDB que se va a usar
-	log.Printf("🔗 MongoDB URL final que se usará: %s", dbURL)
+	return config
+}
+
+// Parse lee la configuración sin detener el arranque. Devuelve la configuración efectiva junto
+// con todos los errores de validación encontrados (nil si es válida)
+func Parse() (Config, error) {
+	// Cargar archivo .env si existe
+	if err := godotenv.Load(); err != nil {
+		slog.Info("No se encontró archivo .env, usando variables de entorno del sistema")
+	}
 
-	// Parsear CORS origins
-	corsOrigins := getenv("CORS_ALLOWED_ORIGINS", "http://localhost:8100,https://localhost:8100,capacitor://localhost,ionic://localhost")
+	src, err := newSource(os.Getenv(configFileEnvName))
+	if err != nil {
+		return Config{sources: map[string]string{}}, err
+	}
+	l := &loader{src: src, origins: map[string]string{}}
 
-	// Limpiar cualquier = al inicio (problema de Cloud Run)
-	corsOrigins = strings.TrimPrefix(corsOrigins, "=")
+	dbURL := l.str("DB_URL", "")
+	if dbURL == "" {
+		dbURL = l.str("MONGO_URL", "")
+		l.origins["DB_URL"] = l.origins["MONGO_URL"]
+	}
 
+	// Limpiar cualquier = al inicio de cada origen (problema de Cloud Run)
 	var allowedOrigins []string
-	for _, origin := range strings.Split(corsOrigins, ",") {
-		origin = strings.TrimSpace(origin)
-		// Limpiar cualquier = al inicio de cada origen
-		origin = strings.TrimPrefix(origin, "=")
-		if origin != "" {
+	for _, origin := range l.list("CORS_ALLOWED_ORIGINS", "http://localhost:8100,https://localhost:8100,capacitor://localhost,ionic://localhost") {
+		if origin = strings.TrimSpace(strings.TrimPrefix(origin, "=")); origin != "" {
 			allowedOrigins = append(allowedOrigins, origin)
 		}
 	}
 
-	// Log de debug para ver los orígenes parseados
-	log.Printf("🌐 CORS Origins parseados: %v", allowedOrigins)
-
+	// Sin APP_ENV se asume producción: un despliegue que lo olvide no expone los endpoints de
+	// prueba ni arranca con el secreto por defecto
+	rawEnvironment := l.str("APP_ENV", EnvProduction)
 	config := Config{
-		Port:               getenv("PORT", "8080"), // Puerto diferente para admin
-		APIBasePath:        getenv("API_BASE_PATH", "/api/v1"),
-		JWTSecret:          getenv("JWT_SECRET", "admin-secret-key"),
-		CORSAllowedOrigins: allowedOrigins,
-		DBURL:              dbURL,
-		DBName:             getenv("DB_NAME", "opo"),
-		PineconeAPIKey:     getenv("PINECONE_API_KEY", ""),
-		SMTPHost:           getenv("SMTP_HOST", ""),
-		SMTPPort:           getenv("SMTP_PORT", "587"),
-		SMTPUser:           getenv("SMTP_USER", ""),
-		SMTPPassword:       getenv("SMTP_PASSWORD", ""),
-		SMTPFrom:           getenv("SMTP_FROM", ""),
-		AppBaseURL:         getenv("APP_BASE_URL", "http://localhost:8080"),
+		Environment:             normalizeEnvironment(rawEnvironment),
+		Port:                    l.str("PORT", "8080"), // Puerto diferente para admin
+		APIBasePath:             l.str("API_BASE_PATH", "/api/v1"),
+		JWTSecret:               l.raw("JWT_SECRET", defaultJWTSecret),
+		CORSAllowedOrigins:      allowedOrigins,
+		DBURL:                   dbURL,
+		DBName:                  l.str("DB_NAME", "opo"),
+		PineconeAPIKey:          l.raw("PINECONE_API_KEY", ""),
+		SMTPHost:                l.str("SMTP_HOST", ""),
+		SMTPPort:                l.str("SMTP_PORT", "587"),
+		SMTPUser:                l.str("SMTP_USER", ""),
+		SMTPPassword:            l.raw("SMTP_PASSWORD", ""),
+		SMTPFrom:                l.str("SMTP_FROM", ""),
+		AppBaseURL:              l.str("APP_BASE_URL", "http://localhost:8080"),
+		TopicSchedulerInterval:  l.duration("TOPIC_SCHEDULER_INTERVAL", time.Minute),
+		AccessTokenTTL:          l.duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:         l.duration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		TOTPIssuer:              l.str("TOTP_ISSUER", "OPO Admin"),
+		LoginMaxAttempts:        l.int("LOGIN_MAX_ATTEMPTS", 5),
+		LoginMaxAttemptsPerIP:   l.int("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+		LoginLockoutDuration:    l.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginBackoffBase:        l.duration("LOGIN_BACKOFF_BASE", time.Second),
+		PasswordMinLength:       l.int("PASSWORD_MIN_LENGTH", 10),
+		PasswordMinClasses:      l.int("PASSWORD_MIN_CLASSES", 3),
+		PasswordBreachListFile:  l.str("PASSWORD_BREACH_LIST_FILE", "config/common-passwords.txt"),
+		PasswordHistorySize:     l.int("PASSWORD_HISTORY_SIZE", 5),
+		PasswordHashAlgorithm:   strings.ToLower(l.str("PASSWORD_HASH_ALGORITHM", "bcrypt")),
+		PasswordBcryptCost:      l.int("PASSWORD_BCRYPT_COST", 12),
+		OIDCIssuerURL:           strings.TrimSuffix(l.str("OIDC_ISSUER_URL", ""), "/"),
+		OIDCClientID:            l.str("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:        l.raw("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:         l.str("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:              l.list("OIDC_SCOPES", "openid,email,profile"),
+		OIDCAllowedDomains:      l.list("OIDC_ALLOWED_DOMAINS", ""),
+		OIDCFrontendRedirectURL: l.str("OIDC_FRONTEND_REDIRECT_URL", ""),
+		LogLevel:                strings.ToLower(l.str("LOG_LEVEL", "")),
+		LogFormat:               strings.ToLower(l.str("LOG_FORMAT", "json")),
+		MetricsToken:            l.raw("METRICS_TOKEN", ""),
+		OTelTracesExporter:      strings.ToLower(l.str("OTEL_TRACES_EXPORTER", "none")),
+		OTelExporterEndpoint:    l.str("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		OTelExporterHeaders:     l.list("OTEL_EXPORTER_OTLP_HEADERS", ""),
+		OTelServiceName:         l.str("OTEL_SERVICE_NAME", "opo-admin-server"),
+		ReadinessOptionalChecks: l.list("READYZ_OPTIONAL_CHECKS", ""),
+		ReadinessTimeout:        l.duration("READYZ_TIMEOUT", 3*time.Second),
+		ShutdownTimeout:         l.duration("SHUTDOWN_TIMEOUT", 8*time.Second),
+		TrustedProxyHops:        l.count("TRUSTED_PROXY_HOPS", 0),
+		JobWorkers:              l.int("JOB_WORKERS", 2),
+		JobMaxAttempts:          l.int("JOB_MAX_ATTEMPTS", 3),
+		JobRetryBackoff:         l.duration("JOB_RETRY_BACKOFF", 30*time.Second),
+		JobPollInterval:         l.duration("JOB_POLL_INTERVAL", 5*time.Second),
+		OpenAIAPIKey:            l.raw("OPENAI_API_KEY", ""),
+		VectorStore:             strings.ToLower(l.str("VECTOR_STORE", "pinecone")),
+		PineconeIndex:           l.str("PINECONE_INDEX", "admin-docs"),
+		PineconeIndexHost:       strings.TrimSuffix(l.str("PINECONE_INDEX_HOST", ""), "/"),
+		VectorCollection:        l.str("VECTOR_STORE_COLLECTION", "vectors"),
+		sources:                 l.origins,
 	}
 
-	// Log de la configuración final
-	log.Printf("=== CONFIGURACIÓN FINAL ADMIN ===")
-	log.Printf("Port: %s", config.Port)
-	log.Printf("APIBasePath: %s", config.APIBasePath)
-	log.Printf("JWTSecret: %s", config.JWTSecret)
-	log.Printf("CORSAllowedOrigins: %v", config.CORSAllowedOrigins)
-	log.Printf("DBURL: %s", config.DBURL)
-	log.Printf("DBName: %s", config.DBName)
-	if config.PineconeAPIKey != "" {
-		log.Printf("PineconeAPIKey: %s***", config.PineconeAPIKey[:min(10, len(config.PineconeAPIKey))])
-	} else {
-		log.Printf("PineconeAPIKey: (no configurado)")
+	// Valores que dependen de otros
+	if config.LogLevel == "" {
+		config.LogLevel = "info"
+		if config.IsDevelopment() {
+			config.LogLevel = "debug"
+		}
+		config.sources["LOG_LEVEL"] = originDerived
+	}
+	// La consola de Pinecone muestra el host sin esquema
+	if config.PineconeIndexHost != "" && !strings.Contains(config.PineconeIndexHost, "://") {
+		config.PineconeIndexHost = "https://" + config.PineconeIndexHost
+	}
+	if config.OIDCRedirectURL == "" {
+		config.OIDCRedirectURL = strings.TrimSuffix(config.AppBaseURL, "/") + config.APIBasePath + "/auth/oidc/callback"
+		config.sources["OIDC_REDIRECT_URL"] = originDerived
 	}
-	log.Printf("SMTPHost: %s", config.SMTPHost)
-	log.Printf("SMTPPort: %s", config.SMTPPort)
-	log.Printf("SMTPFrom: %s", config.SMTPFrom)
-	log.Printf("AppBaseURL: %s", config.AppBaseURL)
-	log.Println("=== FIN CONFIGURACIÓN FINAL ===")
 
-	return config
+	errs := l.errs
+	if config.Environment == "" {
+		errs = append(errs, fmt.Errorf("APP_ENV: valor no válido %q (usa development, staging o production)", rawEnvironment))
+	}
+	for _, key := range src.unknownKeys() {
+		errs = append(errs, fmt.Errorf("%s: opción desconocida %s en %s", configFileEnvName, key, src.fileName))
+	}
+	errs = append(errs, config.validate()...)
+
+	return config, errors.Join(errs...)
 }
 
-func getenv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return def
+// IsDevelopment indica si están permitidos los endpoints de prueba y los logs de depuración
+func (c Config) IsDevelopment() bool {
+	return c.Environment == EnvDevelopment
+}
+
+// IsProduction indica si el servidor se ejecuta en producción
+func (c Config) IsProduction() bool {
+	return c.Environment == EnvProduction
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// normalizeEnvironment acepta los alias habituales (dev, prod...); devuelve "" si no es válido
+func normalizeEnvironment(env string) string {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "development", "dev", "local":
+		return EnvDevelopment
+	case "staging", "stage", "preproduction", "pre":
+		return EnvStaging
+	case "production", "prod":
+		return EnvProduction
 	}
-	return b
+	return ""
 }
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Print escribe la configuración efectiva, una opción por línea con su origen (env, fichero,
+// por defecto...). Los secretos se muestran enmascarados
+func (c Config) Print(w io.Writer) {
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+
+		value := formatValue(v.Field(i).Interface(), field.Tag.Get("secret"))
+		if origin := c.sources[key]; origin != "" {
+			value += " (" + origin + ")"
+		}
+		fmt.Fprintf(w, "%-28s %s\n", key, value)
+	}
+}
+
+func formatValue(value interface{}, secret string) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return "(no configurado)"
+		}
+		switch secret {
+		case "true":
+			return RedactSecret(v)
+		case "url":
+			return RedactURL(v)
+		}
+		return v
+	case []string:
+		if len(v) == 0 {
+			return "(no configurado)"
+		}
+		if secret == "headers" {
+			masked := make([]string, 0, len(v))
+			for _, header := range v {
+				name, _, _ := strings.Cut(header, "=")
+				masked = append(masked, name+"=****")
+			}
+			v = masked
+		}
+		return strings.Join(v, ",")
+	case time.Duration:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
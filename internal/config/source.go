@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Orígenes de cada valor, en orden de prioridad
+const (
+	originEnv         = "env"
+	originEnvFile     = "fichero de secreto"
+	originConfigFile  = "fichero de configuración"
+	originDefault     = "por defecto"
+	originDerived     = "calculado"
+	secretFileSuffix  = "_FILE"
+	configFileEnvName = "CONFIG_FILE"
+)
+
+// source resuelve cada clave (con el nombre de la variable de entorno) consultando, por orden:
+// la variable de entorno, la variable <CLAVE>_FILE (secretos de Docker/Kubernetes montados
+// como fichero), el fichero de configuración YAML/TOML y, en él, <clave>_file
+type source struct {
+	file     map[string]string // Claves del fichero ya aplanadas: smtp.password -> SMTP_PASSWORD
+	fileName string
+}
+
+// newSource lee el fichero de configuración indicado (vacío = solo variables de entorno)
+func newSource(path string) (*source, error) {
+	s := &source{file: map[string]string{}, fileName: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: no se puede leer %q: %w", configFileEnvName, path, err)
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+	default:
+		return nil, fmt.Errorf("%s: formato no soportado %q (usa .yaml, .yml o .toml)", configFileEnvName, filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q no es válido: %w", configFileEnvName, path, err)
+	}
+
+	flatten("", raw, s.file)
+	return s, nil
+}
+
+// flatten convierte las secciones anidadas en claves con el formato de las variables de
+// entorno ({smtp: {password: x}} -> SMTP_PASSWORD) y las listas en valores separados por comas
+func flatten(prefix string, values map[string]interface{}, out map[string]string) {
+	for key, value := range values {
+		key = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, out)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// lookup devuelve el valor de la clave y su origen; ok es false si no está definida en ningún sitio
+func (s *source) lookup(key string) (value, origin string, ok bool, err error) {
+	if v := os.Getenv(key); v != "" {
+		return v, originEnv, true, nil
+	}
+	if path := os.Getenv(key + secretFileSuffix); path != "" {
+		v, err := readSecretFile(key, path)
+		return v, originEnvFile, err == nil, err
+	}
+	if v, found := s.file[key]; found && v != "" {
+		return v, originConfigFile, true, nil
+	}
+	if path := s.file[key+secretFileSuffix]; path != "" {
+		v, err := readSecretFile(key, path)
+		return v, originConfigFile, err == nil, err
+	}
+	return "", "", false, nil
+}
+
+// readSecretFile lee un secreto montado como fichero, sin el salto de línea final
+func readSecretFile(key, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s%s: no se puede leer %q: %w", key, secretFileSuffix, path, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// unknownKeys devuelve las claves del fichero que no corresponden a ninguna opción (erratas)
+func (s *source) unknownKeys() []string {
+	known := map[string]bool{"MONGO_URL": true}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("env"); key != "" {
+			known[key] = true
+		}
+	}
+
+	var unknown []string
+	for key := range s.file {
+		if !known[key] && !known[strings.TrimSuffix(key, secretFileSuffix)] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// loader lee valores tipados de un source y acumula los errores para mostrarlos todos juntos
+type loader struct {
+	src     *source
+	origins map[string]string
+	errs    []error
+}
+
+func (l *loader) raw(key, def string) string {
+	v, origin, ok, err := l.src.lookup(key)
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+	if !ok {
+		if def != "" {
+			l.origins[key] = originDefault
+		}
+		return def
+	}
+	l.origins[key] = origin
+	return v
+}
+
+func (l *loader) str(key, def string) string {
+	return strings.TrimSpace(l.raw(key, def))
+}
+
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	v := l.str(key, "")
+	if v == "" {
+		l.origins[key] = originDefault
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		l.errs = append(l.errs, fmt.Errorf("%s: duración no válida %q (ejemplos: 30s, 15m, 720h)", key, v))
+		return def
+	}
+	return d
+}
+
+func (l *loader) int(key string, def int) int {
+	v := l.str(key, "")
+	if v == "" {
+		l.origins[key] = originDefault
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		l.errs = append(l.errs, fmt.Errorf("%s: debe ser un número entero positivo (valor %q)", key, v))
+		return def
+	}
+	return n
+}
+
+// count es como int pero admite 0
+func (l *loader) count(key string, def int) int {
+	v := l.str(key, "")
+	if v == "" {
+		l.origins[key] = originDefault
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		l.errs = append(l.errs, fmt.Errorf("%s: debe ser un número entero mayor o igual que 0 (valor %q)", key, v))
+		return def
+	}
+	return n
+}
+
+func (l *loader) list(key, def string) []string {
+	var values []string
+	for _, v := range strings.Split(l.raw(key, def), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Longitud mínima recomendada para JWT_SECRET (HS256 usa claves de 256 bits)
+const minJWTSecretLength = 32
+
+// validate comprueba los valores ya leídos y devuelve todos los problemas encontrados
+func (c Config) validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.DBURL == "" {
+		fail("DB_URL", "obligatorio (o MONGO_URL)")
+	} else if !strings.HasPrefix(c.DBURL, "mongodb://") && !strings.HasPrefix(c.DBURL, "mongodb+srv://") {
+		fail("DB_URL", "debe empezar por mongodb:// o mongodb+srv://")
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("PORT", "puerto no válido %q", c.Port)
+	}
+	if !strings.HasPrefix(c.APIBasePath, "/") {
+		fail("API_BASE_PATH", "debe empezar por / (valor %q)", c.APIBasePath)
+	}
+	if err := checkHTTPURL(c.AppBaseURL); err != nil {
+		fail("APP_BASE_URL", "%v", err)
+	}
+
+	// El secreto por defecto es público: solo se admite en desarrollo
+	if c.Environment != "" && !c.IsDevelopment() {
+		if c.JWTSecret == defaultJWTSecret {
+			fail("JWT_SECRET", "obligatorio en %s (no se puede usar el secreto por defecto; en local define APP_ENV=development)", c.Environment)
+		} else if len(c.JWTSecret) < minJWTSecretLength {
+			fail("JWT_SECRET", "demasiado corto para %s (mínimo %d caracteres)", c.Environment, minJWTSecretLength)
+		}
+	}
+
+	if c.SMTPPort != "" {
+		if port, err := strconv.Atoi(c.SMTPPort); err != nil || port < 1 || port > 65535 {
+			fail("SMTP_PORT", "puerto no válido %q", c.SMTPPort)
+		}
+	}
+
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		fail("REFRESH_TOKEN_TTL", "debe ser mayor que ACCESS_TOKEN_TTL (%s)", c.AccessTokenTTL)
+	}
+
+	if c.PasswordMinClasses > 4 {
+		fail("PASSWORD_MIN_CLASSES", "debe estar entre 1 y 4 (valor %d)", c.PasswordMinClasses)
+	}
+	if c.PasswordHashAlgorithm != "bcrypt" && c.PasswordHashAlgorithm != "argon2id" {
+		fail("PASSWORD_HASH_ALGORITHM", "valor no válido %q (usa bcrypt o argon2id)", c.PasswordHashAlgorithm)
+	}
+	if c.PasswordBcryptCost < 10 || c.PasswordBcryptCost > 31 {
+		fail("PASSWORD_BCRYPT_COST", "debe estar entre 10 y 31 (valor %d)", c.PasswordBcryptCost)
+	}
+
+	if c.OIDCIssuerURL != "" {
+		if err := checkHTTPURL(c.OIDCIssuerURL); err != nil {
+			fail("OIDC_ISSUER_URL", "%v", err)
+		} else if c.IsProduction() && !strings.HasPrefix(c.OIDCIssuerURL, "https://") {
+			fail("OIDC_ISSUER_URL", "debe usar https en producción")
+		}
+		if c.OIDCClientID == "" {
+			fail("OIDC_CLIENT_ID", "obligatorio si OIDC_ISSUER_URL está configurado")
+		}
+		if err := checkHTTPURL(c.OIDCRedirectURL); err != nil {
+			fail("OIDC_REDIRECT_URL", "%v", err)
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		fail("LOG_LEVEL", "valor no válido %q (usa debug, info, warn o error)", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		fail("LOG_FORMAT", "valor no válido %q (usa json o text)", c.LogFormat)
+	}
+
+	switch c.OTelTracesExporter {
+	case "none", "stdout", "console":
+	case "otlp":
+		if err := checkHTTPURL(c.OTelExporterEndpoint); err != nil {
+			fail("OTEL_EXPORTER_OTLP_ENDPOINT", "%v", err)
+		}
+	default:
+		fail("OTEL_TRACES_EXPORTER", "valor no válido %q (usa none, otlp o stdout)", c.OTelTracesExporter)
+	}
+	for _, header := range c.OTelExporterHeaders {
+		if name, _, ok := strings.Cut(header, "="); !ok || strings.TrimSpace(name) == "" {
+			fail("OTEL_EXPORTER_OTLP_HEADERS", "cada cabecera debe tener el formato clave=valor")
+			break
+		}
+	}
+
+	for _, check := range c.ReadinessOptionalChecks {
+		switch strings.ToLower(check) {
+		case "smtp", "vectorstore", "pinecone":
+		default:
+			fail("READYZ_OPTIONAL_CHECKS", "comprobación desconocida %q (usa smtp o vectorstore)", check)
+		}
+	}
+
+	switch c.VectorStore {
+	case "pinecone":
+		if c.PineconeIndex == "" {
+			fail("PINECONE_INDEX", "obligatorio con VECTOR_STORE=pinecone")
+		}
+		if c.PineconeIndexHost != "" {
+			if err := checkHTTPURL(c.PineconeIndexHost); err != nil {
+				fail("PINECONE_INDEX_HOST", "%v", err)
+			}
+		}
+	case "mongo":
+		if c.VectorCollection == "" {
+			fail("VECTOR_STORE_COLLECTION", "obligatorio con VECTOR_STORE=mongo")
+		}
+	default:
+		fail("VECTOR_STORE", "valor no válido %q (usa pinecone o mongo)", c.VectorStore)
+	}
+
+	if c.TrustedProxyHops > 10 {
+		fail("TRUSTED_PROXY_HOPS", "máximo 10 (valor %d)", c.TrustedProxyHops)
+	}
+
+	if c.JobWorkers > 32 {
+		fail("JOB_WORKERS", "máximo 32 (valor %d)", c.JobWorkers)
+	}
+
+	return errs
+}
+
+// checkHTTPURL exige una URL absoluta http(s)
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL no válida %q (debe ser http:// o https://)", raw)
+	}
+	return nil
+}
@@ -15,16 +15,21 @@ type EmbeddingConfig struct {
 
 // Document representa un documento procesado
 type Document struct {
-	ID        string    `json:"id" bson:"_id"`
-	FileName  string    `json:"fileName" bson:"fileName"`
-	FileType  string    `json:"fileType" bson:"fileType"`
-	Text      string    `json:"text" bson:"text"`
-	Status    string    `json:"status" bson:"status"` // "uploaded", "processed", "error"
-	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	ID          string    `json:"id" bson:"_id"`
+	FileName    string    `json:"fileName" bson:"fileName"`
+	FileType    string    `json:"fileType" bson:"fileType"`
+	Text        string    `json:"text,omitempty" bson:"text"`
+	TextLength  int       `json:"textLength" bson:"textLength,omitempty"`             // Caracteres del texto extraído
+	FileSize    int64     `json:"fileSize,omitempty" bson:"fileSize,omitempty"`       // Tamaño del archivo original (guardado en GridFS)
+	Status      string    `json:"status" bson:"status"`                               // "uploaded", "processing", "processed", "error", "deleting"
+	JobID       string    `json:"jobId,omitempty" bson:"jobId,omitempty"`             // Último trabajo de vectorización
+	ChunksCount int       `json:"chunksCount,omitempty" bson:"chunksCount,omitempty"` // Chunks guardados en el vector store
+	Error       string    `json:"error,omitempty" bson:"error,omitempty"`             // Motivo del último fallo
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
 }
 
-// Vector representa un vector almacenado en Pinecone
+// Vector representa un vector almacenado en el vector store
 type Vector struct {
 	ID        string                 `json:"id" bson:"id"`
 	Values    []float32              `json:"values" bson:"values"`
@@ -32,6 +37,7 @@ type Vector struct {
 	DocumentID string                `json:"documentId" bson:"documentId"`
 	ChunkIndex int                   `json:"chunkIndex" bson:"chunkIndex"`
 	Text      string                 `json:"text" bson:"text"`
+	Score     float64                `json:"score,omitempty" bson:"-"` // Similitud con el vector de la consulta
 }
 
 // ProcessVectorRequest representa la solicitud para procesar un documento a vectores
@@ -51,8 +57,51 @@ type UploadFileResponse struct {
 
 // ProcessVectorResponse representa la respuesta al procesar un documento
 type ProcessVectorResponse struct {
-	VectorID   string `json:"vectorId"`
-	Status     string `json:"status"`
-	ChunksCount int   `json:"chunksCount"`
+	JobID       string `json:"jobId,omitempty"`
+	VectorID    string `json:"vectorId"`
+	Status      string `json:"status"`
+	ChunksCount int    `json:"chunksCount"`
+}
+
+// Tipos y estados de los trabajos en segundo plano (colección jobs)
+const (
+	JobTypeVectorize = "document_vectorize"
+
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// Job representa un trabajo en segundo plano. Las API keys de la petición no se guardan:
+// solo se conservan en memoria mientras el trabajo está en la cola de esta instancia
+type Job struct {
+	ID              string                 `json:"id" bson:"_id"`
+	Type            string                 `json:"type" bson:"type"`
+	Status          string                 `json:"status" bson:"status"`
+	DocumentID      string                 `json:"documentId" bson:"documentId"`
+	EmbeddingConfig EmbeddingConfig        `json:"embeddingConfig" bson:"embeddingConfig"`
+	Progress        JobProgress            `json:"progress" bson:"progress"`
+	Attempts        int                    `json:"attempts" bson:"attempts"`
+	MaxAttempts     int                    `json:"maxAttempts" bson:"maxAttempts"`
+	LastError       string                 `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	Result          *ProcessVectorResponse `json:"result,omitempty" bson:"result,omitempty"`
+	CancelRequested bool                   `json:"cancelRequested,omitempty" bson:"cancelRequested,omitempty"`
+	RunAfter        time.Time              `json:"runAfter" bson:"runAfter"`                       // No se ejecuta antes (espera entre reintentos)
+	LockedBy        string                 `json:"-" bson:"lockedBy,omitempty"`                    // Instancia que lo ejecuta
+	LockedUntil     *time.Time             `json:"-" bson:"lockedUntil,omitempty"`                 // Si vence, otra instancia puede retomarlo
+	CreatedBy       string                 `json:"createdBy,omitempty" bson:"createdBy,omitempty"` // Email del administrador
+	RequestID       string                 `json:"requestId,omitempty" bson:"requestId,omitempty"` // Petición que lo encoló (aparece en los logs del trabajo)
+	CreatedAt       time.Time              `json:"createdAt" bson:"createdAt"`
+	StartedAt       *time.Time             `json:"startedAt,omitempty" bson:"startedAt,omitempty"`
+	FinishedAt      *time.Time             `json:"finishedAt,omitempty" bson:"finishedAt,omitempty"`
+	UpdatedAt       time.Time              `json:"updatedAt" bson:"updatedAt"`
 }
 
+// JobProgress indica la fase de un trabajo y cuántos elementos lleva procesados
+type JobProgress struct {
+	Stage string `json:"stage" bson:"stage"` // chunking, embedding, storing
+	Done  int    `json:"done" bson:"done"`
+	Total int    `json:"total" bson:"total"`
+}
@@ -4,16 +4,25 @@ import "time"
 
 // User representa un usuario del sistema (admin o usuario de app)
 type User struct {
-	ID        string    `bson:"_id" json:"id"` // Usar _id como identificador principal
-	Name      string    `bson:"name" json:"name"`
-	Email     string    `bson:"email" json:"email"`
-	Password  string    `bson:"password,omitempty" json:"-"`
-	AppID     string    `bson:"appId,omitempty" json:"appId,omitempty"` // Para usuario admin
-	Area      int       `bson:"area,omitempty" json:"area,omitempty"`   // Para usuarios de app: 1=PN, 2=PS
-	Enabled   bool      `bson:"enabled" json:"enabled"`                 // Para habilitar/deshabilitar usuarios
-	LastLogin time.Time `bson:"lastLogin,omitempty" json:"lastLogin,omitempty"`
-	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	ID              string     `bson:"_id" json:"id"` // Usar _id como identificador principal
+	Name            string     `bson:"name" json:"name"`
+	Email           string     `bson:"email" json:"email"`
+	Password        string     `bson:"password,omitempty" json:"-"`
+	PasswordHistory []string   `bson:"passwordHistory,omitempty" json:"-"`             // Para usuario admin: hashes de contraseñas anteriores
+	AppID           string     `bson:"appId,omitempty" json:"appId,omitempty"`         // Para usuario admin
+	Role            string     `bson:"role,omitempty" json:"role,omitempty"`           // Para usuario admin: rol RBAC (vacío = superadmin)
+	Areas           []int      `bson:"areas,omitempty" json:"areas,omitempty"`         // Para usuario admin: áreas asignadas (vacío = appId)
+	Disabled        bool       `bson:"disabled,omitempty" json:"disabled,omitempty"`   // Para usuario admin: cuenta deshabilitada por un superadmin
+	InvitedAt       *time.Time `bson:"invitedAt,omitempty" json:"invitedAt,omitempty"` // Para usuario admin: fecha de la última invitación
+	PasswordTokenID string     `bson:"passwordTokenId,omitempty" json:"-"`             // Para usuario admin: token de un solo uso para establecer contraseña
+	TokenVersion    int        `bson:"tokenVersion,omitempty" json:"-"`                // Para usuario admin: se incrementa para invalidar todos sus tokens
+	ResetRequestAt  *time.Time `bson:"resetRequestAt,omitempty" json:"-"`              // Para usuario admin: última solicitud de "olvidé mi contraseña"
+	TwoFactor       *TwoFactor `bson:"twoFactor,omitempty" json:"-"`                   // Para usuario admin: configuración TOTP
+	Area            int        `bson:"area,omitempty" json:"area,omitempty"`           // Para usuarios de app: 1=PN, 2=PS
+	Enabled         bool       `bson:"enabled" json:"enabled"`                         // Para habilitar/deshabilitar usuarios
+	LastLogin       time.Time  `bson:"lastLogin,omitempty" json:"lastLogin,omitempty"`
+	CreatedAt       time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time  `bson:"updatedAt" json:"updatedAt"`
 }
 
 // App representa una aplicación/área (PN=1, PS=2)
@@ -28,24 +37,56 @@ type App struct {
 	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
+// Estados de un topic. Solo los topics "active" son visibles en la app.
+const (
+	TopicStatusActive = "active" // Publicado y visible
+	TopicStatusHidden = "hidden" // Oculto por un administrador
+	TopicStatusDraft  = "draft"  // En preparación, todavía no publicado
+)
+
+// IsValidTopicStatus indica si el estado es uno de los admitidos
+func IsValidTopicStatus(status string) bool {
+	return status == TopicStatusActive || status == TopicStatusHidden || status == TopicStatusDraft
+}
+
+// TopicStatusFromEnabled traduce el antiguo booleano enabled al estado equivalente
+func TopicStatusFromEnabled(enabled bool) string {
+	if enabled {
+		return TopicStatusActive
+	}
+	return TopicStatusHidden
+}
+
 // Topic representa un topic en la colección topics_uuid_map
 type Topic struct {
-	ID          string    `bson:"_id" json:"_id"`
-	TopicID     int       `bson:"id" json:"id"` // Cambiado a int
-	UUID        string    `bson:"uuid" json:"uuid"`
-	RootID      int       `bson:"rootId" json:"rootId"` // Cambiado a int
-	RootUUID    string    `bson:"rootUuid" json:"rootUuid"`
-	Area        int       `bson:"area" json:"area"` // Cambiado a int: 1=PN, 2=PS
-	Title       string    `bson:"title" json:"title"`
-	Description string    `bson:"description,omitempty" json:"description,omitempty"`
-	ImageURL    string    `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
-	Enabled     bool      `bson:"enabled" json:"enabled"`
-	Premium     bool      `bson:"premium" json:"premium"` // Nuevo campo premium
-	Type        string    `bson:"type" json:"type"`       // Tipo: "topic", "exam", "misc"
-	Order       int       `bson:"order" json:"order"`
-	ParentUUID  string    `bson:"parentUuid,omitempty" json:"parentUuid,omitempty"`
-	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+	ID            string     `bson:"_id" json:"_id"`
+	TopicID       int        `bson:"id" json:"id"` // Cambiado a int
+	UUID          string     `bson:"uuid" json:"uuid"`
+	RootID        int        `bson:"rootId" json:"rootId"` // Cambiado a int
+	RootUUID      string     `bson:"rootUuid" json:"rootUuid"`
+	Area          int        `bson:"area" json:"area"` // Cambiado a int: 1=PN, 2=PS
+	Title         string     `bson:"title" json:"title"`
+	Description   string     `bson:"description,omitempty" json:"description,omitempty"`
+	ImageURL      string     `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
+	Status        string     `bson:"status" json:"status"`   // Estado: "active", "hidden", "draft"
+	Enabled       bool       `bson:"enabled" json:"enabled"` // Espejo de Status == "active" (compatibilidad)
+	Premium       bool       `bson:"premium" json:"premium"` // Nuevo campo premium
+	Type          string     `bson:"type" json:"type"`       // Tipo: "topic", "exam", "misc"
+	Order         int        `bson:"order" json:"order"`
+	ParentUUID    string     `bson:"parentUuid,omitempty" json:"parentUuid,omitempty"`
+	PublishAt     *time.Time `bson:"publishAt,omitempty" json:"publishAt,omitempty"`         // Publicación programada
+	UnpublishAt   *time.Time `bson:"unpublishAt,omitempty" json:"unpublishAt,omitempty"`     // Despublicación programada
+	PublishedAt   *time.Time `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`     // Cuándo lo publicó el scheduler
+	UnpublishedAt *time.Time `bson:"unpublishedAt,omitempty" json:"unpublishedAt,omitempty"` // Cuándo lo despublicó el scheduler
+	CreatedAt     time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time  `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ScheduleTopicRequest representa la programación de publicación de un topic.
+// Un valor null elimina la fecha correspondiente.
+type ScheduleTopicRequest struct {
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
 }
 
 // TopicResponse representa la respuesta del endpoint público de topics
@@ -71,6 +112,43 @@ type PaginatedResponse struct {
 	Pagination PaginationInfo `json:"pagination"`
 }
 
+// TopicsFilter representa los filtros del listado de topics de administración
+type TopicsFilter struct {
+	Area    int    `json:"area"`
+	Status  string `json:"status,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"` // Compatibilidad: true = "active", false = cualquier otro estado
+	Premium *bool  `json:"premium,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Search  string `json:"search,omitempty"`
+}
+
+// BulkTopicsRequest representa una operación masiva sobre topics
+type BulkTopicsRequest struct {
+	IDs     []int         `json:"ids,omitempty"`     // IDs numéricos de los topics
+	Filter  *TopicsFilter `json:"filter,omitempty"`  // Alternativa a ids: mismo filtro que el listado
+	Action  string        `json:"action"`            // "enable" | "disable" | "set_status" | "set_premium" | "set_type" | "delete"
+	Status  string        `json:"status,omitempty"`  // Requerido para "set_status"
+	Premium *bool         `json:"premium,omitempty"` // Requerido para "set_premium"
+	Type    string        `json:"type,omitempty"`    // Requerido para "set_type"
+}
+
+// BulkTopicResult representa el resultado de la operación para un topic
+type BulkTopicResult struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"` // "ok" | "not_found" | "error" | "rolled_back"
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkTopicsResponse respuesta de una operación masiva
+type BulkTopicsResponse struct {
+	Action    string            `json:"action"`
+	Applied   bool              `json:"applied"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BulkTopicResult `json:"results"`
+}
+
 // CreateTopicRequest representa los datos para crear un nuevo topic
 type CreateTopicRequest struct {
 	Title       string `json:"title"`
@@ -79,6 +157,7 @@ type CreateTopicRequest struct {
 	Order       int    `json:"order"`
 	Description string `json:"description,omitempty"`
 	ImageURL    string `json:"imageUrl,omitempty"`
+	Status      string `json:"status,omitempty"` // Opcional, por defecto "active"
 }
 
 // CreateSubtopicRequest representa los datos para crear un nuevo subtopic
@@ -109,16 +188,18 @@ type UserStats struct {
 // SystemInfo representa información del sistema
 type SystemInfo struct {
 	TotalTopics    int `json:"totalTopics"`
-	EnabledTopics  int `json:"enabledTopics"`
-	DisabledTopics int `json:"disabledTopics"`
+	EnabledTopics  int `json:"enabledTopics"`  // Topics con estado "active"
+	DisabledTopics int `json:"disabledTopics"` // Topics "hidden" o "draft"
+	DraftTopics    int `json:"draftTopics"`
 }
 
 // TopicStats representa estadísticas de topics
 type TopicStats struct {
 	TotalTopics    int            `json:"totalTopics"`
 	TopicsByArea   map[string]int `json:"topicsByArea"`
-	EnabledTopics  int            `json:"enabledTopics"`
-	DisabledTopics int            `json:"disabledTopics"`
+	EnabledTopics  int            `json:"enabledTopics"`  // Topics con estado "active"
+	DisabledTopics int            `json:"disabledTopics"` // Topics "hidden" o "draft"
+	DraftTopics    int            `json:"draftTopics"`
 }
 
 // AdProvider representa un proveedor de publicidad
@@ -149,6 +230,146 @@ type CollectionStats struct {
 	Size          int64  `json:"size"`
 }
 
+// CreateAdminRequest representa los datos para crear un administrador
+type CreateAdminRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Areas    []int  `json:"areas,omitempty"`
+	Password string `json:"password,omitempty"` // Opcional: si se omite se envía una invitación por email
+}
+
+// UpdateAdminRequest representa los cambios sobre un administrador (campos opcionales)
+type UpdateAdminRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Role  *string `json:"role,omitempty"`
+	Areas *[]int  `json:"areas,omitempty"`
+}
+
+// TwoFactor guarda la configuración TOTP de un administrador
+type TwoFactor struct {
+	Enabled       bool       `bson:"enabled"`
+	Secret        string     `bson:"secret,omitempty"`        // Secreto activo (base32)
+	PendingSecret string     `bson:"pendingSecret,omitempty"` // Secreto en alta, pendiente de verificar
+	RecoveryCodes []string   `bson:"recoveryCodes,omitempty"` // SHA-256 de los códigos de recuperación no usados
+	LastStep      int64      `bson:"lastStep,omitempty"`      // Último paso TOTP aceptado (evita reutilizar códigos)
+	EnabledAt     *time.Time `bson:"enabledAt,omitempty"`
+}
+
+// SecuritySettings es la configuración global de seguridad de los administradores
+// (colección admin_settings, documento "security")
+type SecuritySettings struct {
+	RequireTwoFactor bool      `bson:"requireTwoFactor" json:"requireTwoFactor"`
+	UpdatedAt        time.Time `bson:"updatedAt" json:"updatedAt"`
+	UpdatedBy        string    `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+}
+
+// LoginChallenge es un login pendiente de completar el segundo factor
+// (colección admin_login_challenges)
+type LoginChallenge struct {
+	ID        string    `bson:"_id"` // SHA-256 del challengeToken
+	UserID    string    `bson:"userId"`
+	Purpose   string    `bson:"purpose"` // "verify" (2FA activo) o "enroll" (2FA obligatorio sin configurar)
+	Attempts  int       `bson:"attempts"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// OIDCLoginState guarda el state, nonce y code verifier PKCE de un login OIDC en curso
+// (colección oidc_states, se consume una sola vez en el callback)
+type OIDCLoginState struct {
+	ID           string    `bson:"_id"` // state enviado al proveedor
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+// LoginAttempt acumula los intentos fallidos de login por email o por IP
+// (colección login_attempts)
+type LoginAttempt struct {
+	ID             string     `bson:"_id" json:"id"`      // "email:<email>" o "ip:<ip>"
+	Kind           string     `bson:"kind" json:"kind"`   // "email" | "ip"
+	Value          string     `bson:"value" json:"value"` // Email o IP
+	Failures       int        `bson:"failures" json:"failures"`
+	FirstFailureAt time.Time  `bson:"firstFailureAt" json:"firstFailureAt"`
+	LastFailureAt  time.Time  `bson:"lastFailureAt" json:"lastFailureAt"`
+	LockedUntil    *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	Lockouts       int        `bson:"lockouts" json:"lockouts"` // Número de bloqueos acumulados
+	ExpiresAt      time.Time  `bson:"expiresAt" json:"-"`       // Índice TTL: se borra tras un periodo sin fallos
+}
+
+// APIKey es una credencial para scripts y otros servicios (colección api_keys).
+// Solo se guarda el hash; la key completa se muestra una única vez al crearla.
+type APIKey struct {
+	ID         string     `bson:"_id" json:"id"`
+	Name       string     `bson:"name" json:"name"`
+	Prefix     string     `bson:"prefix" json:"prefix"` // Parte pública de la key, para identificarla
+	KeyHash    string     `bson:"keyHash" json:"-"`     // SHA-256 de la key completa
+	Scopes     []string   `bson:"scopes" json:"scopes"` // Permisos concedidos (mismos valores que los roles)
+	Areas      []int      `bson:"areas" json:"areas"`   // Áreas permitidas (vacío = todas)
+	ExpiresAt  *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	LastUsedIP string     `bson:"lastUsedIp,omitempty" json:"lastUsedIp,omitempty"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	CreatedBy  string     `bson:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+}
+
+// AuditEntry es un registro inmutable de una operación de escritura de un administrador
+// (colección audit_log, solo se inserta: nunca se modifica ni se borra desde la aplicación)
+type AuditEntry struct {
+	ID         string                 `bson:"_id" json:"id"`
+	RequestID  string                 `bson:"requestId,omitempty" json:"requestId,omitempty"` // Común a las entradas de una misma petición (operaciones masivas)
+	Timestamp  time.Time              `bson:"timestamp" json:"timestamp"`
+	ActorID    string                 `bson:"actorId" json:"actorId"` // user_id del JWT ("apikey:<id>" para API keys)
+	ActorEmail string                 `bson:"actorEmail" json:"actorEmail"`
+	ActorRole  string                 `bson:"actorRole" json:"actorRole"`
+	Action     string                 `bson:"action" json:"action"` // create, update, delete o la acción de un POST (invite, clone...)
+	Method     string                 `bson:"method" json:"method"`
+	Route      string                 `bson:"route" json:"route"` // Patrón de la ruta (p. ej. /api/v1/admin/topics/{id}/enabled)
+	Path       string                 `bson:"path" json:"path"`
+	EntityType string                 `bson:"entityType" json:"entityType"`
+	EntityID   string                 `bson:"entityId,omitempty" json:"entityId,omitempty"`
+	Status     int                    `bson:"status" json:"status"`
+	IP         string                 `bson:"ip" json:"ip"`
+	UserAgent  string                 `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Before     map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"` // Documento antes del cambio (sin secretos)
+	After      map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`   // Documento después del cambio (sin secretos)
+}
+
+// ReadinessCheck es el resultado de comprobar una dependencia en /readyz
+type ReadinessCheck struct {
+	Status    string   `json:"status"`   // ok | error
+	Critical  bool     `json:"critical"` // Si falla, la instancia no está lista
+	LatencyMs int64    `json:"latencyMs"`
+	Error     string   `json:"error,omitempty"`
+	Missing   []string `json:"missing,omitempty"` // Índices o migraciones que faltan
+}
+
+// ReadinessResponse representa la respuesta de /readyz
+type ReadinessResponse struct {
+	Status string                    `json:"status"` // ready | degraded | not_ready | shutting_down
+	Checks map[string]ReadinessCheck `json:"checks"`
+	TS     string                    `json:"ts"`
+}
+
+// AdminSession representa una sesión de administrador (colección admin_sessions).
+// Cada refresh token se usa una sola vez: al rotarlo se revoca la sesión y se crea otra
+// de la misma familia; reutilizar un token revocado revoca toda la familia.
+type AdminSession struct {
+	ID         string     `bson:"_id" json:"id"`
+	UserID     string     `bson:"userId" json:"userId"`
+	FamilyID   string     `bson:"familyId" json:"familyId"`
+	TokenHash  string     `bson:"tokenHash" json:"-"` // SHA-256 del refresh token
+	IP         string     `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent  string     `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  time.Time  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	ReplacedBy string     `bson:"replacedBy,omitempty" json:"-"`
+}
+
 // ErrorResponse representa una respuesta de error
 type ErrorResponse struct {
 	Code    string `json:"code"`
@@ -179,6 +400,31 @@ type CopyQuestionsResponse struct {
 	TopicsProcessed int    `json:"topicsProcessed"`
 }
 
+// CloneTopicRequest para clonar un tema principal (con sus subtemas) en otra área
+type CloneTopicRequest struct {
+	TargetArea int    `json:"targetArea"`
+	Questions  string `json:"questions,omitempty"` // "none" | "link" | "copy"
+}
+
+// CloneTopicMapping relaciona un topic origen con el topic creado
+type CloneTopicMapping struct {
+	SourceID   int    `json:"sourceId"`
+	SourceUUID string `json:"sourceUuid"`
+	NewID      int    `json:"newId"`
+	NewUUID    string `json:"newUuid"`
+}
+
+// CloneTopicResponse respuesta de la clonación de un árbol de temas
+type CloneTopicResponse struct {
+	Message         string              `json:"message"`
+	Topic           Topic               `json:"topic"`
+	Mapping         []CloneTopicMapping `json:"mapping"`
+	QuestionsMode   string              `json:"questionsMode"`
+	QuestionsLinked int                 `json:"questionsLinked"`
+	QuestionsCopied int                 `json:"questionsCopied"`
+	QuestionIDMap   map[int]int         `json:"questionIdMap,omitempty"` // questionId origen -> questionId nuevo (solo modo "copy")
+}
+
 // QuestionAnswer representa una respuesta de una pregunta
 type QuestionAnswer struct {
 	ID      int    `bson:"id" json:"id"`
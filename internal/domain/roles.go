@@ -0,0 +1,144 @@
+package domain
+
+import "strconv"
+
+// Roles de los usuarios administradores
+const (
+	RoleSuperadmin = "superadmin" // Acceso total
+	RoleAreaAdmin  = "area-admin" // Administra el contenido y los usuarios de sus áreas
+	RoleEditor     = "editor"     // Edita contenido (topics, preguntas, notificaciones, privacidad)
+	RoleViewer     = "viewer"     // Solo lectura
+	RoleSupport    = "support"    // Atención a usuarios de la app
+)
+
+// RoleAPIKey identifica en el contexto las peticiones autenticadas con una API key;
+// sus permisos son los scopes de la key, no los de un rol
+const RoleAPIKey = "api-key"
+
+// Permisos comprobados por el middleware de cada grupo de rutas
+const (
+	PermTopicsRead         = "topics:read"
+	PermTopicsWrite        = "topics:write"
+	PermQuestionsWrite     = "questions:write"
+	PermAreasRead          = "areas:read"
+	PermAreasWrite         = "areas:write"
+	PermUsersRead          = "users:read"
+	PermUsersWrite         = "users:write"
+	PermProvidersRead      = "providers:read"
+	PermProvidersWrite     = "providers:write"
+	PermStatsRead          = "stats:read"
+	PermDatabaseRead       = "database:read"
+	PermNotificationsRead  = "notifications:read"
+	PermNotificationsWrite = "notifications:write"
+	PermPrivacyRead        = "privacy:read"
+	PermPrivacyWrite       = "privacy:write"
+	PermDocumentsRead      = "documents:read"
+	PermDocumentsWrite     = "documents:write"
+	PermAdminsManage       = "admins:manage" // Gestión de cuentas de administrador (solo superadmin)
+)
+
+// rolePermissions define los permisos de cada rol (superadmin los tiene todos)
+var rolePermissions = map[string][]string{
+	RoleAreaAdmin: {
+		PermTopicsRead, PermTopicsWrite, PermQuestionsWrite,
+		PermAreasRead,
+		PermUsersRead, PermUsersWrite,
+		PermProvidersRead,
+		PermStatsRead,
+		PermNotificationsRead, PermNotificationsWrite,
+		PermPrivacyRead, PermPrivacyWrite,
+		PermDocumentsRead, PermDocumentsWrite,
+	},
+	RoleEditor: {
+		PermTopicsRead, PermTopicsWrite, PermQuestionsWrite,
+		PermAreasRead,
+		PermStatsRead,
+		PermNotificationsRead, PermNotificationsWrite,
+		PermPrivacyRead, PermPrivacyWrite,
+		PermDocumentsRead, PermDocumentsWrite,
+	},
+	RoleViewer: {
+		PermTopicsRead,
+		PermAreasRead,
+		PermUsersRead,
+		PermProvidersRead,
+		PermStatsRead,
+		PermNotificationsRead,
+		PermPrivacyRead,
+		PermDocumentsRead,
+	},
+	RoleSupport: {
+		PermTopicsRead,
+		PermAreasRead,
+		PermUsersRead, PermUsersWrite,
+		PermStatsRead,
+		PermNotificationsRead,
+	},
+}
+
+// apiKeyScopes son los permisos que se pueden conceder a una API key (la gestión
+// de administradores queda excluida)
+var apiKeyScopes = []string{
+	PermTopicsRead, PermTopicsWrite, PermQuestionsWrite,
+	PermAreasRead, PermAreasWrite,
+	PermUsersRead, PermUsersWrite,
+	PermProvidersRead, PermProvidersWrite,
+	PermStatsRead,
+	PermDatabaseRead,
+	PermNotificationsRead, PermNotificationsWrite,
+	PermPrivacyRead, PermPrivacyWrite,
+	PermDocumentsRead, PermDocumentsWrite,
+}
+
+// IsValidAPIKeyScope indica si el permiso puede concederse a una API key
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range apiKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole indica si el rol es uno de los admitidos
+func IsValidRole(role string) bool {
+	if role == RoleSuperadmin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission indica si el rol concede el permiso
+func RoleHasPermission(role, permission string) bool {
+	if role == RoleSuperadmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRole devuelve el rol del administrador. Los administradores creados
+// antes de existir los roles no tienen el campo y se consideran superadmin.
+func (u User) EffectiveRole() string {
+	if u.Role == "" {
+		return RoleSuperadmin
+	}
+	return u.Role
+}
+
+// AssignedAreas devuelve las áreas asignadas al administrador. Si no tiene
+// ninguna explícita se usa su appId, como hacían los listados por defecto.
+func (u User) AssignedAreas() []int {
+	if len(u.Areas) > 0 {
+		return u.Areas
+	}
+	if area, err := strconv.Atoi(u.AppID); err == nil && area > 0 {
+		return []int{area}
+	}
+	return []int{}
+}
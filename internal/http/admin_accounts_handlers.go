@@ -0,0 +1,582 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ========== Gestión de cuentas de administrador (solo superadmin) ==========
+
+// AdminAccountsList - Listar administradores
+func AdminAccountsList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		filter := bson.M{}
+		if role := r.URL.Query().Get("role"); role != "" {
+			filter = adminRoleFilter(role)
+		}
+
+		cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cur.Close(ctx)
+
+		admins := []domain.User{}
+		if err := cur.All(ctx, &admins); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Mostrar siempre el rol y las áreas efectivas
+		for i := range admins {
+			admins[i].Role = admins[i].EffectiveRole()
+			admins[i].Areas = admins[i].AssignedAreas()
+		}
+
+		writeJSON(w, http.StatusOK, admins)
+	}
+}
+
+// AdminAccountsCreate - Crear un administrador (con contraseña o por invitación)
+func AdminAccountsCreate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req domain.CreateAdminRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+		if req.Name == "" || req.Email == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "name y email requeridos")
+			return
+		}
+		if !strings.Contains(req.Email, "@") || !strings.Contains(req.Email, ".") {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "email inválido")
+			return
+		}
+		if err := validateAdminRoleAndAreas(req.Role, req.Areas); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+		policy := services.NewPasswordPolicy(cfg)
+		if req.Password != "" {
+			if err := validateAdminPassword(policy, req.Password, domain.User{Email: req.Email}); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		// El email identifica al administrador en el login
+		count, err := col.CountDocuments(ctx, bson.M{"email": req.Email})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if count > 0 {
+			writeError(w, http.StatusConflict, "email_exists", "ya existe un administrador con ese email")
+			return
+		}
+
+		now := time.Now()
+		admin := domain.User{
+			ID:        uuid.NewString(),
+			Name:      req.Name,
+			Email:     req.Email,
+			Role:      req.Role,
+			Areas:     req.Areas,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		// appId se mantiene por compatibilidad con los listados por defecto
+		if len(req.Areas) > 0 {
+			admin.AppID = strconv.Itoa(req.Areas[0])
+		}
+		if req.Password != "" {
+			hash, err := policy.Hash(req.Password)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", "no se pudo procesar la contraseña")
+				return
+			}
+			admin.Password = hash
+		} else {
+			admin.InvitedAt = &now
+		}
+
+		if _, err := col.InsertOne(ctx, admin); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Sin contraseña: enviar invitación para que la establezca
+		invited := false
+		if req.Password == "" {
+			if err := sendAdminInvitation(ctx, col, admin, cfg); err != nil {
+				logf(r, "⚠️ AdminAccountsCreate - Error enviando invitación a %s: %v", admin.Email, err)
+			} else {
+				invited = true
+			}
+		}
+
+		logf(r, "✅ AdminAccountsCreate - Administrador %s creado (rol: %s, áreas: %v, invitado: %v)", admin.Email, admin.Role, admin.Areas, invited)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"admin":   admin,
+			"invited": invited,
+		})
+	}
+}
+
+// AdminAccountsUpdate - Actualizar nombre, rol y áreas de un administrador
+func AdminAccountsUpdate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var req domain.UpdateAdminRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		var existing domain.User
+		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		role := existing.EffectiveRole()
+		if req.Role != nil {
+			role = *req.Role
+		}
+		areas := existing.AssignedAreas()
+		if req.Areas != nil {
+			areas = *req.Areas
+		}
+		if err := validateAdminRoleAndAreas(role, areas); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+
+		// No se puede dejar el sistema sin superadmin
+		if role != domain.RoleSuperadmin && existing.EffectiveRole() == domain.RoleSuperadmin {
+			if id == userIDFromRequest(r) {
+				writeError(w, http.StatusUnprocessableEntity, "self_action", "no puedes quitarte el rol de superadmin")
+				return
+			}
+			if err := ensureAnotherSuperadmin(ctx, col, id); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "last_superadmin", err.Error())
+				return
+			}
+		}
+
+		set := bson.M{"role": role, "areas": areas, "updatedAt": time.Now()}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if name == "" {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "name no puede estar vacío")
+				return
+			}
+			set["name"] = name
+		}
+
+		var updated domain.User
+		if err := col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminAccountsUpdate - Administrador %s actualizado (rol: %s, áreas: %v)", updated.Email, role, areas)
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+// AdminAccountsSetDisabled - Deshabilitar o rehabilitar un administrador
+func AdminAccountsSetDisabled(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		var req struct {
+			Disabled bool `json:"disabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		if req.Disabled && id == userIDFromRequest(r) {
+			writeError(w, http.StatusUnprocessableEntity, "self_action", "no puedes deshabilitar tu propia cuenta")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		var existing domain.User
+		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if req.Disabled && existing.EffectiveRole() == domain.RoleSuperadmin {
+			if err := ensureAnotherSuperadmin(ctx, col, id); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "last_superadmin", err.Error())
+				return
+			}
+		}
+
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$set": bson.M{"disabled": req.Disabled, "updatedAt": time.Now()},
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Deshabilitar la cuenta cierra también sus sesiones activas
+		if req.Disabled {
+			if err := revokeAdminSessions(ctx, client.Database(cfg.DBName), id); err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+		}
+
+		logf(r, "✅ AdminAccountsSetDisabled - Administrador %s disabled=%v", existing.Email, req.Disabled)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       id,
+			"disabled": req.Disabled,
+			"message":  "Estado del administrador actualizado exitosamente",
+		})
+	}
+}
+
+// AdminAccountsDelete - Eliminar un administrador
+func AdminAccountsDelete(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		if id == userIDFromRequest(r) {
+			writeError(w, http.StatusUnprocessableEntity, "self_action", "no puedes eliminar tu propia cuenta")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		var existing domain.User
+		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if existing.EffectiveRole() == domain.RoleSuperadmin {
+			if err := ensureAnotherSuperadmin(ctx, col, id); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "last_superadmin", err.Error())
+				return
+			}
+		}
+
+		if _, err := col.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if _, err := client.Database(cfg.DBName).Collection(adminSessionsCollection).DeleteMany(ctx, bson.M{"userId": id}); err != nil {
+			logf(r, "⚠️ AdminAccountsDelete - Error eliminando sesiones de %s: %v", existing.Email, err)
+		}
+
+		logf(r, "✅ AdminAccountsDelete - Administrador %s eliminado", existing.Email)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message":   "Administrador eliminado exitosamente",
+			"deletedId": id,
+		})
+	}
+}
+
+// AdminAccountsInvite - Reenviar la invitación a un administrador sin contraseña
+func AdminAccountsInvite(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		var admin domain.User
+		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&admin); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if admin.Password != "" {
+			writeError(w, http.StatusConflict, "already_active", "el administrador ya tiene contraseña; usa force-password-reset")
+			return
+		}
+
+		if err := sendAdminInvitation(ctx, col, admin, cfg); err != nil {
+			logf(r, "❌ AdminAccountsInvite - Error enviando invitación a %s: %v", admin.Email, err)
+			writeError(w, http.StatusBadGateway, "email_error", "no se pudo enviar la invitación")
+			return
+		}
+
+		logf(r, "✅ AdminAccountsInvite - Invitación reenviada a %s", admin.Email)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Invitación enviada exitosamente",
+		})
+	}
+}
+
+// AdminAccountsForcePasswordReset - Invalidar la contraseña de un administrador y enviarle un enlace para restablecerla
+func AdminAccountsForcePasswordReset(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("user")
+
+		var admin domain.User
+		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&admin); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Eliminar la contraseña actual: no podrá iniciar sesión hasta establecer una nueva
+		// La contraseña invalidada pasa al historial para que no pueda reutilizarse
+		history := services.NewPasswordPolicy(cfg).History(admin.Password, admin.PasswordHistory)
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$unset": bson.M{"password": ""},
+			"$set":   bson.M{"passwordHistory": history, "updatedAt": time.Now()},
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if err := revokeAdminSessions(ctx, client.Database(cfg.DBName), id); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		token, err := issueAdminPasswordToken(ctx, col, admin.ID, admin.Email, adminTokenPasswordReset, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		emailSent := true
+		if err := services.NewEmailService(cfg).SendAdminPasswordResetEmail(ctx, admin.Email, token); err != nil {
+			logf(r, "⚠️ AdminAccountsForcePasswordReset - Error enviando email a %s: %v", admin.Email, err)
+			emailSent = false
+		}
+
+		logf(r, "✅ AdminAccountsForcePasswordReset - Contraseña de %s invalidada (email enviado: %v)", admin.Email, emailSent)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message":   "Contraseña invalidada; el administrador debe establecer una nueva",
+			"emailSent": emailSent,
+		})
+	}
+}
+
+// AdminAccountsRevokeSessions - Cerrar todas las sesiones de un administrador
+func AdminAccountsRevokeSessions(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		var admin domain.User
+		if err := db.Collection("user").FindOne(ctx, bson.M{"_id": id}).Decode(&admin); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if err := revokeAdminSessions(ctx, db, id); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminAccountsRevokeSessions - Sesiones de %s revocadas", admin.Email)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Sesiones del administrador revocadas exitosamente",
+		})
+	}
+}
+
+// sendAdminInvitation genera el token de invitación y envía el email
+func sendAdminInvitation(ctx context.Context, col *mongo.Collection, admin domain.User, cfg config.Config) error {
+	token, err := issueAdminPasswordToken(ctx, col, admin.ID, admin.Email, adminTokenInvite, cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": admin.ID}, bson.M{"$set": bson.M{"invitedAt": time.Now()}}); err != nil {
+		return err
+	}
+	return services.NewEmailService(cfg).SendAdminInvitationEmail(ctx, admin.Email, admin.Name, token)
+}
+
+// validateAdminRoleAndAreas comprueba el rol y que los roles con alcance por área tengan áreas válidas
+func validateAdminRoleAndAreas(role string, areas []int) error {
+	if !domain.IsValidRole(role) {
+		return errors.New("role debe ser 'superadmin', 'area-admin', 'editor', 'viewer' o 'support'")
+	}
+	for _, area := range areas {
+		if area < 1 || area > 10 {
+			return errors.New("areas debe contener áreas entre 1 y 10")
+		}
+	}
+	if role != domain.RoleSuperadmin && len(areas) == 0 {
+		return errors.New("areas es requerido para roles distintos de superadmin")
+	}
+	return nil
+}
+
+// adminRoleFilter construye el filtro por rol (los administradores sin rol son superadmin)
+func adminRoleFilter(role string) bson.M {
+	if role == domain.RoleSuperadmin {
+		return bson.M{"$or": []bson.M{
+			{"role": domain.RoleSuperadmin},
+			{"role": bson.M{"$exists": false}},
+			{"role": ""},
+		}}
+	}
+	return bson.M{"role": role}
+}
+
+// ensureAnotherSuperadmin comprueba que queda otro superadmin activo además de excludeID
+func ensureAnotherSuperadmin(ctx context.Context, col *mongo.Collection, excludeID string) error {
+	filter := adminRoleFilter(domain.RoleSuperadmin)
+	filter["_id"] = bson.M{"$ne": excludeID}
+	filter["disabled"] = bson.M{"$ne": true}
+
+	count, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("debe quedar al menos un superadmin activo")
+	}
+	return nil
+}
+
+// userIDFromRequest devuelve el ID del administrador autenticado
+func userIDFromRequest(r *http.Request) string {
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
@@ -0,0 +1,244 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// forgotPasswordCooldown es el tiempo mínimo entre dos emails de recuperación a la misma cuenta
+const forgotPasswordCooldown = 5 * time.Minute
+
+// forgotPasswordMessage es la respuesta genérica: no revela si el email corresponde a un administrador
+const forgotPasswordMessage = "Si el email corresponde a una cuenta de administrador, recibirás un enlace para restablecer la contraseña."
+
+// ========== Recuperación de contraseña de administrador ("olvidé mi contraseña") ==========
+
+// AdminForgotPasswordForm - Muestra el formulario HTML para solicitar el enlace (endpoint público GET)
+func AdminForgotPasswordForm(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderForgotPasswordPage(w, r, false, "")
+	}
+}
+
+// AdminForgotPasswordRequest - Envía por email un enlace de un solo uso para restablecer la contraseña (endpoint público POST)
+func AdminForgotPasswordRequest(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email string `json:"email"`
+		}
+
+		// Aceptar JSON (frontend) o form-data (formulario HTML)
+		isJSON := strings.Contains(r.Header.Get("Content-Type"), "application/json")
+		if isJSON {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+				return
+			}
+		} else {
+			if err := r.ParseForm(); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "invalid form data")
+				return
+			}
+			req.Email = r.FormValue("email")
+		}
+
+		// done responde siempre lo mismo, exista o no la cuenta
+		done := func() {
+			if isJSON {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"message": forgotPasswordMessage})
+				return
+			}
+			renderForgotPasswordPage(w, r, true, "")
+		}
+
+		req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+		if req.Email == "" || !strings.Contains(req.Email, "@") {
+			if isJSON {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "email inválido")
+				return
+			}
+			renderForgotPasswordPage(w, r, false, "Introduce un email válido.")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		users := client.Database(cfg.DBName).Collection("user")
+
+		var admin domain.User
+		if err := users.FindOne(ctx, bson.M{"email": req.Email}).Decode(&admin); err != nil {
+			if err == mongo.ErrNoDocuments {
+				logf(r, "⚠️ AdminForgotPasswordRequest - Email no registrado: %s", req.Email)
+				done()
+				return
+			}
+			logf(r, "❌ AdminForgotPasswordRequest - Error buscando administrador: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if admin.Disabled {
+			logf(r, "⚠️ AdminForgotPasswordRequest - Cuenta deshabilitada: %s", req.Email)
+			done()
+			return
+		}
+
+		// Limitar el envío de emails: se reserva el hueco de forma atómica
+		now := time.Now()
+		result, err := users.UpdateOne(ctx, bson.M{
+			"_id": admin.ID,
+			"$or": []bson.M{
+				{"resetRequestAt": bson.M{"$exists": false}},
+				{"resetRequestAt": bson.M{"$lt": now.Add(-forgotPasswordCooldown)}},
+			},
+		}, bson.M{"$set": bson.M{"resetRequestAt": now}})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.MatchedCount == 0 {
+			logf(r, "⚠️ AdminForgotPasswordRequest - Solicitud repetida antes de %s: %s", forgotPasswordCooldown, req.Email)
+			done()
+			return
+		}
+
+		// La contraseña actual sigue siendo válida hasta que se use el enlace
+		token, err := issueAdminPasswordToken(ctx, users, admin.ID, admin.Email, adminTokenForgotPassword, cfg)
+		if err != nil {
+			logf(r, "❌ AdminForgotPasswordRequest - Error generando token: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if err := services.NewEmailService(cfg).SendAdminForgotPasswordEmail(ctx, admin.Email, token); err != nil {
+			// No revelamos el error al usuario por seguridad
+			logf(r, "❌ AdminForgotPasswordRequest - Error enviando email a %s: %v", admin.Email, err)
+		} else {
+			logf(r, "✅ AdminForgotPasswordRequest - Enlace de recuperación enviado a %s", admin.Email)
+		}
+
+		done()
+	}
+}
+
+// renderForgotPasswordPage renderiza el formulario de solicitud o la confirmación de envío
+func renderForgotPasswordPage(w http.ResponseWriter, r *http.Request, sent bool, errorMessage string) {
+	tmpl := `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Recuperar contraseña</title>
+    <style>{{.Style}}
+        .form-group {
+            margin-bottom: 20px;
+        }
+        label {
+            display: block;
+            margin-bottom: 8px;
+            color: #333;
+            font-weight: 500;
+            font-size: 14px;
+        }
+        input[type="email"] {
+            width: 100%;
+            padding: 12px 15px;
+            border: 2px solid #e0e0e0;
+            border-radius: 8px;
+            font-size: 16px;
+        }
+        input[type="email"]:focus {
+            outline: none;
+            border-color: #667eea;
+        }
+        .error-message {
+            background-color: #f8d7da;
+            color: #721c24;
+            padding: 15px;
+            border-radius: 8px;
+            margin-bottom: 20px;
+        }
+        .message {
+            color: #555;
+            font-size: 16px;
+            line-height: 1.6;
+            text-align: center;
+        }
+        button {
+            width: 100%;
+            padding: 14px;
+            background-color: #667eea;
+            color: #ffffff;
+            border: none;
+            border-radius: 8px;
+            font-size: 16px;
+            font-weight: 600;
+            cursor: pointer;
+        }
+        button:hover {
+            background-color: #5a67d8;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Recuperar contraseña</h1>
+        {{if .Sent}}
+        <p class="message">{{.Message}}</p>
+        {{else}}
+        {{if .Error}}<div class="error-message">{{.Error}}</div>{{end}}
+        <form method="POST">
+            <div class="form-group">
+                <label for="email">Email de tu cuenta de administrador</label>
+                <input type="email" id="email" name="email" required autocomplete="email" placeholder="tu-email@ejemplo.com">
+            </div>
+            <button type="submit">Enviar enlace</button>
+        </form>
+        {{end}}
+        <div class="footer">
+            <p>El enlace caduca en 1 hora y solo puede usarse una vez.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("forgot-password").Parse(tmpl)
+	if err != nil {
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+
+	data := map[string]interface{}{
+		"Style":   template.CSS(passwordSetupPageStyle),
+		"Sent":    sent,
+		"Message": forgotPasswordMessage,
+		"Error":   errorMessage,
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		logf(r, "❌ Error ejecutando template: %v", err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,13 +11,13 @@ import (
 
 	"opo_admin_server/internal/config"
 	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AdminUserGet - Obtener información del usuario administrador
@@ -100,8 +99,9 @@ func AdminUserUpdate(cfg config.Config) http.HandlerFunc {
 			},
 		}
 
+		// Actualizar solo el administrador autenticado
 		var user domain.User
-		if err := users.FindOneAndUpdate(ctx, bson.M{}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user); err != nil {
+		if err := users.FindOneAndUpdate(ctx, bson.M{"_id": r.Context().Value("user_id")}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user); err != nil {
 			if err == mongo.ErrNoDocuments {
 				writeError(w, http.StatusNotFound, "not_found", "usuario administrador no encontrado")
 				return
@@ -127,8 +127,8 @@ func AdminUserResetPassword(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if req.CurrentPassword == "" || req.NewPassword == "" || len(req.NewPassword) < 6 {
-			writeError(w, http.StatusUnprocessableEntity, "validation_error", "currentPassword y newPassword (>=6) requeridos")
+		if req.CurrentPassword == "" || req.NewPassword == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "currentPassword y newPassword requeridos")
 			return
 		}
 
@@ -145,7 +145,7 @@ func AdminUserResetPassword(cfg config.Config) http.HandlerFunc {
 		users := client.Database(cfg.DBName).Collection("user")
 
 		var user domain.User
-		if err := users.FindOne(ctx, bson.M{}).Decode(&user); err != nil {
+		if err := users.FindOne(ctx, bson.M{"_id": r.Context().Value("user_id")}).Decode(&user); err != nil {
 			if err == mongo.ErrNoDocuments {
 				writeError(w, http.StatusNotFound, "not_found", "usuario administrador no encontrado")
 				return
@@ -154,23 +154,27 @@ func AdminUserResetPassword(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+		policy := services.NewPasswordPolicy(cfg)
+		if !policy.Verify(user.Password, req.CurrentPassword) {
 			writeError(w, http.StatusBadRequest, "invalid_password", "contraseña actual incorrecta")
 			return
 		}
 
-		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err := validateAdminPassword(policy, req.NewPassword, user); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+
+		passwordFields, err := adminPasswordFields(policy, req.NewPassword, user)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "server_error", "no se pudo procesar la nueva contraseña")
 			return
 		}
 
-		users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
-			"$set": bson.M{
-				"password":  string(hash),
-				"updatedAt": time.Now(),
-			},
-		})
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": passwordFields}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"message": "Contraseña actualizada exitosamente",
@@ -210,12 +214,16 @@ func AdminTopicsList(cfg config.Config) http.HandlerFunc {
 			// Si viene el parámetro area, usarlo
 			filterArea, err = strconv.Atoi(areaParam)
 			if err != nil {
-				log.Printf("❌ AdminTopicsList - Error convirtiendo área: %v", err)
+				logf(r, "❌ AdminTopicsList - Error convirtiendo área: %v", err)
 				writeError(w, http.StatusBadRequest, "invalid_area", "área debe ser un número válido")
 				return
 			}
-			log.Printf("🔍 AdminTopicsList - Parámetro area recibido (string): '%s'", areaParam)
-			log.Printf("🔍 AdminTopicsList - Usando área del parámetro (int): %d", filterArea)
+			logf(r, "🔍 AdminTopicsList - Parámetro area recibido (string): '%s'", areaParam)
+			logf(r, "🔍 AdminTopicsList - Usando área del parámetro (int): %d", filterArea)
+		} else if area := areaScopeFromRequest(r).DefaultArea(); area != 0 {
+			// Administrador con áreas asignadas: usar la primera
+			filterArea = area
+			logf(r, "🔍 AdminTopicsList - Usando área asignada al administrador: %d", filterArea)
 		} else {
 			// Si no viene area, usar el área del usuario (appId)
 			userID := r.Context().Value("user_id")
@@ -239,54 +247,33 @@ func AdminTopicsList(cfg config.Config) http.HandlerFunc {
 				writeError(w, http.StatusForbidden, "forbidden", "usuario sin área asignada válida")
 				return
 			}
-			log.Printf("🔍 AdminTopicsList - Usando área del usuario (appId): %s -> %d", user.AppID, filterArea)
+			logf(r, "🔍 AdminTopicsList - Usando área del usuario (appId): %s -> %d", user.AppID, filterArea)
 		}
 
-		// Construir filtro: solo temas principales donde id === rootId Y del área especificada
-		// Usando $expr para comparar campos dentro del mismo documento
-		filter := bson.M{
-			"area": filterArea,
-			"$expr": bson.M{
-				"$eq": []interface{}{"$id", "$rootId"},
-			},
+		// Restringir a las áreas asignadas al administrador
+		if !areaScopeFromRequest(r).Allows(filterArea) {
+			writeAreaForbidden(w, filterArea)
+			return
 		}
 
-		// Agregar filtro de enabled si viene en los parámetros
-		enabledParam := r.URL.Query().Get("enabled")
-		if enabledParam != "" {
+		// Construir filtro: solo temas principales del área especificada
+		topicsFilter := domain.TopicsFilter{
+			Area:   filterArea,
+			Status: r.URL.Query().Get("status"),
+			Type:   r.URL.Query().Get("type"),
+			Search: r.URL.Query().Get("search"),
+		}
+		if enabledParam := r.URL.Query().Get("enabled"); enabledParam != "" {
 			enabled := enabledParam == "true"
-			filter["enabled"] = enabled
-			log.Printf("🔍 AdminTopicsList - Aplicando filtro enabled: %v", enabled)
+			topicsFilter.Enabled = &enabled
 		}
-
-		// Agregar filtro de premium si viene en los parámetros
-		premiumParam := r.URL.Query().Get("premium")
-		if premiumParam != "" {
+		if premiumParam := r.URL.Query().Get("premium"); premiumParam != "" {
 			premium := premiumParam == "true"
-			filter["premium"] = premium
-			log.Printf("🔍 AdminTopicsList - Aplicando filtro premium: %v", premium)
+			topicsFilter.Premium = &premium
 		}
+		filter := buildTopicsListFilter(r, topicsFilter)
 
-		// Agregar filtro de type si viene en los parámetros
-		typeParam := r.URL.Query().Get("type")
-		if typeParam != "" {
-			// Validar que el type sea válido
-			if typeParam == "topic" || typeParam == "exam" || typeParam == "misc" {
-				filter["type"] = typeParam
-				log.Printf("🔍 AdminTopicsList - Aplicando filtro type: %s", typeParam)
-			} else {
-				log.Printf("⚠️ AdminTopicsList - Type inválido ignorado: %s", typeParam)
-			}
-		}
-
-		// Agregar filtro de búsqueda si viene en los parámetros
-		searchParam := r.URL.Query().Get("search")
-		if searchParam != "" {
-			filter["title"] = bson.M{"$regex": searchParam, "$options": "i"}
-			log.Printf("🔍 AdminTopicsList - Aplicando filtro search: %s", searchParam)
-		}
-
-		log.Printf("🔍 AdminTopicsList - Filtro MongoDB final: %+v", filter)
+		logf(r, "🔍 AdminTopicsList - Filtro MongoDB final: %+v", filter)
 
 		// Contar total de temas principales
 		total, err := col.CountDocuments(ctx, filter)
@@ -295,7 +282,7 @@ func AdminTopicsList(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminTopicsList - Total de topics encontrados con filtro: %d", total)
+		logf(r, "🔍 AdminTopicsList - Total de topics encontrados con filtro: %d", total)
 
 		// Opciones de paginación con ordenamiento por order
 		skip := (page - 1) * limit
@@ -315,9 +302,9 @@ func AdminTopicsList(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminTopicsList - Topics recuperados: %d", len(topics))
+		logf(r, "🔍 AdminTopicsList - Topics recuperados: %d", len(topics))
 		if len(topics) > 0 {
-			log.Printf("🔍 AdminTopicsList - Primer topic - ID: %d, Área: %d, Premium: %v, Title: %s", topics[0].TopicID, topics[0].Area, topics[0].Premium, topics[0].Title)
+			logf(r, "🔍 AdminTopicsList - Primer topic - ID: %d, Área: %d, Premium: %v, Title: %s", topics[0].TopicID, topics[0].Area, topics[0].Premium, topics[0].Title)
 		}
 
 		// Calcular páginas totales
@@ -340,6 +327,51 @@ func AdminTopicsList(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// buildTopicsListFilter construye el filtro MongoDB de AdminTopicsList (solo temas principales)
+func buildTopicsListFilter(r *http.Request, f domain.TopicsFilter) bson.M {
+	// Usando $expr para comparar campos dentro del mismo documento (id === rootId)
+	filter := bson.M{
+		"area": f.Area,
+		"$expr": bson.M{
+			"$eq": []interface{}{"$id", "$rootId"},
+		},
+	}
+
+	// status tiene prioridad; enabled se mantiene por compatibilidad con clientes antiguos
+	if f.Status != "" {
+		if domain.IsValidTopicStatus(f.Status) {
+			filter["status"] = f.Status
+		} else {
+			logf(r, "⚠️ buildTopicsListFilter - Status inválido ignorado: %s", f.Status)
+		}
+	} else if f.Enabled != nil {
+		if *f.Enabled {
+			filter["status"] = domain.TopicStatusActive
+		} else {
+			filter["status"] = bson.M{"$ne": domain.TopicStatusActive}
+		}
+	}
+
+	if f.Premium != nil {
+		filter["premium"] = *f.Premium
+	}
+
+	if f.Type != "" {
+		// Validar que el type sea válido
+		if f.Type == "topic" || f.Type == "exam" || f.Type == "misc" {
+			filter["type"] = f.Type
+		} else {
+			logf(r, "⚠️ buildTopicsListFilter - Type inválido ignorado: %s", f.Type)
+		}
+	}
+
+	if f.Search != "" {
+		filter["title"] = bson.M{"$regex": f.Search, "$options": "i"}
+	}
+
+	return filter
+}
+
 // AdminTopicsGetByID - Obtener topic específico
 func AdminTopicsGetByID(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -502,7 +534,7 @@ func AdminTopicsCreateSubtopic(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminTopicsCreateSubtopic - Parent: ID=%d, UUID=%s, Area=%d", parentTopic.TopicID, parentTopic.UUID, parentTopic.Area)
+		logf(r, "🔍 AdminTopicsCreateSubtopic - Parent: ID=%d, UUID=%s, Area=%d", parentTopic.TopicID, parentTopic.UUID, parentTopic.Area)
 
 		// 2. Generar ID único para el subtopic según el rango del área
 		// Buscar el último ID del área
@@ -518,7 +550,7 @@ func AdminTopicsCreateSubtopic(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminTopicsCreateSubtopic - Siguiente ID para área %d: %d", parentTopic.Area, nextID)
+		logf(r, "🔍 AdminTopicsCreateSubtopic - Siguiente ID para área %d: %d", parentTopic.Area, nextID)
 
 		// 3. Generar UUID único
 		topicUUID := uuid.NewString()
@@ -547,7 +579,8 @@ func AdminTopicsCreateSubtopic(cfg config.Config) http.HandlerFunc {
 			Title:       req.Title,
 			Description: req.Description,
 			ImageURL:    req.ImageURL,
-			Enabled:     true,  // Por defecto habilitado
+			Status:      domain.TopicStatusActive, // Por defecto activo
+			Enabled:     true,
 			Premium:     false, // Por defecto no premium
 			Type:        subtopicType,
 			Order:       req.Order,
@@ -561,7 +594,7 @@ func AdminTopicsCreateSubtopic(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminTopicsCreateSubtopic - Subtopic %d creado bajo parent %d, type: %s, área: %d", subtopic.TopicID, parentId, subtopic.Type, subtopic.Area)
+		logf(r, "✅ AdminTopicsCreateSubtopic - Subtopic %d creado bajo parent %d, type: %s, área: %d", subtopic.TopicID, parentId, subtopic.Type, subtopic.Area)
 		writeJSON(w, http.StatusCreated, subtopic)
 	}
 }
@@ -586,6 +619,10 @@ func AdminTopicsCreate(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusUnprocessableEntity, "validation_error", "area debe ser entre 1 y 10")
 			return
 		}
+		if !areaScopeFromRequest(r).Allows(req.Area) {
+			writeAreaForbidden(w, req.Area)
+			return
+		}
 
 		// Validar tipo si se proporciona, sino establecer valor por defecto
 		if req.Type == "" {
@@ -597,6 +634,14 @@ func AdminTopicsCreate(cfg config.Config) http.HandlerFunc {
 			}
 		}
 
+		// Validar estado si se proporciona, sino establecer valor por defecto
+		if req.Status == "" {
+			req.Status = domain.TopicStatusActive
+		} else if !domain.IsValidTopicStatus(req.Status) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "status debe ser 'active', 'hidden' o 'draft'")
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
@@ -626,7 +671,7 @@ func AdminTopicsCreate(cfg config.Config) http.HandlerFunc {
 		// Generar UUID único
 		topicUUID := uuid.NewString()
 
-		// Crear el topic completo
+		// Crear el topic completo (activo por defecto, o en el estado solicitado)
 		now := time.Now()
 		topic := domain.Topic{
 			ID:          uuid.NewString(),
@@ -638,7 +683,8 @@ func AdminTopicsCreate(cfg config.Config) http.HandlerFunc {
 			Title:       req.Title,
 			Description: req.Description,
 			ImageURL:    req.ImageURL,
-			Enabled:     true,  // Por defecto habilitado
+			Status:      req.Status,
+			Enabled:     req.Status == domain.TopicStatusActive,
 			Premium:     false, // Por defecto no premium
 			Type:        req.Type,
 			Order:       req.Order,
@@ -651,7 +697,7 @@ func AdminTopicsCreate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminTopicsCreate - Topic %d creado con type: %s, área: %d", topic.TopicID, topic.Type, topic.Area)
+		logf(r, "✅ AdminTopicsCreate - Topic %d creado con type: %s, área: %d", topic.TopicID, topic.Type, topic.Area)
 		writeJSON(w, http.StatusCreated, topic)
 	}
 }
@@ -681,6 +727,11 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 				writeError(w, http.StatusUnprocessableEntity, "validation_error", "area debe ser entre 1 y 10")
 				return
 			}
+			// Mover el topic a otra área requiere acceso también al área destino
+			if !areaScopeFromRequest(r).Allows(req.Area) {
+				writeAreaForbidden(w, req.Area)
+				return
+			}
 		}
 
 		// Validar tipo si se proporciona
@@ -691,6 +742,11 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 			}
 		}
 
+		if req.Status != "" && !domain.IsValidTopicStatus(req.Status) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "status debe ser 'active', 'hidden' o 'draft'")
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
@@ -703,12 +759,28 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 
 		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
 
+		// Estado: si no se envía status se conserva el guardado, salvo que el antiguo campo enabled
+		// cambie respecto a él (así un borrador no pasa a oculto por enviar enabled: false)
+		if req.Status == "" {
+			var current domain.Topic
+			if err := col.FindOne(ctx, bson.M{"id": id}, options.FindOne().SetProjection(bson.M{"status": 1, "enabled": 1})).Decode(&current); err != nil {
+				if err == mongo.ErrNoDocuments {
+					writeError(w, http.StatusNotFound, "not_found", "topic no encontrado")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			req.Status = updatedTopicStatus(current, req.Enabled)
+		}
+
 		update := bson.M{
 			"$set": bson.M{
 				"title":       req.Title,
 				"description": req.Description,
 				"imageUrl":    req.ImageURL,
-				"enabled":     req.Enabled,
+				"status":      req.Status,
+				"enabled":     req.Status == domain.TopicStatusActive,
 				"order":       req.Order,
 				"updatedAt":   time.Now(),
 			},
@@ -717,25 +789,25 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 		// Agregar rootId al update si se proporciona
 		if req.RootID != 0 {
 			update["$set"].(bson.M)["rootId"] = req.RootID
-			log.Printf("🔄 AdminTopicsUpdate - Actualizando rootId del topic %d a %d", id, req.RootID)
+			logf(r, "🔄 AdminTopicsUpdate - Actualizando rootId del topic %d a %d", id, req.RootID)
 		}
 
 		// Agregar rootUuid al update si se proporciona
 		if req.RootUUID != "" {
 			update["$set"].(bson.M)["rootUuid"] = req.RootUUID
-			log.Printf("🔄 AdminTopicsUpdate - Actualizando rootUuid del topic %d", id)
+			logf(r, "🔄 AdminTopicsUpdate - Actualizando rootUuid del topic %d", id)
 		}
 
 		// Agregar área al update si se proporciona
 		if req.Area != 0 {
 			update["$set"].(bson.M)["area"] = req.Area
-			log.Printf("🔄 AdminTopicsUpdate - Actualizando área del topic %d a %d", id, req.Area)
+			logf(r, "🔄 AdminTopicsUpdate - Actualizando área del topic %d a %d", id, req.Area)
 		}
 
 		// Agregar tipo al update si se proporciona
 		if req.Type != "" {
 			update["$set"].(bson.M)["type"] = req.Type
-			log.Printf("🔄 AdminTopicsUpdate - Actualizando type del topic %d a %s", id, req.Type)
+			logf(r, "🔄 AdminTopicsUpdate - Actualizando type del topic %d a %s", id, req.Type)
 		}
 
 		var topic domain.Topic
@@ -750,7 +822,7 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 
 		// Si se cambió el área o el tipo y es un tema principal, actualizar todos los subtopics
 		if (req.Area != 0 || req.Type != "") && topic.IsMainTopic() {
-			log.Printf("🔍 AdminTopicsUpdate - Es un tema principal, buscando subtopics con rootId=%d", id)
+			logf(r, "🔍 AdminTopicsUpdate - Es un tema principal, buscando subtopics con rootId=%d", id)
 
 			// Buscar todos los subtopics (donde rootId == id del tema principal y id != rootId)
 			subtopicsFilter := bson.M{
@@ -761,9 +833,9 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 			// Contar cuántos subtopics hay
 			subtopicsCount, err := col.CountDocuments(ctx, subtopicsFilter)
 			if err != nil {
-				log.Printf("⚠️ AdminTopicsUpdate - Error contando subtopics: %v", err)
+				logf(r, "⚠️ AdminTopicsUpdate - Error contando subtopics: %v", err)
 			} else {
-				log.Printf("📊 AdminTopicsUpdate - Encontrados %d subtopics para actualizar", subtopicsCount)
+				logf(r, "📊 AdminTopicsUpdate - Encontrados %d subtopics para actualizar", subtopicsCount)
 
 				if subtopicsCount > 0 {
 					// Preparar actualización de subtopics
@@ -787,26 +859,39 @@ func AdminTopicsUpdate(cfg config.Config) http.HandlerFunc {
 
 					updateResult, err := col.UpdateMany(ctx, subtopicsFilter, subtopicsUpdate)
 					if err != nil {
-						log.Printf("❌ AdminTopicsUpdate - Error actualizando subtopics: %v", err)
+						logf(r, "❌ AdminTopicsUpdate - Error actualizando subtopics: %v", err)
 						// No devolvemos error porque el topic principal sí se actualizó
 					} else {
 						if req.Area != 0 && req.Type != "" {
-							log.Printf("✅ AdminTopicsUpdate - %d subtopics actualizados (área: %d, type: %s)", updateResult.ModifiedCount, req.Area, req.Type)
+							logf(r, "✅ AdminTopicsUpdate - %d subtopics actualizados (área: %d, type: %s)", updateResult.ModifiedCount, req.Area, req.Type)
 						} else if req.Area != 0 {
-							log.Printf("✅ AdminTopicsUpdate - %d subtopics actualizados al área %d", updateResult.ModifiedCount, req.Area)
+							logf(r, "✅ AdminTopicsUpdate - %d subtopics actualizados al área %d", updateResult.ModifiedCount, req.Area)
 						} else if req.Type != "" {
-							log.Printf("✅ AdminTopicsUpdate - %d subtopics actualizados al type %s", updateResult.ModifiedCount, req.Type)
+							logf(r, "✅ AdminTopicsUpdate - %d subtopics actualizados al type %s", updateResult.ModifiedCount, req.Type)
 						}
 					}
 				}
 			}
 		}
 
-		log.Printf("✅ AdminTopicsUpdate - Topic %d actualizado exitosamente", id)
+		logf(r, "✅ AdminTopicsUpdate - Topic %d actualizado exitosamente", id)
 		writeJSON(w, http.StatusOK, topic)
 	}
 }
 
+// updatedTopicStatus resuelve el estado de un topic actualizado sin status: conserva el guardado
+// (o el equivalente de enabled en los temas anteriores al campo) salvo que enabled cambie
+func updatedTopicStatus(current domain.Topic, enabled bool) string {
+	status := current.Status
+	if status == "" {
+		status = domain.TopicStatusFromEnabled(current.Enabled)
+	}
+	if enabled != (status == domain.TopicStatusActive) {
+		return domain.TopicStatusFromEnabled(enabled)
+	}
+	return status
+}
+
 // AdminTopicsToggleEnabled - Cambiar estado enabled/disabled
 func AdminTopicsToggleEnabled(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -840,8 +925,11 @@ func AdminTopicsToggleEnabled(cfg config.Config) http.HandlerFunc {
 
 		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
 
+		// Compatibilidad: enabled se traduce al estado equivalente
+		status := domain.TopicStatusFromEnabled(req.Enabled)
 		update := bson.M{
 			"$set": bson.M{
+				"status":    status,
 				"enabled":   req.Enabled,
 				"updatedAt": time.Now(),
 			},
@@ -860,37 +948,105 @@ func AdminTopicsToggleEnabled(cfg config.Config) http.HandlerFunc {
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"_id":     idStr,
+			"status":  status,
 			"enabled": req.Enabled,
 			"message": "Estado del topic actualizado exitosamente",
 		})
 	}
 }
 
+// AdminTopicsSetStatus - Cambiar el estado (active/hidden/draft) de un topic
+func AdminTopicsSetStatus(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		// Convertir id de string a int
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "id debe ser un número")
+			return
+		}
+
+		var req struct {
+			Status string `json:"status"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		if !domain.IsValidTopicStatus(req.Status) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "status debe ser 'active', 'hidden' o 'draft'")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
+
+		update := bson.M{
+			"$set": bson.M{
+				"status":    req.Status,
+				"enabled":   req.Status == domain.TopicStatusActive,
+				"updatedAt": time.Now(),
+			},
+		}
+
+		result, err := col.UpdateOne(ctx, bson.M{"id": id}, update)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			writeError(w, http.StatusNotFound, "not_found", "topic no encontrado")
+			return
+		}
+
+		logf(r, "✅ AdminTopicsSetStatus - Topic %d actualizado a status: %s", id, req.Status)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"_id":     idStr,
+			"status":  req.Status,
+			"enabled": req.Status == domain.TopicStatusActive,
+			"message": "Estado del topic actualizado exitosamente",
+		})
+	}
+}
+
 // AdminTopicsTogglePremium - Cambiar estado premium/no-premium
 func AdminTopicsTogglePremium(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := chi.URLParam(r, "id")
-		log.Printf("🔍 Toggle Premium - ID string recibido: %s", idStr)
+		logf(r, "🔍 Toggle Premium - ID string recibido: %s", idStr)
 
 		// Convertir id de string a int
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			log.Printf("❌ Toggle Premium - Error al convertir ID: %v", err)
+			logf(r, "❌ Toggle Premium - Error al convertir ID: %v", err)
 			writeError(w, http.StatusBadRequest, "invalid_id", "id debe ser un número")
 			return
 		}
-		log.Printf("🔍 Toggle Premium - ID convertido a int: %d", id)
+		logf(r, "🔍 Toggle Premium - ID convertido a int: %d", id)
 
 		var req struct {
 			Premium bool `json:"premium"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("❌ Toggle Premium - Error al decodificar JSON: %v", err)
+			logf(r, "❌ Toggle Premium - Error al decodificar JSON: %v", err)
 			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
 			return
 		}
-		log.Printf("🔍 Toggle Premium - Valor premium recibido: %v", req.Premium)
+		logf(r, "🔍 Toggle Premium - Valor premium recibido: %v", req.Premium)
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -985,19 +1141,21 @@ func AdminStatsUser(cfg config.Config) http.HandlerFunc {
 		}
 		defer client.Disconnect(context.Background())
 
-		// Obtener usuario
+		// Obtener el usuario autenticado
 		users := client.Database(cfg.DBName).Collection("user")
 		var user domain.User
-		if err := users.FindOne(ctx, bson.M{}).Decode(&user); err != nil {
+		if err := users.FindOne(ctx, bson.M{"_id": r.Context().Value("user_id")}).Decode(&user); err != nil {
 			writeError(w, http.StatusNotFound, "not_found", "usuario administrador no encontrado")
 			return
 		}
 
-		// Obtener estadísticas de topics
+		// Obtener estadísticas de topics (limitadas a las áreas del administrador)
 		topics := client.Database(cfg.DBName).Collection("topics_uuid_map")
+		scope := areaScopeFromRequest(r)
 
-		totalTopics, _ := topics.CountDocuments(ctx, bson.M{})
-		enabledTopics, _ := topics.CountDocuments(ctx, bson.M{"enabled": true})
+		totalTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{}, "area"))
+		enabledTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{"status": domain.TopicStatusActive}, "area"))
+		draftTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{"status": domain.TopicStatusDraft}, "area"))
 		disabledTopics := totalTopics - enabledTopics
 
 		response := domain.UserStats{
@@ -1006,6 +1164,7 @@ func AdminStatsUser(cfg config.Config) http.HandlerFunc {
 				TotalTopics:    int(totalTopics),
 				EnabledTopics:  int(enabledTopics),
 				DisabledTopics: int(disabledTopics),
+				DraftTopics:    int(draftTopics),
 			},
 		}
 
@@ -1024,6 +1183,11 @@ func AdminStatsArea(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if !areaScopeFromRequest(r).Allows(areaId) {
+			writeAreaForbidden(w, areaId)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
@@ -1069,16 +1233,17 @@ func AdminStatsArea(cfg config.Config) http.HandlerFunc {
 		}
 		subtopics, _ := topicsCol.CountDocuments(ctx, subtopicsFilter)
 
-		// Lógica invertida: enabled:false = Habilitado, enabled:true = Deshabilitado
-		enabledTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": false})
+		// Topics por estado: solo "active" cuenta como habilitado
+		enabledTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "status": domain.TopicStatusActive})
+		draftTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "status": domain.TopicStatusDraft})
 		disabledTopics := totalTopics - enabledTopics
 
 		// Estadísticas de usuarios
 		usersCol := client.Database(cfg.DBName).Collection("users")
 
 		totalUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId})
-		// Lógica invertida: enabled:false = Habilitado, enabled:true = Deshabilitado
-		enabledUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": false})
+		// Lógica invertida de appUserDisabled: enabled:false = Habilitado
+		enabledUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": !appUserDisabled})
 		disabledUsers := totalUsers - enabledUsers
 
 		response := map[string]interface{}{
@@ -1090,6 +1255,7 @@ func AdminStatsArea(cfg config.Config) http.HandlerFunc {
 				"subtopics":  int(subtopics),
 				"enabled":    int(enabledTopics),
 				"disabled":   int(disabledTopics),
+				"draft":      int(draftTopics),
 			},
 			"users": map[string]interface{}{
 				"total":    int(totalUsers),
@@ -1135,9 +1301,13 @@ func AdminStatsAllAreas(cfg config.Config) http.HandlerFunc {
 
 		topicsCol := client.Database(cfg.DBName).Collection("topics_uuid_map")
 		usersCol := client.Database(cfg.DBName).Collection("users")
+		scope := areaScopeFromRequest(r)
 
 		for _, area := range areas {
 			areaId, _ := strconv.Atoi(area.ID)
+			if !scope.Allows(areaId) {
+				continue
+			}
 
 			// Estadísticas de topics
 			totalTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId})
@@ -1158,14 +1328,15 @@ func AdminStatsAllAreas(cfg config.Config) http.HandlerFunc {
 			}
 			subtopics, _ := topicsCol.CountDocuments(ctx, subtopicsFilter)
 
-			// Lógica invertida: enabled:false = Habilitado, enabled:true = Deshabilitado
-			enabledTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": false})
+			// Topics por estado: solo "active" cuenta como habilitado
+			enabledTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "status": domain.TopicStatusActive})
+			draftTopics, _ := topicsCol.CountDocuments(ctx, bson.M{"area": areaId, "status": domain.TopicStatusDraft})
 			disabledTopics := totalTopics - enabledTopics
 
 			// Estadísticas de usuarios
 			totalUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId})
-			// Lógica invertida: enabled:false = Habilitado, enabled:true = Deshabilitado
-			enabledUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": false})
+			// Lógica invertida de appUserDisabled: enabled:false = Habilitado
+			enabledUsers, _ := usersCol.CountDocuments(ctx, bson.M{"area": areaId, "enabled": !appUserDisabled})
 			disabledUsers := totalUsers - enabledUsers
 
 			areaStats := map[string]interface{}{
@@ -1177,6 +1348,7 @@ func AdminStatsAllAreas(cfg config.Config) http.HandlerFunc {
 					"subtopics":  int(subtopics),
 					"enabled":    int(enabledTopics),
 					"disabled":   int(disabledTopics),
+					"draft":      int(draftTopics),
 				},
 				"users": map[string]interface{}{
 					"total":    int(totalUsers),
@@ -1206,23 +1378,30 @@ func AdminStatsTopics(cfg config.Config) http.HandlerFunc {
 		defer client.Disconnect(context.Background())
 
 		topics := client.Database(cfg.DBName).Collection("topics_uuid_map")
+		scope := areaScopeFromRequest(r)
 
-		totalTopics, _ := topics.CountDocuments(ctx, bson.M{})
-		enabledTopics, _ := topics.CountDocuments(ctx, bson.M{"enabled": true})
+		totalTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{}, "area"))
+		enabledTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{"status": domain.TopicStatusActive}, "area"))
+		draftTopics, _ := topics.CountDocuments(ctx, scope.Restrict(bson.M{"status": domain.TopicStatusDraft}, "area"))
 		disabledTopics := totalTopics - enabledTopics
 
-		// Topics por área
+		// Topics por área (solo las áreas accesibles)
 		topicsByArea := make(map[string]int)
-		pnTopics, _ := topics.CountDocuments(ctx, bson.M{"area": 1})
-		psTopics, _ := topics.CountDocuments(ctx, bson.M{"area": 2})
-		topicsByArea["PN"] = int(pnTopics)
-		topicsByArea["PS"] = int(psTopics)
+		if scope.Allows(1) {
+			pnTopics, _ := topics.CountDocuments(ctx, bson.M{"area": 1})
+			topicsByArea["PN"] = int(pnTopics)
+		}
+		if scope.Allows(2) {
+			psTopics, _ := topics.CountDocuments(ctx, bson.M{"area": 2})
+			topicsByArea["PS"] = int(psTopics)
+		}
 
 		response := domain.TopicStats{
 			TotalTopics:    int(totalTopics),
 			TopicsByArea:   topicsByArea,
 			EnabledTopics:  int(enabledTopics),
 			DisabledTopics: int(disabledTopics),
+			DraftTopics:    int(draftTopics),
 		}
 
 		writeJSON(w, http.StatusOK, response)
@@ -1563,11 +1742,15 @@ func AdminUsersList(cfg config.Config) http.HandlerFunc {
 			// Si viene el parámetro area, usarlo
 			filterArea, err = strconv.Atoi(areaParam)
 			if err != nil {
-				log.Printf("❌ AdminUsersList - Error convirtiendo área: %v", err)
+				logf(r, "❌ AdminUsersList - Error convirtiendo área: %v", err)
 				writeError(w, http.StatusBadRequest, "invalid_area", "área debe ser un número válido")
 				return
 			}
-			log.Printf("🔍 AdminUsersList - Usando área del parámetro: %d", filterArea)
+			logf(r, "🔍 AdminUsersList - Usando área del parámetro: %d", filterArea)
+		} else if area := areaScopeFromRequest(r).DefaultArea(); area != 0 {
+			// Administrador con áreas asignadas: usar la primera
+			filterArea = area
+			logf(r, "🔍 AdminUsersList - Usando área asignada al administrador: %d", filterArea)
 		} else {
 			// Si no viene area, usar el área del admin logueado
 			userID := r.Context().Value("user_id")
@@ -1591,7 +1774,13 @@ func AdminUsersList(cfg config.Config) http.HandlerFunc {
 				writeError(w, http.StatusForbidden, "forbidden", "admin sin área asignada válida")
 				return
 			}
-			log.Printf("🔍 AdminUsersList - Usando área del admin: %d", filterArea)
+			logf(r, "🔍 AdminUsersList - Usando área del admin: %d", filterArea)
+		}
+
+		// Restringir a las áreas asignadas al administrador
+		if !areaScopeFromRequest(r).Allows(filterArea) {
+			writeAreaForbidden(w, filterArea)
+			return
 		}
 
 		// Construir filtro por área
@@ -1599,7 +1788,7 @@ func AdminUsersList(cfg config.Config) http.HandlerFunc {
 			"area": filterArea,
 		}
 
-		log.Printf("🔍 AdminUsersList - Filtro MongoDB: %+v", filter)
+		logf(r, "🔍 AdminUsersList - Filtro MongoDB: %+v", filter)
 
 		// Contar total de usuarios
 		total, err := col.CountDocuments(ctx, filter)
@@ -1608,7 +1797,7 @@ func AdminUsersList(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminUsersList - Total usuarios encontrados: %d", total)
+		logf(r, "🔍 AdminUsersList - Total usuarios encontrados: %d", total)
 
 		// Opciones de paginación con ordenamiento por createdAt descendente
 		skip := (page - 1) * limit
@@ -1628,7 +1817,7 @@ func AdminUsersList(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminUsersList - Usuarios recuperados: %d", len(users))
+		logf(r, "🔍 AdminUsersList - Usuarios recuperados: %d", len(users))
 
 		// Calcular páginas totales
 		totalPages := int(total) / limit
@@ -1676,6 +1865,23 @@ func AdminUsersToggleEnabled(cfg config.Config) http.HandlerFunc {
 
 		col := client.Database(cfg.DBName).Collection("users")
 
+		// Verificar que el usuario pertenece a un área del administrador
+		if scope := areaScopeFromRequest(r); !scope.all {
+			var appUser domain.User
+			if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&appUser); err != nil {
+				if err == mongo.ErrNoDocuments {
+					writeError(w, http.StatusNotFound, "not_found", "usuario no encontrado")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			if !scope.Allows(appUser.Area) {
+				writeAreaForbidden(w, appUser.Area)
+				return
+			}
+		}
+
 		update := bson.M{
 			"$set": bson.M{
 				"enabled":   req.Enabled,
@@ -1695,7 +1901,7 @@ func AdminUsersToggleEnabled(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminUsersToggleEnabled - Usuario %s actualizado a enabled: %v", id, req.Enabled)
+		logf(r, "✅ AdminUsersToggleEnabled - Usuario %s actualizado a enabled: %v", id, req.Enabled)
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"id":      id,
@@ -1864,7 +2070,7 @@ func AdminProvidersCreate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminProvidersCreate - Proveedor %s creado", req.ProviderID)
+		logf(r, "✅ AdminProvidersCreate - Proveedor %s creado", req.ProviderID)
 		writeJSON(w, http.StatusCreated, req)
 	}
 }
@@ -1920,7 +2126,7 @@ func AdminProvidersUpdate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminProvidersUpdate - Proveedor %s actualizado", id)
+		logf(r, "✅ AdminProvidersUpdate - Proveedor %s actualizado", id)
 		writeJSON(w, http.StatusOK, provider)
 	}
 }
@@ -1969,7 +2175,7 @@ func AdminProvidersToggleEnabled(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminProvidersToggleEnabled - Proveedor %s actualizado a enabled: %v", id, req.Enabled)
+		logf(r, "✅ AdminProvidersToggleEnabled - Proveedor %s actualizado a enabled: %v", id, req.Enabled)
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"id":      id,
@@ -2007,7 +2213,7 @@ func AdminProvidersDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminProvidersDelete - Proveedor %s eliminado", id)
+		logf(r, "✅ AdminProvidersDelete - Proveedor %s eliminado", id)
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"message":   "Proveedor eliminado exitosamente",
@@ -2051,7 +2257,7 @@ func AdminDatabaseStats(cfg config.Config) http.HandlerFunc {
 			// Contar documentos
 			docCount, err := collection.CountDocuments(ctx, bson.M{})
 			if err != nil {
-				log.Printf("⚠️ Error contando documentos en colección %s: %v", collectionName, err)
+				logf(r, "⚠️ Error contando documentos en colección %s: %v", collectionName, err)
 				docCount = 0
 			}
 
@@ -2059,7 +2265,7 @@ func AdminDatabaseStats(cfg config.Config) http.HandlerFunc {
 			var stats bson.M
 			err = database.RunCommand(ctx, bson.M{"collStats": collectionName}).Decode(&stats)
 			if err != nil {
-				log.Printf("⚠️ Error obteniendo estadísticas de colección %s: %v", collectionName, err)
+				logf(r, "⚠️ Error obteniendo estadísticas de colección %s: %v", collectionName, err)
 				stats = bson.M{"size": 0}
 			}
 
@@ -2087,7 +2293,7 @@ func AdminDatabaseStats(cfg config.Config) http.HandlerFunc {
 			TotalDocuments: totalDocuments,
 		}
 
-		log.Printf("✅ AdminDatabaseStats - Estadísticas obtenidas: %d colecciones, %d documentos, %d bytes", len(collectionStats), totalDocuments, totalSize)
+		logf(r, "✅ AdminDatabaseStats - Estadísticas obtenidas: %d colecciones, %d documentos, %d bytes", len(collectionStats), totalDocuments, totalSize)
 		writeJSON(w, http.StatusOK, response)
 	}
 }
@@ -2137,7 +2343,7 @@ func AdminDatabaseDownload(cfg config.Config) http.HandlerFunc {
 			// Obtener documentos con cursor para evitar cargar todo en memoria
 			cursor, err := collection.Find(ctx, bson.M{})
 			if err != nil {
-				log.Printf("⚠️ Error obteniendo documentos de colección %s: %v", collectionName, err)
+				logf(r, "⚠️ Error obteniendo documentos de colección %s: %v", collectionName, err)
 				w.Write([]byte("    ]"))
 				if i < len(collections)-1 {
 					w.Write([]byte(","))
@@ -2152,7 +2358,7 @@ func AdminDatabaseDownload(cfg config.Config) http.HandlerFunc {
 			for cursor.Next(ctx) {
 				var document bson.M
 				if err := cursor.Decode(&document); err != nil {
-					log.Printf("⚠️ Error decodificando documento en colección %s: %v", collectionName, err)
+					logf(r, "⚠️ Error decodificando documento en colección %s: %v", collectionName, err)
 					continue
 				}
 
@@ -2165,7 +2371,7 @@ func AdminDatabaseDownload(cfg config.Config) http.HandlerFunc {
 				// Convertir documento a JSON
 				docJSON, err := json.Marshal(document)
 				if err != nil {
-					log.Printf("⚠️ Error serializando documento en colección %s: %v", collectionName, err)
+					logf(r, "⚠️ Error serializando documento en colección %s: %v", collectionName, err)
 					continue
 				}
 				w.Write(docJSON)
@@ -2187,14 +2393,14 @@ func AdminDatabaseDownload(cfg config.Config) http.HandlerFunc {
 			}
 			w.Write([]byte("\n"))
 
-			log.Printf("✅ Colección %s exportada: %d documentos", collectionName, docCount)
+			logf(r, "✅ Colección %s exportada: %d documentos", collectionName, docCount)
 		}
 
 		// Cerrar JSON
 		w.Write([]byte("  }\n"))
 		w.Write([]byte("}\n"))
 
-		log.Printf("✅ AdminDatabaseDownload - Backup JSON enviado exitosamente: %s", backupFileName)
+		logf(r, "✅ AdminDatabaseDownload - Backup JSON enviado exitosamente: %s", backupFileName)
 	}
 }
 
@@ -2208,7 +2414,7 @@ func AdminGetAvailableSourceTopics(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminGetAvailableSourceTopics - Buscando temas origen para topicId: %d", topicId)
+		logf(r, "🔍 AdminGetAvailableSourceTopics - Buscando temas origen para topicId: %d", topicId)
 
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
@@ -2228,30 +2434,34 @@ func AdminGetAvailableSourceTopics(cfg config.Config) http.HandlerFunc {
 		var destTopic domain.Topic
 		if err := topicsCol.FindOne(ctx, bson.M{"id": topicId}).Decode(&destTopic); err != nil {
 			if err == mongo.ErrNoDocuments {
-				log.Printf("❌ AdminGetAvailableSourceTopics - Tema destino %d no encontrado", topicId)
+				logf(r, "❌ AdminGetAvailableSourceTopics - Tema destino %d no encontrado", topicId)
 				writeError(w, http.StatusNotFound, "topic_not_found", "tema destino no encontrado")
 				return
 			}
-			log.Printf("❌ AdminGetAvailableSourceTopics - Error buscando tema destino %d: %v", topicId, err)
+			logf(r, "❌ AdminGetAvailableSourceTopics - Error buscando tema destino %d: %v", topicId, err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 
-		log.Printf("✅ AdminGetAvailableSourceTopics - Tema destino encontrado: ID=%d, Área=%d, Title=%s", destTopic.TopicID, destTopic.Area, destTopic.Title)
+		logf(r, "✅ AdminGetAvailableSourceTopics - Tema destino encontrado: ID=%d, Área=%d, Title=%s", destTopic.TopicID, destTopic.Area, destTopic.Title)
 
 		// 2. Buscar temas principales de OTRAS áreas (excluyendo la del tema destino)
 		// Tema principal: id == rootId
 		filter := bson.M{
-			"area":    bson.M{"$ne": destTopic.Area},                  // Excluir el área del tema destino
-			"enabled": false,                                          // Solo temas habilitados (lógica invertida)
-			"$expr":   bson.M{"$eq": []interface{}{"$id", "$rootId"}}, // id == rootId (tema principal)
+			"area":   bson.M{"$ne": destTopic.Area},                  // Excluir el área del tema destino
+			"status": domain.TopicStatusActive,                       // Solo temas activos
+			"$expr":  bson.M{"$eq": []interface{}{"$id", "$rootId"}}, // id == rootId (tema principal)
+		}
+		// Solo áreas origen a las que el administrador tiene acceso
+		if scope := areaScopeFromRequest(r); !scope.all {
+			filter["area"] = bson.M{"$ne": destTopic.Area, "$in": scope.areas}
 		}
 
-		log.Printf("🔍 AdminGetAvailableSourceTopics - Filtro de búsqueda: %+v", filter)
+		logf(r, "🔍 AdminGetAvailableSourceTopics - Filtro de búsqueda: %+v", filter)
 
 		cur, err := topicsCol.Find(ctx, filter)
 		if err != nil {
-			log.Printf("❌ AdminGetAvailableSourceTopics - Error en consulta Find: %v", err)
+			logf(r, "❌ AdminGetAvailableSourceTopics - Error en consulta Find: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
@@ -2259,24 +2469,24 @@ func AdminGetAvailableSourceTopics(cfg config.Config) http.HandlerFunc {
 
 		var mainTopics []domain.Topic
 		if err := cur.All(ctx, &mainTopics); err != nil {
-			log.Printf("❌ AdminGetAvailableSourceTopics - Error en cur.All: %v", err)
+			logf(r, "❌ AdminGetAvailableSourceTopics - Error en cur.All: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 
-		log.Printf("🔍 AdminGetAvailableSourceTopics - Encontrados %d temas principales de otras áreas", len(mainTopics))
+		logf(r, "🔍 AdminGetAvailableSourceTopics - Encontrados %d temas principales de otras áreas", len(mainTopics))
 
 		// 3. Para cada tema principal, contar subtemas y preguntas
 		var sourceTopics []domain.SourceTopicInfo
 		for _, topic := range mainTopics {
 			// Contar subtemas: rootId == id del tema principal
 			subtopicFilter := bson.M{
-				"rootId":  topic.TopicID, // rootId debe ser igual al id del tema principal
-				"enabled": false,         // Lógica invertida
+				"rootId": topic.TopicID, // rootId debe ser igual al id del tema principal
+				"status": domain.TopicStatusActive,
 			}
 			subtopicCount, err := topicsCol.CountDocuments(ctx, subtopicFilter)
 			if err != nil {
-				log.Printf("Error contando subtemas para topic %s: %v", topic.UUID, err)
+				logf(r, "Error contando subtemas para topic %s: %v", topic.UUID, err)
 				subtopicCount = 0
 			}
 
@@ -2285,44 +2495,44 @@ func AdminGetAvailableSourceTopics(cfg config.Config) http.HandlerFunc {
 			allTopicIds = append(allTopicIds, topic.TopicID) // Añadir tema principal
 
 			// Añadir subtemas
-			log.Printf("🔍 AdminGetAvailableSourceTopics - Buscando subtemas para topic %s con rootId: %d", topic.Title, topic.TopicID)
-			log.Printf("🔍 AdminGetAvailableSourceTopics - Filtro subtemas: %+v", subtopicFilter)
+			logf(r, "🔍 AdminGetAvailableSourceTopics - Buscando subtemas para topic %s con rootId: %d", topic.Title, topic.TopicID)
+			logf(r, "🔍 AdminGetAvailableSourceTopics - Filtro subtemas: %+v", subtopicFilter)
 			subtopicCur, err := topicsCol.Find(ctx, subtopicFilter)
 			if err == nil {
 				var subtopics []domain.Topic
 				if err := subtopicCur.All(ctx, &subtopics); err == nil {
-					log.Printf("🔍 AdminGetAvailableSourceTopics - Encontrados %d subtemas para topic %s", len(subtopics), topic.Title)
+					logf(r, "🔍 AdminGetAvailableSourceTopics - Encontrados %d subtemas para topic %s", len(subtopics), topic.Title)
 					for _, subtopic := range subtopics {
-						log.Printf("  - Subtema: %s (ID: %d, rootId: %d)", subtopic.Title, subtopic.TopicID, subtopic.RootID)
+						logf(r, "  - Subtema: %s (ID: %d, rootId: %d)", subtopic.Title, subtopic.TopicID, subtopic.RootID)
 						allTopicIds = append(allTopicIds, subtopic.TopicID)
 					}
 				} else {
-					log.Printf("❌ Error en cur.All para subtemas de topic %s: %v", topic.Title, err)
+					logf(r, "❌ Error en cur.All para subtemas de topic %s: %v", topic.Title, err)
 				}
 				subtopicCur.Close(ctx)
 			} else {
-				log.Printf("❌ Error buscando subtemas para topic %s: %v", topic.Title, err)
+				logf(r, "❌ Error buscando subtemas para topic %s: %v", topic.Title, err)
 			}
 
 			// Verificar si hay algún tema con ese rootId (para debugging)
 			debugFilter := bson.M{"rootId": topic.TopicID}
 			debugCount, debugErr := topicsCol.CountDocuments(ctx, debugFilter)
 			if debugErr == nil {
-				log.Printf("🔍 AdminGetAvailableSourceTopics - Debug: %d temas tienen rootId = %d", debugCount, topic.TopicID)
+				logf(r, "🔍 AdminGetAvailableSourceTopics - Debug: %d temas tienen rootId = %d", debugCount, topic.TopicID)
 			}
 
 			// Contar preguntas totales (principal + subtemas)
 			questionCount := int64(0)
 			if len(allTopicIds) > 0 {
 				questionFilter := bson.M{"topicId": bson.M{"$in": allTopicIds}}
-				log.Printf("🔍 AdminGetAvailableSourceTopics - Contando preguntas para topic %s con topicIds: %+v", topic.Title, allTopicIds)
-				log.Printf("🔍 AdminGetAvailableSourceTopics - Filtro questions_units_uuid: %+v", questionFilter)
+				logf(r, "🔍 AdminGetAvailableSourceTopics - Contando preguntas para topic %s con topicIds: %+v", topic.Title, allTopicIds)
+				logf(r, "🔍 AdminGetAvailableSourceTopics - Filtro questions_units_uuid: %+v", questionFilter)
 				questionCount, err = questionsUnitsCol.CountDocuments(ctx, questionFilter)
 				if err != nil {
-					log.Printf("❌ Error contando preguntas para topic %s: %v", topic.UUID, err)
+					logf(r, "❌ Error contando preguntas para topic %s: %v", topic.UUID, err)
 					questionCount = 0
 				} else {
-					log.Printf("✅ AdminGetAvailableSourceTopics - Topic %s tiene %d preguntas", topic.Title, questionCount)
+					logf(r, "✅ AdminGetAvailableSourceTopics - Topic %s tiene %d preguntas", topic.Title, questionCount)
 				}
 			}
 
@@ -2339,7 +2549,7 @@ func AdminGetAvailableSourceTopics(cfg config.Config) http.HandlerFunc {
 			sourceTopics = append(sourceTopics, sourceTopic)
 		}
 
-		log.Printf("✅ AdminGetAvailableSourceTopics - Devolviendo %d temas origen", len(sourceTopics))
+		logf(r, "✅ AdminGetAvailableSourceTopics - Devolviendo %d temas origen", len(sourceTopics))
 		writeJSON(w, http.StatusOK, sourceTopics)
 	}
 }
@@ -2416,6 +2626,12 @@ func AdminCopyQuestionsFromTopics(cfg config.Config) http.HandlerFunc {
 				return
 			}
 
+			// Verificar que el administrador tiene acceso al área origen
+			if !areaScopeFromRequest(r).Allows(topic.Area) {
+				writeAreaForbidden(w, topic.Area)
+				return
+			}
+
 			sourceTopics = append(sourceTopics, topic)
 		}
 
@@ -2427,7 +2643,7 @@ func AdminCopyQuestionsFromTopics(cfg config.Config) http.HandlerFunc {
 			// Añadir subtemas
 			subtopicFilter := bson.M{
 				"rootUuid": topic.UUID,
-				"enabled":  false, // Lógica invertida
+				"status":   domain.TopicStatusActive,
 			}
 			subtopicCur, err := topicsCol.Find(ctx, subtopicFilter)
 			if err == nil {
@@ -2442,13 +2658,13 @@ func AdminCopyQuestionsFromTopics(cfg config.Config) http.HandlerFunc {
 		}
 
 		// 4. Obtener todas las preguntas de los temas origen
-		log.Printf("🔍 AdminCopyQuestionsFromTopics - UUIDs de temas origen: %+v", allSourceUuids)
+		logf(r, "🔍 AdminCopyQuestionsFromTopics - UUIDs de temas origen: %+v", allSourceUuids)
 		questionUnitsFilter := bson.M{"topicUuid": bson.M{"$in": allSourceUuids}}
-		log.Printf("🔍 AdminCopyQuestionsFromTopics - Filtro questions_units_uuid: %+v", questionUnitsFilter)
+		logf(r, "🔍 AdminCopyQuestionsFromTopics - Filtro questions_units_uuid: %+v", questionUnitsFilter)
 
 		questionUnitsCur, err := questionsUnitsCol.Find(ctx, questionUnitsFilter)
 		if err != nil {
-			log.Printf("❌ AdminCopyQuestionsFromTopics - Error en consulta questions_units_uuid: %v", err)
+			logf(r, "❌ AdminCopyQuestionsFromTopics - Error en consulta questions_units_uuid: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
@@ -2463,15 +2679,15 @@ func AdminCopyQuestionsFromTopics(cfg config.Config) http.HandlerFunc {
 
 		var questionUnits []QuestionUnit
 		if err := questionUnitsCur.All(ctx, &questionUnits); err != nil {
-			log.Printf("❌ AdminCopyQuestionsFromTopics - Error en cur.All: %v", err)
+			logf(r, "❌ AdminCopyQuestionsFromTopics - Error en cur.All: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 
-		log.Printf("🔍 AdminCopyQuestionsFromTopics - Encontradas %d unidades de preguntas", len(questionUnits))
+		logf(r, "🔍 AdminCopyQuestionsFromTopics - Encontradas %d unidades de preguntas", len(questionUnits))
 
 		if len(questionUnits) == 0 {
-			log.Printf("❌ AdminCopyQuestionsFromTopics - No hay preguntas en los temas seleccionados")
+			logf(r, "❌ AdminCopyQuestionsFromTopics - No hay preguntas en los temas seleccionados")
 			writeError(w, http.StatusUnprocessableEntity, "no_questions", "no hay preguntas disponibles en los temas seleccionados")
 			return
 		}
@@ -2640,7 +2856,7 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("🔍 AdminUploadQuestionsToTopic - Topic encontrado: ID=%d, UUID=%s, Area=%d", topic.TopicID, topic.UUID, topic.Area)
+		logf(r, "🔍 AdminUploadQuestionsToTopic - Topic encontrado: ID=%d, UUID=%s, Area=%d", topic.TopicID, topic.UUID, topic.Area)
 
 		// 2. Validar que el subtopic existe si se proporcionó
 		var subtopic *domain.Topic
@@ -2656,14 +2872,14 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 			}
 
 		// Validar que el subtopic pertenece al topic
-		log.Printf("🔍 AdminUploadQuestionsToTopic - Validación de subtopic: subtopic.rootId=%d, topic.TopicID=%d", st.RootID, topic.TopicID)
+		logf(r, "🔍 AdminUploadQuestionsToTopic - Validación de subtopic: subtopic.rootId=%d, topic.TopicID=%d", st.RootID, topic.TopicID)
 		if st.RootID != topic.TopicID {
 			writeError(w, http.StatusUnprocessableEntity, "validation_error", "el subtopic no pertenece al topic especificado")
 			return
 		}
 
 			subtopic = &st
-			log.Printf("🔍 AdminUploadQuestionsToTopic - Subtopic encontrado: ID=%d, UUID=%s", subtopic.TopicID, subtopic.UUID)
+			logf(r, "🔍 AdminUploadQuestionsToTopic - Subtopic encontrado: ID=%d, UUID=%s", subtopic.TopicID, subtopic.UUID)
 		}
 
 		// Determinar el topic/subtopic de destino
@@ -2683,7 +2899,7 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 				return
 			}
-			log.Printf("🔍 AdminUploadQuestionsToTopic - Eliminadas %d relaciones antiguas", deleteResult.DeletedCount)
+			logf(r, "🔍 AdminUploadQuestionsToTopic - Eliminadas %d relaciones antiguas", deleteResult.DeletedCount)
 		}
 
 		// 4. Obtener el máximo questionId para generar IDs únicos
@@ -2703,7 +2919,7 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 		}
 		cursor.Close(ctx)
 
-		log.Printf("🔍 AdminUploadQuestionsToTopic - Máximo questionId: %d", maxQuestionID)
+		logf(r, "🔍 AdminUploadQuestionsToTopic - Máximo questionId: %d", maxQuestionID)
 
 		// Obtener el máximo answer ID para generar IDs únicos
 		answerOpts := options.Find().SetSort(bson.D{{Key: "answers.id", Value: -1}}).SetLimit(1)
@@ -2727,7 +2943,7 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 		}
 		answerCursor.Close(ctx)
 
-		log.Printf("🔍 AdminUploadQuestionsToTopic - Máximo answerId: %d", maxAnswerID)
+		logf(r, "🔍 AdminUploadQuestionsToTopic - Máximo answerId: %d", maxAnswerID)
 
 		// 5. Preparar preguntas y relaciones para insertar
 		var questionsToInsert []interface{}
@@ -2783,12 +2999,12 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 
 			_, err = questionsCol.BulkWrite(ctx, questionOps)
 			if err != nil {
-				log.Printf("❌ AdminUploadQuestionsToTopic - Error insertando preguntas: %v", err)
+				logf(r, "❌ AdminUploadQuestionsToTopic - Error insertando preguntas: %v", err)
 				writeError(w, http.StatusInternalServerError, "server_error", "error insertando preguntas: "+err.Error())
 				return
 			}
 
-			log.Printf("✅ AdminUploadQuestionsToTopic - Insertadas %d preguntas", len(questionsToInsert))
+			logf(r, "✅ AdminUploadQuestionsToTopic - Insertadas %d preguntas", len(questionsToInsert))
 		}
 
 		if len(questionUnitsToInsert) > 0 {
@@ -2801,12 +3017,12 @@ func AdminUploadQuestionsToTopic(cfg config.Config) http.HandlerFunc {
 
 			_, err = questionsUnitsCol.BulkWrite(ctx, unitOps)
 			if err != nil {
-				log.Printf("❌ AdminUploadQuestionsToTopic - Error insertando relaciones: %v", err)
+				logf(r, "❌ AdminUploadQuestionsToTopic - Error insertando relaciones: %v", err)
 				writeError(w, http.StatusInternalServerError, "server_error", "error insertando relaciones: "+err.Error())
 				return
 			}
 
-			log.Printf("✅ AdminUploadQuestionsToTopic - Insertadas %d relaciones", len(questionUnitsToInsert))
+			logf(r, "✅ AdminUploadQuestionsToTopic - Insertadas %d relaciones", len(questionUnitsToInsert))
 		}
 
 		// 7. Obtener total de preguntas después de la operación
@@ -0,0 +1,449 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Tipos de token para establecer la contraseña de un administrador
+const (
+	adminTokenInvite         = "admin_invite"
+	adminTokenPasswordReset  = "admin_password_reset"
+	adminTokenForgotPassword = "admin_forgot_password" // Solicitado por el propio administrador
+)
+
+// ========== Establecer contraseña de administrador (invitación / restablecimiento) ==========
+
+// AdminPasswordSetupForm - Muestra el formulario HTML para establecer la contraseña (endpoint público GET)
+func AdminPasswordSetupForm(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			renderPasswordSetupResultPage(w, r, false, "Enlace no válido: falta el token.")
+			return
+		}
+
+		if _, err := validateAdminPasswordToken(token, cfg); err != nil {
+			logf(r, "❌ AdminPasswordSetupForm - Token inválido: %v", err)
+			renderPasswordSetupResultPage(w, r, false, "El enlace no es válido o ha expirado. Solicita uno nuevo.")
+			return
+		}
+
+		renderPasswordSetupFormPage(w, r, token, "", services.NewPasswordPolicy(cfg).Description())
+	}
+}
+
+// AdminPasswordSetupSubmit - Establece la contraseña a partir del token (endpoint público POST)
+func AdminPasswordSetupSubmit(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token           string `json:"token"`
+			Password        string `json:"password"`
+			ConfirmPassword string `json:"confirmPassword"`
+		}
+
+		// Aceptar JSON (frontend) o form-data (formulario HTML)
+		isJSON := strings.Contains(r.Header.Get("Content-Type"), "application/json")
+		if isJSON {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+				return
+			}
+			if req.ConfirmPassword == "" {
+				req.ConfirmPassword = req.Password
+			}
+		} else {
+			if err := r.ParseForm(); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "invalid form data")
+				return
+			}
+			req.Token = r.FormValue("token")
+			req.Password = r.FormValue("password")
+			req.ConfirmPassword = r.FormValue("confirmPassword")
+		}
+
+		// fail responde en el formato de la petición
+		fail := func(status int, code, message string) {
+			if isJSON {
+				writeError(w, status, code, message)
+				return
+			}
+			if code == "invalid_token" {
+				renderPasswordSetupResultPage(w, r, false, message)
+				return
+			}
+			renderPasswordSetupFormPage(w, r, req.Token, message, services.NewPasswordPolicy(cfg).Description())
+		}
+
+		claims, err := validateAdminPasswordToken(req.Token, cfg)
+		if err != nil {
+			logf(r, "❌ AdminPasswordSetupSubmit - Token inválido: %v", err)
+			fail(http.StatusBadRequest, "invalid_token", "El enlace no es válido o ha expirado. Solicita uno nuevo.")
+			return
+		}
+
+		if req.Password != req.ConfirmPassword {
+			fail(http.StatusUnprocessableEntity, "validation_error", "Las contraseñas no coinciden.")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
+			fail(http.StatusInternalServerError, "server_error", "Error interno del servidor. Por favor, intenta más tarde.")
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		users := client.Database(cfg.DBName).Collection("user")
+
+		userID, _ := claims["sub"].(string)
+		tokenID, _ := claims["jti"].(string)
+
+		var admin domain.User
+		if err := users.FindOne(ctx, bson.M{"_id": userID}).Decode(&admin); err != nil {
+			if err == mongo.ErrNoDocuments {
+				fail(http.StatusBadRequest, "invalid_token", "El enlace no es válido o ha expirado. Solicita uno nuevo.")
+				return
+			}
+			fail(http.StatusInternalServerError, "server_error", "Error interno del servidor. Por favor, intenta más tarde.")
+			return
+		}
+
+		policy := services.NewPasswordPolicy(cfg)
+		if err := validateAdminPassword(policy, req.Password, admin); err != nil {
+			fail(http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+
+		passwordFields, err := adminPasswordFields(policy, req.Password, admin)
+		if err != nil {
+			fail(http.StatusInternalServerError, "server_error", "No se pudo procesar la contraseña.")
+			return
+		}
+
+		// El token es de un solo uso: solo se acepta si coincide con el último emitido
+		result, err := users.UpdateOne(ctx, bson.M{"_id": userID, "passwordTokenId": tokenID}, bson.M{
+			"$set":   passwordFields,
+			"$unset": bson.M{"passwordTokenId": ""},
+		})
+		if err != nil {
+			logf(r, "❌ AdminPasswordSetupSubmit - Error actualizando contraseña: %v", err)
+			fail(http.StatusInternalServerError, "server_error", "Error al procesar la solicitud. Por favor, intenta más tarde.")
+			return
+		}
+		if result.MatchedCount == 0 {
+			fail(http.StatusBadRequest, "invalid_token", "Este enlace ya se ha utilizado o ha sido sustituido por uno más reciente.")
+			return
+		}
+
+		// Las sesiones abiertas con la contraseña anterior dejan de ser válidas
+		if err := revokeAdminSessions(ctx, client.Database(cfg.DBName), userID); err != nil {
+			logf(r, "⚠️ AdminPasswordSetupSubmit - Error revocando sesiones de %s: %v", userID, err)
+		}
+		// Quien controla el email puede desbloquear su cuenta restableciendo la contraseña
+		if email, _ := claims["email"].(string); email != "" {
+			clearLoginFailures(ctx, client.Database(cfg.DBName), email)
+		}
+
+		logf(r, "✅ AdminPasswordSetupSubmit - Contraseña establecida para administrador %s (%s)", userID, claims["type"])
+
+		if isJSON {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"message": "Contraseña establecida exitosamente",
+			})
+			return
+		}
+		renderPasswordSetupResultPage(w, r, true, "Tu contraseña se ha establecido correctamente. Ya puedes iniciar sesión en el panel de administración.")
+	}
+}
+
+// validateAdminPassword aplica la política de contraseñas y comprueba que no se reutilice
+// la contraseña actual ni las del historial del administrador
+func validateAdminPassword(policy *services.PasswordPolicy, password string, admin domain.User) error {
+	if err := policy.Validate(password, admin.Email); err != nil {
+		return err
+	}
+	if policy.ReusedIn(password, append([]string{admin.Password}, admin.PasswordHistory...)) {
+		return errors.New("no puedes reutilizar una contraseña anterior")
+	}
+	return nil
+}
+
+// adminPasswordFields genera el hash de la nueva contraseña y devuelve los campos a guardar,
+// moviendo la contraseña actual al historial
+func adminPasswordFields(policy *services.PasswordPolicy, password string, admin domain.User) (bson.M, error) {
+	hash, err := policy.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{
+		"password":        hash,
+		"passwordHistory": policy.History(admin.Password, admin.PasswordHistory),
+		"updatedAt":       time.Now(),
+	}, nil
+}
+
+// issueAdminPasswordToken genera un token de un solo uso y lo registra en el administrador,
+// invalidando cualquier enlace anterior
+func issueAdminPasswordToken(ctx context.Context, users *mongo.Collection, userID, email, tokenType string, cfg config.Config) (string, error) {
+	ttl := 24 * time.Hour
+	switch tokenType {
+	case adminTokenInvite:
+		ttl = 72 * time.Hour
+	case adminTokenForgotPassword:
+		ttl = time.Hour
+	}
+
+	tokenID := uuid.NewString()
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set": bson.M{"passwordTokenId": tokenID, "updatedAt": time.Now()},
+	}); err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":   userID,
+		"email": email,
+		"type":  tokenType,
+		"jti":   tokenID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(ttl).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWTSecret))
+}
+
+// validateAdminPasswordToken valida y decodifica un token de invitación o restablecimiento
+func validateAdminPasswordToken(tokenString string, cfg config.Config) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(cfg.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	// Verificar que el token es de establecimiento de contraseña
+	switch claims["type"] {
+	case adminTokenInvite, adminTokenPasswordReset, adminTokenForgotPassword:
+	default:
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	return claims, nil
+}
+
+// passwordSetupPageStyle contiene los estilos comunes de las páginas de contraseña
+const passwordSetupPageStyle = `
+        * {
+            margin: 0;
+            padding: 0;
+            box-sizing: border-box;
+        }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
+            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            padding: 20px;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 12px;
+            box-shadow: 0 10px 40px rgba(0,0,0,0.2);
+            max-width: 500px;
+            width: 100%;
+            padding: 40px;
+        }
+        h1 {
+            color: #2c3e50;
+            margin-bottom: 20px;
+            font-size: 28px;
+            text-align: center;
+        }
+        .footer {
+            margin-top: 30px;
+            padding-top: 20px;
+            border-top: 1px solid #eee;
+            font-size: 12px;
+            color: #777;
+            text-align: center;
+        }`
+
+// renderPasswordSetupFormPage renderiza el formulario HTML para establecer la contraseña
+func renderPasswordSetupFormPage(w http.ResponseWriter, r *http.Request, token, errorMessage, hint string) {
+	tmpl := `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Establecer contraseña</title>
+    <style>{{.Style}}
+        .form-group {
+            margin-bottom: 20px;
+        }
+        label {
+            display: block;
+            margin-bottom: 8px;
+            color: #333;
+            font-weight: 500;
+            font-size: 14px;
+        }
+        input[type="password"] {
+            width: 100%;
+            padding: 12px 15px;
+            border: 2px solid #e0e0e0;
+            border-radius: 8px;
+            font-size: 16px;
+        }
+        input[type="password"]:focus {
+            outline: none;
+            border-color: #667eea;
+        }
+        .error-message {
+            background-color: #f8d7da;
+            color: #721c24;
+            padding: 15px;
+            border-radius: 8px;
+            margin-bottom: 20px;
+        }
+        .hint {
+            color: #777;
+            font-size: 13px;
+            margin-top: 5px;
+        }
+        button {
+            width: 100%;
+            padding: 14px;
+            background-color: #667eea;
+            color: #ffffff;
+            border: none;
+            border-radius: 8px;
+            font-size: 16px;
+            font-weight: 600;
+            cursor: pointer;
+        }
+        button:hover {
+            background-color: #5a67d8;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Establecer contraseña</h1>
+        {{if .Error}}<div class="error-message">{{.Error}}</div>{{end}}
+        <form method="POST">
+            <input type="hidden" name="token" value="{{.Token}}">
+            <div class="form-group">
+                <label for="password">Nueva contraseña</label>
+                <input type="password" id="password" name="password" required autocomplete="new-password">
+                <p class="hint">{{.Hint}}</p>
+            </div>
+            <div class="form-group">
+                <label for="confirmPassword">Repite la contraseña</label>
+                <input type="password" id="confirmPassword" name="confirmPassword" required autocomplete="new-password">
+            </div>
+            <button type="submit">Guardar contraseña</button>
+        </form>
+        <div class="footer">
+            <p>Este enlace solo puede utilizarse una vez.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("password-setup-form").Parse(tmpl)
+	if err != nil {
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+
+	data := map[string]interface{}{
+		"Style": template.CSS(passwordSetupPageStyle),
+		"Token": token,
+		"Error": errorMessage,
+		"Hint":  hint,
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		logf(r, "❌ Error ejecutando template: %v", err)
+	}
+}
+
+// renderPasswordSetupResultPage renderiza la página HTML de resultado
+func renderPasswordSetupResultPage(w http.ResponseWriter, r *http.Request, success bool, message string) {
+	tmpl := `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{if .Success}}Contraseña establecida{{else}}Enlace no válido{{end}}</title>
+    <style>{{.Style}}
+        .message {
+            color: #555;
+            font-size: 16px;
+            line-height: 1.6;
+            text-align: center;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>{{if .Success}}✓ Contraseña establecida{{else}}✗ Enlace no válido{{end}}</h1>
+        <p class="message">{{.Message}}</p>
+        <div class="footer">
+            <p>Este es un mensaje automático del sistema.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("password-setup-result").Parse(tmpl)
+	if err != nil {
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+
+	data := map[string]interface{}{
+		"Style":   template.CSS(passwordSetupPageStyle),
+		"Success": success,
+		"Message": message,
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		logf(r, "❌ Error ejecutando template: %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	apiKeysCollection = "api_keys"
+
+	// Las keys tienen el formato opo_<prefijo>_<secreto>
+	apiKeyTokenPrefix = "opo_"
+
+	// Frecuencia máxima con la que se actualiza lastUsedAt
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// AdminAPIKeysList - Listar las API keys
+func AdminAPIKeysList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		filter := bson.M{}
+		if r.URL.Query().Get("includeRevoked") != "true" {
+			filter["revokedAt"] = bson.M{"$exists": false}
+		}
+
+		cursor, err := client.Database(cfg.DBName).Collection(apiKeysCollection).Find(ctx, filter,
+			options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cursor.Close(ctx)
+
+		keys := []domain.APIKey{}
+		if err := cursor.All(ctx, &keys); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// AdminAPIKeysCreate - Crear una API key (la key completa solo se devuelve en esta respuesta)
+func AdminAPIKeysCreate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name      string     `json:"name"`
+			Scopes    []string   `json:"scopes"`
+			Areas     []int      `json:"areas"`
+			ExpiresAt *time.Time `json:"expiresAt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "name requerido")
+			return
+		}
+		if err := validateAPIKeyScopes(req.Scopes); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+		for _, area := range req.Areas {
+			if area <= 0 {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "áreas inválidas")
+				return
+			}
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "expiresAt debe ser una fecha futura")
+			return
+		}
+		if req.Areas == nil {
+			req.Areas = []int{}
+		}
+
+		prefix := strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+		secret, err := newRefreshToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		key := apiKeyTokenPrefix + prefix + "_" + secret
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		apiKey := domain.APIKey{
+			ID:        uuid.NewString(),
+			Name:      req.Name,
+			Prefix:    prefix,
+			KeyHash:   hashRefreshToken(key),
+			Scopes:    req.Scopes,
+			Areas:     req.Areas,
+			ExpiresAt: req.ExpiresAt,
+			CreatedBy: userIDFromRequest(r),
+			CreatedAt: time.Now(),
+		}
+		if _, err := client.Database(cfg.DBName).Collection(apiKeysCollection).InsertOne(ctx, apiKey); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminAPIKeysCreate - API key '%s' creada (scopes: %v, áreas: %v)", apiKey.Name, apiKey.Scopes, apiKey.Areas)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"apiKey":  apiKey,
+			"key":     key,
+			"message": "Guarda la key ahora: no se podrá volver a consultar",
+		})
+	}
+}
+
+// AdminAPIKeysRevoke - Revocar una API key
+func AdminAPIKeysRevoke(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		result, err := client.Database(cfg.DBName).Collection(apiKeysCollection).UpdateOne(ctx,
+			bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.MatchedCount == 0 {
+			writeError(w, http.StatusNotFound, "not_found", "API key no encontrada o ya revocada")
+			return
+		}
+
+		logf(r, "✅ AdminAPIKeysRevoke - API key %s revocada", id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "API key revocada exitosamente",
+		})
+	}
+}
+
+// apiKeyFromRequest devuelve la API key enviada en X-API-Key o como Bearer con prefijo opo_
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")); strings.HasPrefix(auth, "Bearer ") && strings.HasPrefix(token, apiKeyTokenPrefix) {
+		return token
+	}
+	return ""
+}
+
+// checkAPIKey valida la key (existe, no revocada ni caducada) y registra su uso
+func checkAPIKey(ctx context.Context, cfg config.Config, key string, r *http.Request) (domain.APIKey, error) {
+	var apiKey domain.APIKey
+
+	client, err := getMongoClient(ctx, cfg)
+	if err != nil {
+		return apiKey, err
+	}
+	defer client.Disconnect(context.Background())
+
+	col := client.Database(cfg.DBName).Collection(apiKeysCollection)
+	if err := col.FindOne(ctx, bson.M{"keyHash": hashRefreshToken(key)}).Decode(&apiKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return apiKey, &authFailure{http.StatusUnauthorized, "unauthorized", "API key no válida"}
+		}
+		return apiKey, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return apiKey, &authFailure{http.StatusUnauthorized, "api_key_revoked", "la API key ha sido revocada"}
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now) {
+		return apiKey, &authFailure{http.StatusUnauthorized, "api_key_expired", "la API key ha caducado"}
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{
+			"$set": bson.M{"lastUsedAt": now, "lastUsedIp": clientIP(r)},
+		}); err != nil {
+			logf(r, "⚠️ Error registrando uso de la API key %s: %v", apiKey.ID, err)
+		}
+	}
+
+	return apiKey, nil
+}
+
+// validateAPIKeyScopes comprueba que los scopes existen y pueden concederse a una key
+func validateAPIKeyScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("scopes requeridos")
+	}
+	for _, scope := range scopes {
+		if !domain.IsValidAPIKeyScope(scope) {
+			return fmt.Errorf("scope no válido: %s", scope)
+		}
+	}
+	return nil
+}
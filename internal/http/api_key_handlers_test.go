@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoTestEnv prepara una base de datos temporal en el MongoDB de TEST_MONGO_URL. Sin
+// TEST_MONGO_URL la prueba se omite
+func mongoTestEnv(t *testing.T) (config.Config, *mongo.Database) {
+	t.Helper()
+	url := os.Getenv("TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("TEST_MONGO_URL no definida: se omite la prueba con MongoDB")
+	}
+
+	cfg := config.Config{
+		DBURL:  url,
+		DBName: "opo_admin_test_" + uuid.NewString()[:8],
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := getMongoClient(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(cfg.DBName)
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return cfg, db
+}
+
+// insertAPIKey guarda una API key con los scopes y áreas indicados (modificable con edit) y
+// devuelve la key completa
+func insertAPIKey(t *testing.T, db *mongo.Database, scopes []string, areas []int, edit func(*domain.APIKey)) string {
+	t.Helper()
+	prefix := uuid.NewString()[:8]
+	key := apiKeyTokenPrefix + prefix + "_" + uuid.NewString()
+	apiKey := domain.APIKey{
+		ID:        uuid.NewString(),
+		Name:      "test-" + prefix,
+		Prefix:    prefix,
+		KeyHash:   hashRefreshToken(key),
+		Scopes:    scopes,
+		Areas:     areas,
+		CreatedAt: time.Now(),
+	}
+	if edit != nil {
+		edit(&apiKey)
+	}
+	if _, err := db.Collection(apiKeysCollection).InsertOne(context.Background(), apiKey); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// callWithAPIKey hace la petición con la key en X-API-Key y devuelve el código HTTP y el de error
+func callWithAPIKey(handler http.Handler, method, path, key string) (int, string) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response.Code
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	cfg, db := mongoTestEnv(t)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	scopes := []string{domain.PermTopicsRead}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantCode   string
+	}{
+		{"válida", insertAPIKey(t, db, scopes, nil, nil), 0, ""},
+		{"con caducidad futura", insertAPIKey(t, db, scopes, nil, func(k *domain.APIKey) { k.ExpiresAt = &future }), 0, ""},
+		{"revocada", insertAPIKey(t, db, scopes, nil, func(k *domain.APIKey) { k.RevokedAt = &past }), http.StatusUnauthorized, "api_key_revoked"},
+		{"caducada", insertAPIKey(t, db, scopes, nil, func(k *domain.APIKey) { k.ExpiresAt = &past }), http.StatusUnauthorized, "api_key_expired"},
+		{"desconocida", apiKeyTokenPrefix + "00000000_" + uuid.NewString(), http.StatusUnauthorized, "unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/topics", nil)
+			_, err := checkAPIKey(context.Background(), cfg, tt.key, req)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("se esperaba una key válida: %v", err)
+				}
+				return
+			}
+			failure, ok := err.(*authFailure)
+			if !ok || failure.status != tt.wantStatus || failure.code != tt.wantCode {
+				t.Fatalf("error %v, se esperaba %d %s", err, tt.wantStatus, tt.wantCode)
+			}
+
+			// La key rechazada tampoco pasa por AuthJWT
+			status, code := callWithAPIKey(AuthJWT(cfg)(http.HandlerFunc(okHandler)), http.MethodGet, "/admin/topics", tt.key)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("AuthJWT: %d %s, se esperaba %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	cfg, db := mongoTestEnv(t)
+	key := insertAPIKey(t, db, []string{domain.PermTopicsRead, domain.PermDocumentsRead}, nil, nil)
+
+	tests := []struct {
+		permission string
+		wantStatus int
+	}{
+		{domain.PermTopicsRead, http.StatusOK},
+		{domain.PermDocumentsRead, http.StatusOK},
+		{domain.PermTopicsWrite, http.StatusForbidden},
+		{domain.PermDocumentsWrite, http.StatusForbidden},
+		{domain.PermStatsRead, http.StatusForbidden},
+		{domain.PermAdminsManage, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			handler := AuthJWT(cfg)(RequirePermission(tt.permission)(http.HandlerFunc(okHandler)))
+			if status, _ := callWithAPIKey(handler, http.MethodGet, "/admin/resource", key); status != tt.wantStatus {
+				t.Errorf("permiso %s: %d, se esperaba %d", tt.permission, status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAreas(t *testing.T) {
+	cfg, db := mongoTestEnv(t)
+	if _, err := db.Collection("topics_uuid_map").InsertMany(context.Background(), []interface{}{
+		domain.Topic{ID: uuid.NewString(), TopicID: 10, UUID: uuid.NewString(), Area: 1},
+		domain.Topic{ID: uuid.NewString(), TopicID: 20, UUID: uuid.NewString(), Area: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	scopes := []string{domain.PermTopicsRead}
+	areaKey := insertAPIKey(t, db, scopes, []int{1}, nil)
+	allAreasKey := insertAPIKey(t, db, scopes, []int{}, nil)
+
+	router := chi.NewRouter()
+	router.With(AuthJWT(cfg), RequirePermission(domain.PermTopicsRead), RequireTopicArea(cfg)).Get("/admin/topics/{id}", okHandler)
+
+	tests := []struct {
+		name       string
+		key        string
+		topicID    string
+		wantStatus int
+		wantCode   string
+	}{
+		{"área permitida", areaKey, "10", http.StatusOK, ""},
+		{"área no permitida", areaKey, "20", http.StatusForbidden, "forbidden_area"},
+		{"sin áreas: área 1", allAreasKey, "10", http.StatusOK, ""},
+		{"sin áreas: área 2", allAreasKey, "20", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := callWithAPIKey(router, http.MethodGet, "/admin/topics/"+tt.topicID, tt.key)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("topic %s: %d %s, se esperaba %d %s", tt.topicID, status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRejectAPIKey(t *testing.T) {
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{domain.RoleAPIKey, http.StatusForbidden},
+		{domain.RoleSuperadmin, http.StatusOK},
+		{domain.RoleViewer, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/user", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "user_role", tt.role))
+			rec := httptest.NewRecorder()
+			RejectAPIKey(http.HandlerFunc(okHandler)).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("rol %s: %d, se esperaba %d", tt.role, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
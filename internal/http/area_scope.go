@@ -0,0 +1,68 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"opo_admin_server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// areaScope representa las áreas a las que tiene acceso el administrador autenticado
+type areaScope struct {
+	all   bool  // superadmin: sin restricción de área
+	areas []int // Áreas asignadas (solo si all es false)
+}
+
+// areaScopeFromRequest obtiene el alcance por áreas a partir del token
+func areaScopeFromRequest(r *http.Request) areaScope {
+	role, _ := r.Context().Value("user_role").(string)
+	if role == domain.RoleSuperadmin {
+		return areaScope{all: true}
+	}
+	areas, _ := r.Context().Value("user_areas").([]int)
+	// Una API key sin áreas no tiene restricción de área
+	if role == domain.RoleAPIKey && len(areas) == 0 {
+		return areaScope{all: true}
+	}
+	if areas == nil {
+		areas = []int{}
+	}
+	return areaScope{areas: areas}
+}
+
+// Allows indica si el administrador puede acceder al área
+func (s areaScope) Allows(area int) bool {
+	if s.all {
+		return true
+	}
+	for _, a := range s.areas {
+		if a == area {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultArea devuelve el área a usar cuando la petición no indica ninguna (0 si no hay)
+func (s areaScope) DefaultArea() int {
+	if len(s.areas) > 0 {
+		return s.areas[0]
+	}
+	return 0
+}
+
+// Restrict añade al filtro la condición de área (no hace nada para superadmin)
+func (s areaScope) Restrict(filter bson.M, field string) bson.M {
+	if s.all {
+		return filter
+	}
+	filter[field] = bson.M{"$in": s.areas}
+	return filter
+}
+
+// writeAreaForbidden responde 403 indicando el área no permitida
+func writeAreaForbidden(w http.ResponseWriter, area int) {
+	writeError(w, http.StatusForbidden, "forbidden_area", fmt.Sprintf("sin acceso al área %d", area))
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"opo_admin_server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// withPrincipal devuelve la petición con el rol y las áreas que AuthJWT deja en el contexto
+func withPrincipal(r *http.Request, role string, areas []int) *http.Request {
+	ctx := context.WithValue(r.Context(), "user_role", role)
+	if areas != nil {
+		ctx = context.WithValue(ctx, "user_areas", areas)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestAreaScopeFromRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		role         string
+		areas        []int
+		wantAll      bool
+		allowed      []int
+		denied       []int
+		wantDefault  int
+		wantRestrict bson.M
+	}{
+		{"superadmin", domain.RoleSuperadmin, nil, true, []int{1, 2, 7}, nil, 0, bson.M{}},
+		{"superadmin con áreas", domain.RoleSuperadmin, []int{1}, true, []int{1, 2}, nil, 0, bson.M{}},
+		{"area-admin", domain.RoleAreaAdmin, []int{1, 3}, false, []int{1, 3}, []int{2}, 1, bson.M{"area": bson.M{"$in": []int{1, 3}}}},
+		{"area-admin sin áreas", domain.RoleAreaAdmin, nil, false, nil, []int{1, 2}, 0, bson.M{"area": bson.M{"$in": []int{}}}},
+		{"editor", domain.RoleEditor, []int{2}, false, []int{2}, []int{1}, 2, bson.M{"area": bson.M{"$in": []int{2}}}},
+		{"api key sin áreas", domain.RoleAPIKey, []int{}, true, []int{1, 2}, nil, 0, bson.M{}},
+		{"api key sin áreas en el contexto", domain.RoleAPIKey, nil, true, []int{1, 2}, nil, 0, bson.M{}},
+		{"api key con áreas", domain.RoleAPIKey, []int{2}, false, []int{2}, []int{1}, 2, bson.M{"area": bson.M{"$in": []int{2}}}},
+		{"sin rol", "", nil, false, nil, []int{1}, 0, bson.M{"area": bson.M{"$in": []int{}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := withPrincipal(httptest.NewRequest(http.MethodGet, "/admin/topics", nil), tt.role, tt.areas)
+			scope := areaScopeFromRequest(r)
+
+			if scope.all != tt.wantAll {
+				t.Errorf("all = %v, se esperaba %v", scope.all, tt.wantAll)
+			}
+			for _, area := range tt.allowed {
+				if !scope.Allows(area) {
+					t.Errorf("debería permitir el área %d", area)
+				}
+			}
+			for _, area := range tt.denied {
+				if scope.Allows(area) {
+					t.Errorf("no debería permitir el área %d", area)
+				}
+			}
+			if got := scope.DefaultArea(); got != tt.wantDefault {
+				t.Errorf("DefaultArea = %d, se esperaba %d", got, tt.wantDefault)
+			}
+			if got := scope.Restrict(bson.M{}, "area"); !reflect.DeepEqual(got, tt.wantRestrict) {
+				t.Errorf("Restrict = %v, se esperaba %v", got, tt.wantRestrict)
+			}
+		})
+	}
+}
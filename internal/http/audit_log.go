@@ -0,0 +1,523 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	auditLogCollection = "audit_log"
+
+	// Límite de cada acceso a MongoDB del middleware
+	auditTimeout = 10 * time.Second
+
+	// Tamaño máximo de respuesta que se inspecciona para obtener el id de una entidad creada
+	auditMaxResponseCapture = 64 << 10
+)
+
+// auditResource indica dónde se guarda la entidad de cada grupo de rutas de /admin
+type auditResource struct {
+	entityType string
+	collection string // Vacío = no se guardan snapshots
+	idField    string // Campo por el que se busca la entidad
+	numericID  bool   // El id de la ruta se guarda como número
+}
+
+// auditResources se indexa por el primer segmento de la ruta tras /admin
+var auditResources = map[string]auditResource{
+	"topics":           {"topic", "topics_uuid_map", "id", true},
+	"areas":            {"area", "apps", "id", false},
+	"users":            {"app_user", "users", "_id", false},
+	"admins":           {"admin", "user", "_id", false},
+	"user":             {"admin", "user", "_id", false},
+	"auth":             {"admin", "user", "_id", false},
+	"providers":        {"provider", "ad_providers", "_id", false},
+	"notifications":    {"notification", "notifications", "_id", false},
+	"privacy-policies": {"privacy_policy", "privacy_policies", "area", true},
+	"api-keys":         {"api_key", apiKeysCollection, "_id", false},
+	"ia-works":         {"document", "", "", false},
+}
+
+// Campos que nunca se copian a los snapshots de auditoría
+var auditRedactedFields = []string{
+	"password", "passwordHistory", "passwordTokenId", "twoFactor", "tokenVersion", "keyHash", "tokenHash",
+}
+
+// AuditLog - Middleware que registra en audit_log cada petición de escritura de un administrador
+// (quién, qué entidad, estado antes y después, IP y fecha). Debe ir después de AuthJWT.
+func AuditLog(cfg config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			segments := auditPathSegments(cfg, r)
+			action := auditAction(r.Method, segments)
+			if action == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entry := domain.AuditEntry{
+				ID:        uuid.NewString(),
+				Timestamp: time.Now(),
+				Action:    action,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				IP:        clientIP(r),
+				UserAgent: r.UserAgent(),
+			}
+			entry.ActorID, _ = r.Context().Value("user_id").(string)
+			entry.ActorEmail, _ = r.Context().Value("user_email").(string)
+			entry.ActorRole, _ = r.Context().Value("user_role").(string)
+
+			resource, entityID := auditTarget(segments, entry.ActorID)
+			entry.EntityType = resource.entityType
+			entry.RequestID = logging.RequestID(r.Context())
+
+			// Sin la cancelación de la petición: el registro se guarda aunque el cliente se desconecte.
+			// Cada fase tiene su propio límite porque el handler puede tardar más (clonaciones)
+			baseCtx := context.WithoutCancel(r.Context())
+			ctx, cancel := context.WithTimeout(baseCtx, auditTimeout)
+			defer cancel()
+
+			client, err := getMongoClient(ctx, cfg)
+			if err != nil {
+				// Sin auditoría no se permiten cambios
+				logf(r, "❌ AuditLog - Error conectando a MongoDB: %v", err)
+				writeError(w, http.StatusServiceUnavailable, "audit_unavailable", "no se puede registrar la operación en la auditoría")
+				return
+			}
+			defer client.Disconnect(context.Background())
+
+			db := client.Database(cfg.DBName)
+
+			// Las rutas que afectan a varias entidades (/topics/bulk, /topics/{id}/clone) las
+			// declaran con auditTrack antes de modificarlas
+			tracker := &auditTracker{ctx: baseCtx, db: db, resource: resource, before: map[string]map[string]interface{}{}}
+			if entityID != "" {
+				tracker.track(entityID)
+			}
+
+			recorder := &auditResponseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), auditTrackerKey{}, tracker)))
+
+			entry.Status = recorder.status
+			if routeCtx := chi.RouteContext(r.Context()); routeCtx != nil {
+				entry.Route = routeCtx.RoutePattern()
+			}
+
+			ctx, cancel = context.WithTimeout(baseCtx, auditTimeout)
+			defer cancel()
+
+			ids := tracker.entityIDs()
+			if len(ids) == 0 && recorder.status < 400 && action == "create" {
+				if createdID := auditCreatedID(recorder.body.Bytes(), resource); createdID != "" {
+					ids = []string{createdID}
+				}
+			}
+
+			var after map[string]map[string]interface{}
+			if recorder.status < 400 && action != "delete" {
+				after = loadAuditSnapshots(ctx, db, resource, ids)
+			}
+
+			// Una entrada por entidad afectada (todas con el mismo requestId)
+			entries := []interface{}{}
+			for _, id := range ids {
+				item := entry
+				item.ID = uuid.NewString()
+				item.EntityID = id
+				item.Before = tracker.before[id]
+				item.After = after[id]
+				entries = append(entries, item)
+			}
+			if len(entries) == 0 {
+				entries = append(entries, entry)
+			}
+
+			if _, err := db.Collection(auditLogCollection).InsertMany(ctx, entries); err != nil {
+				logf(r, "❌ AuditLog - Error registrando %s %s de %s: %v", entry.Method, entry.Path, entry.ActorEmail, err)
+			}
+		})
+	}
+}
+
+// auditTrackerKey guarda en el contexto de la petición el auditTracker del middleware
+type auditTrackerKey struct{}
+
+// auditTracker acumula las entidades afectadas por una petición y su estado anterior
+type auditTracker struct {
+	ctx      context.Context
+	db       *mongo.Database
+	resource auditResource
+	mu       sync.Mutex
+	ids      []string
+	before   map[string]map[string]interface{}
+}
+
+// track añade entidades (sin repetir) y guarda su snapshot actual como estado anterior
+func (t *auditTracker) track(ids ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var pending []string
+	for _, id := range ids {
+		if _, seen := t.before[id]; seen || id == "" {
+			continue
+		}
+		t.before[id] = nil
+		t.ids = append(t.ids, id)
+		pending = append(pending, id)
+	}
+	ctx, cancel := context.WithTimeout(t.ctx, auditTimeout)
+	defer cancel()
+	for id, snapshot := range loadAuditSnapshots(ctx, t.db, t.resource, pending) {
+		t.before[id] = snapshot
+	}
+}
+
+func (t *auditTracker) entityIDs() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.ids...)
+}
+
+// auditTrack declara las entidades que un handler va a modificar cuando la ruta no las
+// identifica. Debe llamarse antes del cambio para que la auditoría guarde el estado anterior;
+// las entidades que se van a crear no tienen estado anterior.
+func auditTrack(ctx context.Context, ids ...string) {
+	if tracker, ok := ctx.Value(auditTrackerKey{}).(*auditTracker); ok {
+		tracker.track(ids...)
+	}
+}
+
+// auditTrackInts es auditTrack para entidades con id numérico (topics)
+func auditTrackInts(ctx context.Context, ids ...int) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	auditTrack(ctx, values...)
+}
+
+// AdminAuditLogSearch - Buscar en el registro de auditoría por actor, entidad y rango de fechas
+func AdminAuditLogSearch(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := bson.M{}
+
+		if actor := strings.TrimSpace(query.Get("actor")); actor != "" {
+			filter["$or"] = []bson.M{{"actorId": actor}, {"actorEmail": strings.ToLower(actor)}}
+		}
+		for param, field := range map[string]string{"entityType": "entityType", "entityId": "entityId", "action": "action"} {
+			if value := strings.TrimSpace(query.Get(param)); value != "" {
+				filter[field] = value
+			}
+		}
+
+		dateRange := bson.M{}
+		for param, operator := range map[string]string{"from": "$gte", "to": "$lte"} {
+			value := query.Get(param)
+			if value == "" {
+				continue
+			}
+			t, err := parseAuditDate(value, param == "to")
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("%s debe ser una fecha (YYYY-MM-DD o RFC3339)", param))
+				return
+			}
+			dateRange[operator] = t
+		}
+		if len(dateRange) > 0 {
+			filter["timestamp"] = dateRange
+		}
+
+		page, _ := strconv.Atoi(query.Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		if limit < 1 || limit > 200 {
+			limit = 50
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection(auditLogCollection)
+
+		total, err := col.CountDocuments(ctx, filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		cursor, err := col.Find(ctx, filter, options.Find().
+			SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+			SetSkip(int64((page-1)*limit)).
+			SetLimit(int64(limit)))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cursor.Close(ctx)
+
+		entries := []domain.AuditEntry{}
+		for cursor.Next(ctx) {
+			var entry domain.AuditEntry
+			if err := decodeAuditDocument(cursor.Current, &entry); err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			entries = append(entries, entry)
+		}
+		if err := cursor.Err(); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"items": entries,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// auditAction traduce el método HTTP a la acción registrada (vacío = no se audita).
+// Un POST sobre una subruta es una acción concreta (invite, clone, logout...).
+func auditAction(method string, segments []string) string {
+	switch method {
+	case http.MethodPost:
+		if len(segments) > 1 {
+			return segments[len(segments)-1]
+		}
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return ""
+}
+
+// auditPathSegments devuelve los segmentos de la ruta tras /admin. Se calcula sobre la URL
+// porque los parámetros de chi aún no están resueltos en este middleware.
+func auditPathSegments(cfg config.Config, r *http.Request) []string {
+	path := strings.TrimPrefix(r.URL.Path, cfg.APIBasePath+"/admin/")
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// auditTarget identifica la entidad afectada y su id a partir de la ruta
+func auditTarget(segments []string, actorID string) (auditResource, string) {
+	name := segments[0]
+	if name == "security" && len(segments) > 1 {
+		switch segments[1] {
+		case "settings":
+			return auditResource{"security_settings", adminSettingsCollection, "_id", false}, "security"
+		case "lockouts":
+			return auditResource{"login_lockout", loginAttemptsCollection, "_id", false}, auditSegment(segments, 2)
+		}
+	}
+
+	resource, ok := auditResources[name]
+	if !ok {
+		return auditResource{entityType: name}, ""
+	}
+
+	var id string
+	switch name {
+	case "user", "auth":
+		// Operaciones sobre la propia cuenta
+		if !strings.HasPrefix(actorID, "apikey:") {
+			id = actorID
+		}
+	case "privacy-policies":
+		if auditSegment(segments, 1) == "area" {
+			id = auditSegment(segments, 2)
+		}
+	case "ia-works":
+		// Sin snapshots (copiarían el texto extraído), pero se guarda el id del documento
+		if auditSegment(segments, 1) == "documents" {
+			return resource, auditSegment(segments, 2)
+		}
+	default:
+		id = auditSegment(segments, 1)
+	}
+
+	if resource.collection == "" {
+		return resource, ""
+	}
+	if resource.numericID {
+		// Rutas como /topics/bulk no apuntan a una entidad concreta
+		if _, err := strconv.Atoi(id); err != nil {
+			return resource, ""
+		}
+	}
+	return resource, id
+}
+
+func auditSegment(segments []string, i int) string {
+	if i < len(segments) {
+		return segments[i]
+	}
+	return ""
+}
+
+// loadAuditSnapshots devuelve el documento actual de cada entidad (por id) sin los campos
+// secretos; las entidades que no existen no aparecen
+func loadAuditSnapshots(ctx context.Context, db *mongo.Database, resource auditResource, ids []string) map[string]map[string]interface{} {
+	if resource.collection == "" || len(ids) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if !resource.numericID {
+			values = append(values, id)
+			continue
+		}
+		if n, err := strconv.Atoi(id); err == nil {
+			values = append(values, n)
+		}
+	}
+
+	cursor, err := db.Collection(resource.collection).Find(ctx, bson.M{resource.idField: bson.M{"$in": values}})
+	if err != nil {
+		logging.Printf(ctx, "⚠️ AuditLog - Error leyendo %s %v: %v", resource.entityType, ids, err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make(map[string]map[string]interface{}, len(ids))
+	for cursor.Next(ctx) {
+		var snapshot map[string]interface{}
+		if err := decodeAuditDocument(cursor.Current, &snapshot); err != nil {
+			logging.Printf(ctx, "⚠️ AuditLog - Error decodificando %s: %v", resource.entityType, err)
+			continue
+		}
+		for _, field := range auditRedactedFields {
+			delete(snapshot, field)
+		}
+		snapshots[fmt.Sprint(snapshot[resource.idField])] = snapshot
+	}
+	if err := cursor.Err(); err != nil {
+		logging.Printf(ctx, "⚠️ AuditLog - Error leyendo %s %v: %v", resource.entityType, ids, err)
+	}
+	return snapshots
+}
+
+// decodeAuditDocument decodifica documentos anidados como mapas (no bson.D) para que
+// los snapshots se serialicen a JSON como objetos
+func decodeAuditDocument(raw bson.Raw, v interface{}) error {
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return err
+	}
+	dec.DefaultDocumentM()
+	return dec.Decode(v)
+}
+
+// auditCreatedID obtiene el id de la entidad creada de la respuesta JSON del handler
+// (en la raíz o dentro del primer objeto anidado, p. ej. {"admin": {...}})
+func auditCreatedID(body []byte, resource auditResource) string {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	field := "id"
+	if resource.idField == "area" {
+		field = "area"
+	}
+
+	candidates := []map[string]interface{}{response}
+	for _, value := range response {
+		if nested, ok := value.(map[string]interface{}); ok {
+			candidates = append(candidates, nested)
+		}
+	}
+	for _, candidate := range candidates {
+		switch id := candidate[field].(type) {
+		case string:
+			if id != "" {
+				return id
+			}
+		case float64:
+			return strconv.Itoa(int(id))
+		}
+	}
+	return ""
+}
+
+// parseAuditDate acepta YYYY-MM-DD (día completo en "to") o RFC3339
+func parseAuditDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// auditResponseRecorder guarda el código de estado y el inicio de la respuesta
+type auditResponseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *auditResponseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *auditResponseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	if remaining := auditMaxResponseCapture - rec.body.Len(); remaining > 0 {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		rec.body.Write(b[:remaining])
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *auditResponseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
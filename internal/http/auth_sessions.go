@@ -0,0 +1,456 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Colección donde se guardan las sesiones (refresh tokens) de los administradores
+const adminSessionsCollection = "admin_sessions"
+
+// sessionTokens es la pareja de tokens devuelta al iniciar sesión o rotar el refresh token
+type sessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // Segundos de validez del access token
+}
+
+// authFailure describe por qué se rechaza un access token válido por firma
+type authFailure struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *authFailure) Error() string { return e.message }
+
+// AuthRefresh - Rotar el refresh token y emitir un nuevo access token
+func AuthRefresh(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.RefreshToken) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "refreshToken requerido")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		sessions := db.Collection(adminSessionsCollection)
+
+		var session domain.AdminSession
+		if err := sessions.FindOne(ctx, bson.M{"tokenHash": hashRefreshToken(req.RefreshToken)}).Decode(&session); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "refresh token no válido")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Un refresh token ya rotado que vuelve a usarse indica que se ha filtrado:
+		// se revoca toda la familia de sesiones
+		if session.RevokedAt != nil {
+			logf(r, "⚠️ AuthRefresh - Reutilización de refresh token revocado (usuario %s, familia %s)", session.UserID, session.FamilyID)
+			if err := revokeSessionFamily(ctx, db, session.FamilyID); err != nil {
+				logf(r, "❌ AuthRefresh - Error revocando familia %s: %v", session.FamilyID, err)
+			}
+			writeError(w, http.StatusUnauthorized, "refresh_token_reused", "refresh token ya utilizado; inicia sesión de nuevo")
+			return
+		}
+
+		if time.Now().After(session.ExpiresAt) {
+			writeError(w, http.StatusUnauthorized, "refresh_token_expired", "refresh token caducado; inicia sesión de nuevo")
+			return
+		}
+
+		var user domain.User
+		if err := db.Collection("user").FindOne(ctx, bson.M{"_id": session.UserID}).Decode(&user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusUnauthorized, "invalid_refresh_token", "refresh token no válido")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if user.Disabled {
+			writeError(w, http.StatusForbidden, "account_disabled", "la cuenta de administrador está deshabilitada")
+			return
+		}
+
+		// Marcar la sesión actual como rotada; si otra petición se adelantó, se trata como reutilización
+		newSessionID := uuid.New().String()
+		result, err := sessions.UpdateOne(ctx,
+			bson.M{"_id": session.ID, "revokedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revokedAt": time.Now(), "replacedBy": newSessionID}},
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.ModifiedCount == 0 {
+			revokeSessionFamily(ctx, db, session.FamilyID)
+			writeError(w, http.StatusUnauthorized, "refresh_token_reused", "refresh token ya utilizado; inicia sesión de nuevo")
+			return
+		}
+
+		tokens, err := createAdminSessionWithID(ctx, db, user, newSessionID, session.FamilyID, r, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"token":        tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
+		})
+	}
+}
+
+// AuthLogout - Cerrar la sesión actual (revoca su refresh token)
+func AuthLogout(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, _ := r.Context().Value("session_id").(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		var session domain.AdminSession
+		if err := db.Collection(adminSessionsCollection).FindOne(ctx, bson.M{"_id": sessionID}).Decode(&session); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "sesión no encontrada")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if err := revokeSessionFamily(ctx, db, session.FamilyID); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AuthLogout - Sesión %s cerrada (usuario %s)", sessionID, session.UserID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Sesión cerrada exitosamente",
+		})
+	}
+}
+
+// AuthSessionsList - Listar las sesiones activas del administrador autenticado
+func AuthSessionsList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		sessionID, _ := r.Context().Value("session_id").(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		cursor, err := client.Database(cfg.DBName).Collection(adminSessionsCollection).Find(ctx,
+			bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}, "expiresAt": bson.M{"$gt": time.Now()}},
+			options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var sessions []domain.AdminSession
+		if err := cursor.All(ctx, &sessions); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		items := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			items = append(items, map[string]interface{}{
+				"id":        s.ID,
+				"ip":        s.IP,
+				"userAgent": s.UserAgent,
+				"createdAt": s.CreatedAt,
+				"expiresAt": s.ExpiresAt,
+				"current":   s.ID == sessionID,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// AuthSessionsRevokeAll - Cerrar todas las sesiones del administrador autenticado
+func AuthSessionsRevokeAll(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		if err := revokeAdminSessions(ctx, client.Database(cfg.DBName), userID); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AuthSessionsRevokeAll - Sesiones del usuario %s revocadas", userID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Todas las sesiones han sido cerradas",
+		})
+	}
+}
+
+// completeAdminLogin registra el último login, abre una sesión y devuelve la respuesta de login
+func completeAdminLogin(ctx context.Context, db *mongo.Database, user domain.User, r *http.Request, cfg config.Config) (map[string]interface{}, error) {
+	// Actualizar último login
+	db.Collection("user").UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"lastLogin": time.Now()},
+	})
+
+	// Crear la sesión: access token corto + refresh token rotativo
+	tokens, err := createAdminSession(ctx, db, user, "", r, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"user": map[string]interface{}{
+			"id":               user.ID,
+			"name":             user.Name,
+			"email":            user.Email,
+			"appId":            user.AppID,
+			"role":             user.EffectiveRole(),
+			"areas":            user.AssignedAreas(),
+			"twoFactorEnabled": user.TwoFactor != nil && user.TwoFactor.Enabled,
+			"createdAt":        user.CreatedAt.Format(time.RFC3339),
+			"updatedAt":        user.UpdatedAt.Format(time.RFC3339),
+		},
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+	}, nil
+}
+
+// createAdminSession crea una sesión nueva (o la siguiente de una familia) y devuelve sus tokens
+func createAdminSession(ctx context.Context, db *mongo.Database, user domain.User, familyID string, r *http.Request, cfg config.Config) (sessionTokens, error) {
+	return createAdminSessionWithID(ctx, db, user, uuid.New().String(), familyID, r, cfg)
+}
+
+func createAdminSessionWithID(ctx context.Context, db *mongo.Database, user domain.User, sessionID, familyID string, r *http.Request, cfg config.Config) (sessionTokens, error) {
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	if familyID == "" {
+		familyID = sessionID
+	}
+
+	now := time.Now()
+	session := domain.AdminSession{
+		ID:        sessionID,
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(refreshToken),
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(cfg.RefreshTokenTTL),
+	}
+	if _, err := db.Collection(adminSessionsCollection).InsertOne(ctx, session); err != nil {
+		return sessionTokens{}, err
+	}
+
+	return sessionTokens{
+		AccessToken:  generateJWT(user.ID, user.Email, user.EffectiveRole(), user.AssignedAreas(), sessionID, user.TokenVersion, cfg),
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(cfg.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeAdminSessions invalida todos los tokens del administrador: incrementa su
+// tokenVersion (access tokens en vuelo) y revoca sus refresh tokens
+func revokeAdminSessions(ctx context.Context, db *mongo.Database, userID string) error {
+	if _, err := db.Collection("user").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$inc": bson.M{"tokenVersion": 1},
+	}); err != nil {
+		return err
+	}
+	_, err := db.Collection(adminSessionsCollection).UpdateMany(ctx,
+		bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+// revokeSessionFamily revoca todas las sesiones derivadas del mismo login
+func revokeSessionFamily(ctx context.Context, db *mongo.Database, familyID string) error {
+	_, err := db.Collection(adminSessionsCollection).UpdateMany(ctx,
+		bson.M{"familyId": familyID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+// checkAdminSession comprueba que el access token sigue vigente en servidor: el
+// administrador existe y no está deshabilitado, su tokenVersion coincide y la
+// sesión no ha sido revocada. Devuelve el administrador actual.
+func checkAdminSession(ctx context.Context, cfg config.Config, claims jwt.MapClaims) (domain.User, error) {
+	userID, _ := claims["sub"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if userID == "" || sessionID == "" {
+		return domain.User{}, &authFailure{http.StatusUnauthorized, "unauthorized", "token sin sesión; inicia sesión de nuevo"}
+	}
+
+	client, err := getMongoClient(ctx, cfg)
+	if err != nil {
+		return domain.User{}, err
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(cfg.DBName)
+
+	var user domain.User
+	if err := db.Collection("user").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.User{}, &authFailure{http.StatusUnauthorized, "unauthorized", "usuario no encontrado"}
+		}
+		return domain.User{}, err
+	}
+	if user.Disabled {
+		return domain.User{}, &authFailure{http.StatusForbidden, "account_disabled", "la cuenta de administrador está deshabilitada"}
+	}
+
+	version, _ := claims["ver"].(float64)
+	if int(version) != user.TokenVersion {
+		return domain.User{}, &authFailure{http.StatusUnauthorized, "session_revoked", "la sesión ha sido revocada"}
+	}
+
+	count, err := db.Collection(adminSessionsCollection).CountDocuments(ctx, bson.M{
+		"_id":       sessionID,
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	if count == 0 {
+		// La sesión rotada sigue siendo válida para el access token emitido con ella
+		// mientras la familia tenga una sesión activa
+		var session domain.AdminSession
+		if err := db.Collection(adminSessionsCollection).FindOne(ctx, bson.M{"_id": sessionID}).Decode(&session); err != nil || session.ReplacedBy == "" {
+			return domain.User{}, &authFailure{http.StatusUnauthorized, "session_revoked", "la sesión ha sido revocada"}
+		}
+		active, err := db.Collection(adminSessionsCollection).CountDocuments(ctx, bson.M{
+			"familyId":  session.FamilyID,
+			"revokedAt": bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": time.Now()},
+		})
+		if err != nil {
+			return domain.User{}, err
+		}
+		if active == 0 {
+			return domain.User{}, &authFailure{http.StatusUnauthorized, "session_revoked", "la sesión ha sido revocada"}
+		}
+	}
+
+	return user, nil
+}
+
+// newRefreshToken genera un refresh token aleatorio opaco
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken devuelve el hash con el que se guarda el refresh token
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// trustedProxyHops es TRUSTED_PROXY_HOPS (se fija al crear el router)
+var trustedProxyHops int
+
+// clientIP devuelve la IP del cliente. Las entradas de la izquierda de X-Forwarded-For las
+// controla el cliente, así que solo se usa la que añadió el proxy de confianza más lejano
+// (contando desde la derecha); sin proxies de confianza se usa la IP de la conexión
+func clientIP(r *http.Request) string {
+	if trustedProxyHops > 0 {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			ip := entries[max(len(entries)-trustedProxyHops, 0)]
+			if net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRefreshTokenHelpers(t *testing.T) {
+	a, err := newRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || len(a) != 43 {
+		t.Errorf("tokens no aleatorios o con longitud inesperada: %q %q", a, b)
+	}
+	if hashRefreshToken(a) != hashRefreshToken(a) || hashRefreshToken(a) == hashRefreshToken(b) {
+		t.Error("el hash del refresh token debe ser determinista y distinto para cada token")
+	}
+	if hashRefreshToken(a) == a {
+		t.Error("el refresh token no debe guardarse en claro")
+	}
+}
+
+// refreshTestEnv prepara una base de datos temporal en el MongoDB de TEST_MONGO_URL con un
+// administrador y una sesión iniciada. Sin TEST_MONGO_URL la prueba se omite
+func refreshTestEnv(t *testing.T) (config.Config, *mongo.Database, domain.User, string) {
+	t.Helper()
+	url := os.Getenv("TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("TEST_MONGO_URL no definida: se omite la prueba con MongoDB")
+	}
+
+	cfg := config.Config{
+		DBURL:           url,
+		DBName:          "opo_admin_test_" + uuid.NewString()[:8],
+		JWTSecret:       "refresh-rotation-test-secret-0123456789",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := getMongoClient(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(cfg.DBName)
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	user := domain.User{ID: uuid.NewString(), Email: "admin@example.com", Role: domain.RoleSuperadmin}
+	if _, err := db.Collection("user").InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := createAdminSession(ctx, db, user, "", httptest.NewRequest(http.MethodPost, "/auth/login", nil), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, db, user, tokens.RefreshToken
+}
+
+// callRefresh llama a AuthRefresh y devuelve el código HTTP, el código de error y el nuevo refresh token
+func callRefresh(cfg config.Config, refreshToken string) (int, string, string) {
+	body, _ := json.Marshal(map[string]string{"refreshToken": refreshToken})
+	rec := httptest.NewRecorder()
+	AuthRefresh(cfg)(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewReader(body)))
+
+	var response struct {
+		Code         string `json:"code"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response.Code, response.RefreshToken
+}
+
+func TestAuthRefreshRotationAndReuse(t *testing.T) {
+	cfg, db, user, first := refreshTestEnv(t)
+
+	// Rotación: cada refresh devuelve un token nuevo y revoca el anterior
+	status, _, second := callRefresh(cfg, first)
+	if status != http.StatusOK || second == "" || second == first {
+		t.Fatalf("primer refresh: %d, token nuevo %q", status, second)
+	}
+	status, _, third := callRefresh(cfg, second)
+	if status != http.StatusOK || third == "" {
+		t.Fatalf("segundo refresh: %d", status)
+	}
+
+	// Reutilizar un token ya rotado revoca toda la familia, incluido el último token emitido
+	if status, code, _ := callRefresh(cfg, first); status != http.StatusUnauthorized || code != "refresh_token_reused" {
+		t.Fatalf("reutilización: %d %s, se esperaba 401 refresh_token_reused", status, code)
+	}
+	if status, code, _ := callRefresh(cfg, third); status != http.StatusUnauthorized || code != "refresh_token_reused" {
+		t.Errorf("token vigente tras la reutilización: %d %s, se esperaba 401 refresh_token_reused", status, code)
+	}
+
+	active, err := db.Collection(adminSessionsCollection).CountDocuments(context.Background(),
+		bson.M{"userId": user.ID, "revokedAt": bson.M{"$exists": false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active != 0 {
+		t.Errorf("quedan %d sesiones activas tras detectar la reutilización", active)
+	}
+
+	// Un login nuevo abre otra familia que no se ve afectada
+	tokens, err := createAdminSession(context.Background(), db, user, "", httptest.NewRequest(http.MethodPost, "/auth/login", nil), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := callRefresh(cfg, tokens.RefreshToken); status != http.StatusOK {
+		t.Errorf("refresh de una familia nueva: %d", status)
+	}
+}
+
+func TestAuthRefreshConcurrentUse(t *testing.T) {
+	cfg, _, _, token := refreshTestEnv(t)
+
+	// Dos peticiones con el mismo token: solo una puede rotarlo
+	var wg sync.WaitGroup
+	statuses := make([]int, 2)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], _, _ = callRefresh(cfg, token)
+		}(i)
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Errorf("respuestas %v: se esperaba exactamente un refresh correcto", statuses)
+	}
+}
+
+func TestAuthRefreshInvalidTokens(t *testing.T) {
+	cfg, db, _, token := refreshTestEnv(t)
+
+	if status, code, _ := callRefresh(cfg, "token-desconocido"); status != http.StatusUnauthorized || code != "invalid_refresh_token" {
+		t.Errorf("token desconocido: %d %s", status, code)
+	}
+
+	if _, err := db.Collection(adminSessionsCollection).UpdateOne(context.Background(),
+		bson.M{"tokenHash": hashRefreshToken(token)},
+		bson.M{"$set": bson.M{"expiresAt": time.Now().Add(-time.Minute)}}); err != nil {
+		t.Fatal(err)
+	}
+	if status, code, _ := callRefresh(cfg, token); status != http.StatusUnauthorized || code != "refresh_token_expired" {
+		t.Errorf("token caducado: %d %s", status, code)
+	}
+}
@@ -3,7 +3,6 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,13 +10,15 @@ import (
 
 	"opo_admin_server/internal/config"
 	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/services"
+	"opo_admin_server/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Healthz - Endpoint de salud
@@ -65,7 +66,7 @@ func TestLogin(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Generar token JWT simple
-		token := generateJWT("test-admin-id", "admin@example.com", config.Config{JWTSecret: "test-secret"})
+		token := generateJWT("test-admin-id", "admin@example.com", domain.RoleSuperadmin, []int{1}, "", 0, config.Config{JWTSecret: "test-secret"})
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"user":  user,
@@ -100,56 +101,102 @@ func AuthLogin(cfg config.Config) http.HandlerFunc {
 
 		client, err := getMongoClient(ctx, cfg)
 		if err != nil {
-			log.Printf("❌ Error conectando a MongoDB: %v", err)
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 		defer client.Disconnect(context.Background())
 
-		log.Printf("✅ Conexión a MongoDB exitosa")
-		log.Printf("🗄️ Base de datos: %s", cfg.DBName)
+		logf(r, "✅ Conexión a MongoDB exitosa")
+		logf(r, "🗄️ Base de datos: %s", cfg.DBName)
 
-		users := client.Database(cfg.DBName).Collection("user")
-		log.Printf("👤 Buscando usuario con email: %s", req.Email)
+		db := client.Database(cfg.DBName)
+		ip := clientIP(r)
+
+		// Protección contra fuerza bruta: bloqueo o backoff pendiente por IP o por email
+		wait, err := loginRetryAfter(ctx, db, ip, req.Email, cfg)
+		if err != nil {
+			logf(r, "❌ Error comprobando intentos de login: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if wait > 0 {
+			logf(r, "🔒 Login rechazado por intentos fallidos: %s (IP %s)", req.Email, ip)
+			writeTooManyAttempts(w, wait)
+			return
+		}
+
+		users := db.Collection("user")
+		logf(r, "👤 Buscando usuario con email: %s", req.Email)
 
 		var user domain.User
 		if err := users.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user); err != nil {
 			if err == mongo.ErrNoDocuments {
-				log.Printf("❌ Usuario no encontrado: %s", req.Email)
+				logf(r, "❌ Usuario no encontrado: %s", req.Email)
+				registerLoginFailure(ctx, db, ip, req.Email, nil, cfg)
 				writeError(w, http.StatusBadRequest, "invalid_credentials", "usuario o contraseña incorrectos")
 				return
 			}
-			log.Printf("❌ Error buscando usuario: %v", err)
+			logf(r, "❌ Error buscando usuario: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 
-		log.Printf("✅ Usuario encontrado: %s (ID: %s)", user.Name, user.ID)
+		logf(r, "✅ Usuario encontrado: %s (ID: %s)", user.Name, user.ID)
 
-		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
-			log.Printf("❌ Contraseña incorrecta para usuario: %s", req.Email)
+		policy := services.NewPasswordPolicy(cfg)
+		if !policy.Verify(user.Password, req.Password) {
+			logf(r, "❌ Contraseña incorrecta para usuario: %s", req.Email)
+			registerLoginFailure(ctx, db, ip, req.Email, &user, cfg)
 			writeError(w, http.StatusBadRequest, "invalid_credentials", "usuario o contraseña incorrectos")
 			return
 		}
 
-		log.Printf("✅ Contraseña correcta para usuario: %s", req.Email)
+		logf(r, "✅ Contraseña correcta para usuario: %s", req.Email)
+
+		// Rehash transparente si cambió el algoritmo o el coste configurado
+		if policy.NeedsRehash(user.Password) {
+			if hash, err := policy.Hash(req.Password); err == nil {
+				if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID, "password": user.Password}, bson.M{
+					"$set": bson.M{"password": hash},
+				}); err != nil {
+					logf(r, "⚠️ Error actualizando el hash de la contraseña de %s: %v", req.Email, err)
+				} else {
+					logf(r, "🔐 Hash de contraseña actualizado para %s", req.Email)
+				}
+			}
+		}
 
-		// Actualizar último login
-		users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
-			"$set": bson.M{"lastLogin": time.Now()},
-		})
+		// Cuentas deshabilitadas por un superadmin no pueden iniciar sesión
+		if user.Disabled {
+			logf(r, "❌ Cuenta deshabilitada: %s", req.Email)
+			writeError(w, http.StatusForbidden, "account_disabled", "la cuenta de administrador está deshabilitada")
+			return
+		}
 
-		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"user": map[string]interface{}{
-				"id":        user.ID,
-				"name":      user.Name,
-				"email":     user.Email,
-				"appId":     user.AppID,
-				"createdAt": user.CreatedAt.Format(time.RFC3339),
-				"updatedAt": user.UpdatedAt.Format(time.RFC3339),
-			},
-			"token": generateJWT(user.ID, user.Email, cfg),
-		})
+		// Segundo factor: si el administrador tiene TOTP activo (o es obligatorio y aún no
+		// lo ha configurado) se devuelve un challenge pendiente en lugar de los tokens
+		purpose, err := twoFactorPurpose(ctx, db, user)
+		if err != nil {
+			logf(r, "❌ Error leyendo configuración de seguridad: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if purpose != "" {
+			// El contador del email se reinicia cuando se supera también el segundo factor
+			writeTwoFactorChallenge(ctx, w, db, user, purpose)
+			return
+		}
+
+		clearLoginFailures(ctx, db, req.Email)
+		response, err := completeAdminLogin(ctx, db, user, r, cfg)
+		if err != nil {
+			logf(r, "❌ Error creando sesión: %v", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -174,11 +221,16 @@ func TopicListByArea(cfg config.Config) http.HandlerFunc {
 		}
 		defer client.Disconnect(context.Background())
 
-		// Consultar topics_uuid_map filtrado por área y habilitados
+		// Consultar topics_uuid_map filtrado por área, activos y dentro de su ventana de publicación
 		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
+		now := time.Now()
 		filter := bson.M{
-			"area":    areaInt, // Ahora es int directamente
-			"enabled": true,
+			"area":   areaInt, // Ahora es int directamente
+			"status": domain.TopicStatusActive,
+			"$and": []bson.M{
+				{"$or": []bson.M{{"publishAt": nil}, {"publishAt": bson.M{"$lte": now}}}},
+				{"$or": []bson.M{{"unpublishAt": nil}, {"unpublishAt": bson.M{"$gt": now}}}},
+			},
 		}
 
 		cur, err := col.Find(ctx, filter, options.Find())
@@ -252,15 +304,23 @@ func writeError(w http.ResponseWriter, status int, code, message string) {
 }
 
 func getMongoClient(ctx context.Context, cfg config.Config) (*mongo.Client, error) {
-	return mongo.Connect(ctx, options.Client().ApplyURI(cfg.DBURL))
+	return mongo.Connect(ctx, options.Client().ApplyURI(cfg.DBURL).SetMonitor(tracing.MongoMonitor(metrics.MongoMonitor())))
 }
 
-func generateJWT(userID, email string, cfg config.Config) string {
+func generateJWT(userID, email, role string, areas []int, sessionID string, tokenVersion int, cfg config.Config) string {
+	ttl := cfg.AccessTokenTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
 	claims := jwt.MapClaims{
 		"sub":   userID,
 		"email": email,
+		"role":  role,
+		"areas": areas,
+		"sid":   sessionID,
+		"ver":   tokenVersion,
 		"iat":   time.Now().Unix(),
-		"exp":   time.Now().Add(24 * time.Hour).Unix(),
+		"exp":   time.Now().Add(ttl).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	s, _ := token.SignedString([]byte(cfg.JWTSecret))
@@ -0,0 +1,527 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// documentFilesBucket es el bucket de GridFS con los archivos originales subidos a ia-works
+const documentFilesBucket = "document_files"
+
+// documentContentType devuelve el tipo MIME que usa el conversor para una extensión
+func documentContentType(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case ".doc":
+		return "application/msword"
+	case ".pdf":
+		return "application/pdf"
+	case ".txt":
+		return "text/plain"
+	}
+	return ""
+}
+
+// documentFiles abre el bucket de GridFS de los archivos originales
+func documentFiles(db *mongo.Database) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(db, options.GridFSBucket().SetName(documentFilesBucket))
+}
+
+// storeDocumentFile guarda en GridFS el archivo original de un documento (con el mismo id) y
+// devuelve su tamaño
+func storeDocumentFile(db *mongo.Database, documentID, fileName, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	bucket, err := documentFiles(db)
+	if err != nil {
+		return 0, err
+	}
+	if err := bucket.SetWriteDeadline(time.Now().Add(2 * time.Minute)); err != nil {
+		return 0, err
+	}
+	if err := bucket.UploadFromStreamWithID(documentID, fileName, f); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// documentWithoutText proyecta el documento sin el texto, calculando su longitud si es un
+// documento antiguo que no la tiene guardada
+var documentWithoutText = []bson.M{
+	{"$addFields": bson.M{"textLength": bson.M{"$ifNull": bson.A{"$textLength", bson.M{"$strLenCP": bson.M{"$ifNull": bson.A{"$text", ""}}}}}}},
+	{"$project": bson.M{"text": 0}},
+}
+
+// AdminIAWorksDocumentsList - Listar documentos paginados sin su texto (filtros: status, fileType, search)
+func AdminIAWorksDocumentsList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, _ := strconv.Atoi(query.Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		filter := bson.M{}
+		if status := strings.TrimSpace(query.Get("status")); status != "" {
+			filter["status"] = status
+		}
+		// fileType admite la extensión (pdf, .pdf) o el tipo MIME con el que se guardó
+		if fileType := strings.ToLower(strings.TrimSpace(query.Get("fileType"))); fileType != "" {
+			types := []string{fileType}
+			if !strings.Contains(fileType, "/") {
+				ext := "." + strings.TrimPrefix(fileType, ".")
+				types = []string{ext}
+				if contentType := documentContentType(ext); contentType != "" {
+					types = append(types, contentType)
+				}
+			}
+			filter["fileType"] = bson.M{"$in": types}
+		}
+		if search := strings.TrimSpace(query.Get("search")); search != "" {
+			filter["fileName"] = bson.M{"$regex": regexp.QuoteMeta(search), "$options": "i"}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("documents")
+
+		total, err := col.CountDocuments(ctx, filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		pipeline := append([]bson.M{
+			{"$match": filter},
+			{"$sort": bson.D{{Key: "createdAt", Value: -1}}},
+			{"$skip": int64((page - 1) * limit)},
+			{"$limit": int64(limit)},
+		}, documentWithoutText...)
+		cur, err := col.Aggregate(ctx, pipeline)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cur.Close(ctx)
+
+		documents := []domain.Document{}
+		if err := cur.All(ctx, &documents); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		totalPages := int(total) / limit
+		if int(total)%limit > 0 {
+			totalPages++
+		}
+
+		writeJSON(w, http.StatusOK, domain.PaginatedResponse{
+			Items: documents,
+			Pagination: domain.PaginationInfo{
+				Page:       page,
+				Limit:      limit,
+				Total:      int(total),
+				TotalPages: totalPages,
+			},
+		})
+	}
+}
+
+// AdminIAWorksDocumentGet - Obtener un documento. Con includeText=false se omite el texto extraído
+func AdminIAWorksDocumentGet(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		includeText := r.URL.Query().Get("includeText") != "false"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("documents")
+		filter := bson.M{"_id": chi.URLParam(r, "id")}
+
+		var document domain.Document
+		if includeText {
+			err = col.FindOne(ctx, filter).Decode(&document)
+			if err == nil && document.TextLength == 0 {
+				document.TextLength = utf8.RuneCountInString(document.Text)
+			}
+		} else {
+			var cur *mongo.Cursor
+			cur, err = col.Aggregate(ctx, append([]bson.M{{"$match": filter}}, documentWithoutText...))
+			if err == nil {
+				defer cur.Close(ctx)
+				if !cur.Next(ctx) {
+					err = cur.Err()
+					if err == nil {
+						err = mongo.ErrNoDocuments
+					}
+				} else {
+					err = cur.Decode(&document)
+				}
+			}
+		}
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "documento no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, document)
+	}
+}
+
+// AdminIAWorksDocumentUpdate - Renombrar un documento
+func AdminIAWorksDocumentUpdate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			FileName string `json:"fileName"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+		req.FileName = strings.TrimSpace(req.FileName)
+		if req.FileName == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "fileName requerido")
+			return
+		}
+		if len(req.FileName) > 255 || strings.ContainsAny(req.FileName, "/\\") {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "fileName no válido")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		id := chi.URLParam(r, "id")
+		var document domain.Document
+		err = client.Database(cfg.DBName).Collection("documents").FindOneAndUpdate(ctx, bson.M{"_id": id},
+			bson.M{"$set": bson.M{"fileName": req.FileName, "updatedAt": time.Now()}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"text": 0}),
+		).Decode(&document)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "documento no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✏️ Documento %s renombrado a %q", id, req.FileName)
+		writeJSON(w, http.StatusOK, document)
+	}
+}
+
+// AdminIAWorksDocumentExtract - Volver a extraer el texto a partir del archivo original guardado.
+// El documento vuelve a "uploaded" y hay que procesarlo de nuevo para actualizar sus vectores
+func AdminIAWorksDocumentExtract(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 2*time.Minute)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		documents := db.Collection("documents")
+
+		var document domain.Document
+		if err := documents.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"text": 0})).Decode(&document); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "documento no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if document.Status == "processing" {
+			writeError(w, http.StatusConflict, "conflict", "el documento se está procesando")
+			return
+		}
+
+		bucket, err := documentFiles(db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// Descargar el original a un archivo temporal para el conversor
+		tempDir := "/tmp/uploads"
+		if err := os.MkdirAll(tempDir, 0755); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", "error al crear directorio temporal")
+			return
+		}
+		tempFilePath := filepath.Join(tempDir, id+"-extract"+strings.ToLower(filepath.Ext(document.FileName)))
+		dst, err := os.Create(tempFilePath)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", "error al crear archivo temporal")
+			return
+		}
+		defer os.Remove(tempFilePath)
+
+		bucket.SetReadDeadline(time.Now().Add(2 * time.Minute))
+		_, err = bucket.DownloadToStream(id, dst)
+		dst.Close()
+		if err != nil {
+			if errors.Is(err, gridfs.ErrFileNotFound) {
+				writeError(w, http.StatusConflict, "conflict", "no se guardó el archivo original de este documento: vuelve a subirlo")
+				return
+			}
+			logf(r, "❌ Error al descargar el archivo original de %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, "server_error", "error al leer el archivo original")
+			return
+		}
+
+		text, err := services.ConvertFileToText(tempFilePath, document.FileType)
+		if err != nil {
+			logf(r, "❌ Error al convertir el documento %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, "conversion_error", fmt.Sprintf("error al convertir archivo: %v", err))
+			return
+		}
+
+		err = documents.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": bson.M{"$nin": bson.A{"processing", "deleting"}}}, bson.M{
+			"$set":   bson.M{"text": text, "textLength": utf8.RuneCountInString(text), "status": "uploaded", "updatedAt": time.Now()},
+			"$unset": bson.M{"error": "", "chunksCount": ""},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"text": 0})).Decode(&document)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusConflict, "conflict", "el documento se está procesando o borrando")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "📄 Texto del documento %s extraído de nuevo: %d caracteres", id, document.TextLength)
+		writeJSON(w, http.StatusOK, document)
+	}
+}
+
+// AdminIAWorksDocumentProcess - Volver a vectorizar un documento. Sin embeddingConfig en el body se
+// reutiliza la configuración de su último trabajo
+func AdminIAWorksDocumentProcess(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			EmbeddingConfig *domain.EmbeddingConfig `json:"embeddingConfig"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		var document domain.Document
+		if err := db.Collection("documents").FindOne(ctx, bson.M{"_id": chi.URLParam(r, "id")}).Decode(&document); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "documento no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		var embeddingConfig domain.EmbeddingConfig
+		if req.EmbeddingConfig != nil {
+			embeddingConfig = *req.EmbeddingConfig
+		} else {
+			var last domain.Job
+			if document.JobID == "" {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "embeddingConfig requerido (el documento no se ha procesado antes)")
+				return
+			}
+			if err := db.Collection(services.JobsCollection).FindOne(ctx, bson.M{"_id": document.JobID}).Decode(&last); err != nil {
+				if err == mongo.ErrNoDocuments {
+					writeError(w, http.StatusUnprocessableEntity, "validation_error", "embeddingConfig requerido (el último trabajo ya no existe)")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			embeddingConfig = last.EmbeddingConfig
+		}
+
+		if status, code, msg := validateEmbeddingConfig(cfg, embeddingConfig); status != 0 {
+			writeError(w, status, code, msg)
+			return
+		}
+
+		enqueueVectorize(ctx, w, r, cfg, db, document, embeddingConfig)
+	}
+}
+
+// AdminIAWorksDocumentDelete - Eliminar un documento, sus vectores y su archivo original.
+// Los trabajos pendientes o en curso del documento se cancelan
+func AdminIAWorksDocumentDelete(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 30*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		documents := db.Collection("documents")
+		jobs := db.Collection(services.JobsCollection)
+
+		// Sin vector store no se pueden borrar los vectores: se rechaza para no dejarlos huérfanos
+		store, err := services.NewVectorStore(cfg, db)
+		if err != nil {
+			logf(r, "❌ Vector store no disponible para borrar el documento %s: %v", id, err)
+			writeError(w, http.StatusServiceUnavailable, "vector_store_unavailable", "el vector store no está disponible; no se puede borrar el documento")
+			return
+		}
+
+		// El estado "deleting" avisa a los trabajos que terminen durante el borrado (también en otras
+		// instancias) de que deben volver a borrar los vectores que hayan guardado. Si el borrado
+		// falla el documento se queda en este estado y se puede reintentar
+		now := time.Now()
+		result, err := documents.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"status": "deleting", "updatedAt": now}})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.MatchedCount == 0 {
+			writeError(w, http.StatusNotFound, "not_found", "documento no encontrado")
+			return
+		}
+
+		// Cancelar los trabajos del documento para que no vuelvan a escribir vectores
+		if _, err := jobs.UpdateMany(ctx, bson.M{"documentId": id, "status": domain.JobStatusQueued}, bson.M{
+			"$set": bson.M{
+				"status":          domain.JobStatusCanceled,
+				"cancelRequested": true,
+				"lastError":       services.ErrJobCanceled.Error(),
+				"finishedAt":      now,
+				"updatedAt":       now,
+			},
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		cur, err := jobs.Find(ctx, bson.M{"documentId": id, "status": domain.JobStatusRunning}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		var running []domain.Job
+		if err := cur.All(ctx, &running); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		for _, job := range running {
+			if _, err := jobs.UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{"$set": bson.M{"cancelRequested": true, "updatedAt": now}}); err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			services.CancelLocalJob(job.ID)
+		}
+
+		// Borrar los vectores del documento. Si falla no se borra nada más para poder reintentarlo.
+		// Los trabajos de otras instancias pueden guardar algún lote más antes de ver la
+		// cancelación: al terminar vuelven a borrar el namespace (el documento está en "deleting")
+		if err := store.DeleteNamespace(ctx, services.DocumentNamespace(id)); err != nil {
+			logf(r, "❌ Error al borrar los vectores del documento %s: %v", id, err)
+			writeError(w, http.StatusBadGateway, "vector_store_error", "error al borrar los vectores del documento")
+			return
+		}
+
+		// Borrar el archivo original (los documentos antiguos no lo tienen)
+		if bucket, err := documentFiles(db); err == nil {
+			if err := bucket.DeleteContext(ctx, id); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+				logf(r, "⚠️ No se pudo borrar el archivo original del documento %s: %v", id, err)
+			}
+		}
+
+		if _, err := documents.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "🗑️ Documento %s eliminado (%d trabajos en curso cancelados)", id, len(running))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
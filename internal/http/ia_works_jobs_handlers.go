@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AdminIAWorksJobsList - Listar los trabajos más recientes (filtros: status, documentId, type)
+func AdminIAWorksJobsList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := bson.M{}
+		for param, field := range map[string]string{"status": "status", "documentId": "documentId", "type": "type"} {
+			if value := strings.TrimSpace(query.Get(param)); value != "" {
+				filter[field] = value
+			}
+		}
+
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		cursor, err := client.Database(cfg.DBName).Collection(services.JobsCollection).Find(ctx, filter, options.Find().
+			SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+			SetLimit(int64(limit)))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		jobs := []domain.Job{}
+		if err := cursor.All(ctx, &jobs); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"items": jobs,
+			"limit": limit,
+		})
+	}
+}
+
+// AdminIAWorksJobGet - Estado y progreso de un trabajo
+func AdminIAWorksJobGet(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		var job domain.Job
+		if err := client.Database(cfg.DBName).Collection(services.JobsCollection).FindOne(ctx, bson.M{"_id": chi.URLParam(r, "id")}).Decode(&job); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "trabajo no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+// AdminIAWorksJobCancel - Cancelar un trabajo pendiente o en curso. Los pendientes se cancelan al
+// momento; los que están en curso se detienen en su siguiente avance
+func AdminIAWorksJobCancel(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		jobs := client.Database(cfg.DBName).Collection(services.JobsCollection)
+		now := time.Now()
+
+		// Pendiente: se cancela directamente
+		var job domain.Job
+		err = jobs.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": domain.JobStatusQueued}, bson.M{
+			"$set": bson.M{
+				"status":          domain.JobStatusCanceled,
+				"cancelRequested": true,
+				"lastError":       services.ErrJobCanceled.Error(),
+				"finishedAt":      now,
+				"updatedAt":       now,
+			},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&job)
+		if err == nil {
+			client.Database(cfg.DBName).Collection("documents").UpdateOne(ctx,
+				bson.M{"_id": job.DocumentID, "jobId": job.ID, "status": "processing"},
+				bson.M{"$set": bson.M{"status": "uploaded", "updatedAt": now}})
+			logf(r, "🛑 Trabajo %s cancelado", id)
+			writeJSON(w, http.StatusOK, job)
+			return
+		}
+		if err != mongo.ErrNoDocuments {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// En curso: lo detiene el worker que lo ejecuta
+		err = jobs.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": domain.JobStatusRunning}, bson.M{
+			"$set": bson.M{"cancelRequested": true, "updatedAt": now},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&job)
+		if err == nil {
+			services.CancelLocalJob(id)
+			logf(r, "🛑 Cancelación solicitada para el trabajo %s", id)
+			writeJSON(w, http.StatusAccepted, job)
+			return
+		}
+		if err != mongo.ErrNoDocuments {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if err := jobs.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+			writeError(w, http.StatusNotFound, "not_found", "trabajo no encontrado")
+			return
+		}
+		writeError(w, http.StatusConflict, "conflict", "el trabajo ya ha terminado ("+job.Status+")")
+	}
+}
@@ -0,0 +1,249 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/lifecycle"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	loginAttemptsCollection = "login_attempts"
+
+	// Tiempo que se conservan los contadores sin nuevos fallos
+	loginAttemptRetention = 24 * time.Hour
+)
+
+// AdminLoginLockoutsList - Listar los contadores de intentos fallidos y bloqueos activos
+func AdminLoginLockoutsList(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		filter := bson.M{}
+		if kind := r.URL.Query().Get("kind"); kind != "" {
+			filter["kind"] = kind
+		}
+		if locked, _ := strconv.ParseBool(r.URL.Query().Get("locked")); locked {
+			filter["lockedUntil"] = bson.M{"$gt": time.Now()}
+		}
+
+		cursor, err := client.Database(cfg.DBName).Collection(loginAttemptsCollection).Find(ctx, filter,
+			options.Find().SetSort(bson.D{{Key: "lastFailureAt", Value: -1}}).SetLimit(500))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var attempts []domain.LoginAttempt
+		if err := cursor.All(ctx, &attempts); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		now := time.Now()
+		items := make([]map[string]interface{}, 0, len(attempts))
+		for _, a := range attempts {
+			items = append(items, map[string]interface{}{
+				"id":             a.ID,
+				"kind":           a.Kind,
+				"value":          a.Value,
+				"failures":       a.Failures,
+				"lockouts":       a.Lockouts,
+				"firstFailureAt": a.FirstFailureAt,
+				"lastFailureAt":  a.LastFailureAt,
+				"lockedUntil":    a.LockedUntil,
+				"locked":         a.LockedUntil != nil && a.LockedUntil.After(now),
+			})
+		}
+
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// AdminLoginLockoutsClear - Desbloquear un email o una IP (borra su contador)
+func AdminLoginLockoutsClear(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		result, err := client.Database(cfg.DBName).Collection(loginAttemptsCollection).DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.DeletedCount == 0 {
+			writeError(w, http.StatusNotFound, "not_found", "no hay intentos registrados para "+id)
+			return
+		}
+
+		logf(r, "✅ AdminLoginLockoutsClear - Bloqueo eliminado: %s (por %s)", id, userIDFromRequest(r))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Bloqueo eliminado exitosamente",
+			"id":      id,
+		})
+	}
+}
+
+// loginAttemptKeys devuelve los identificadores de los contadores de IP y email
+func loginAttemptKeys(ip, email string) (string, string) {
+	return "ip:" + ip, "email:" + email
+}
+
+// loginRetryAfter devuelve cuánto debe esperar el cliente antes de volver a intentar el
+// login: el tiempo restante de un bloqueo o del backoff exponencial tras el último fallo
+func loginRetryAfter(ctx context.Context, db *mongo.Database, ip, email string, cfg config.Config) (time.Duration, error) {
+	ipKey, emailKey := loginAttemptKeys(ip, email)
+
+	cursor, err := db.Collection(loginAttemptsCollection).Find(ctx, bson.M{"_id": bson.M{"$in": []string{ipKey, emailKey}}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []domain.LoginAttempt
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, a := range attempts {
+		if a.LockedUntil != nil && a.LockedUntil.After(now) {
+			wait = maxDuration(wait, a.LockedUntil.Sub(now))
+			continue
+		}
+		if a.Failures > 0 {
+			next := a.LastFailureAt.Add(loginBackoff(a.Failures, cfg))
+			if next.After(now) {
+				wait = maxDuration(wait, next.Sub(now))
+			}
+		}
+	}
+	return wait, nil
+}
+
+// loginBackoff calcula la espera exponencial tras n fallos consecutivos
+func loginBackoff(failures int, cfg config.Config) time.Duration {
+	backoff := cfg.LoginBackoffBase
+	for i := 1; i < failures && backoff < cfg.LoginLockoutDuration; i++ {
+		backoff *= 2
+	}
+	if backoff > cfg.LoginLockoutDuration {
+		backoff = cfg.LoginLockoutDuration
+	}
+	return backoff
+}
+
+// registerLoginFailure suma un fallo a los contadores de IP y email y aplica el bloqueo
+// al alcanzar el máximo. Si la cuenta (email) acaba de bloquearse se avisa a su titular.
+func registerLoginFailure(ctx context.Context, db *mongo.Database, ip, email string, user *domain.User, cfg config.Config) {
+	ipKey, emailKey := loginAttemptKeys(ip, email)
+
+	if _, err := incrementLoginFailures(ctx, db, ipKey, "ip", ip, cfg.LoginMaxAttemptsPerIP, cfg); err != nil {
+		logging.Printf(ctx, "❌ Error registrando intento fallido para %s: %v", ipKey, err)
+	}
+
+	attempt, err := incrementLoginFailures(ctx, db, emailKey, "email", email, cfg.LoginMaxAttempts, cfg)
+	if err != nil {
+		logging.Printf(ctx, "❌ Error registrando intento fallido para %s: %v", emailKey, err)
+		return
+	}
+
+	// Solo se notifica a cuentas existentes, para no revelar qué emails están registrados
+	if attempt != nil && user != nil {
+		until := *attempt.LockedUntil
+		lifecycle.Go("lockout-email", func() {
+			if err := services.NewEmailService(cfg).SendAdminLockoutEmail(context.WithoutCancel(ctx), user.Email, ip, cfg.LoginMaxAttempts, until); err != nil {
+				logging.Printf(ctx, "⚠️ Error enviando aviso de bloqueo a %s: %v", user.Email, err)
+			}
+		})
+	}
+}
+
+// incrementLoginFailures incrementa el contador indicado. Devuelve el contador solo si
+// este fallo ha provocado un bloqueo nuevo.
+func incrementLoginFailures(ctx context.Context, db *mongo.Database, id, kind, value string, maxAttempts int, cfg config.Config) (*domain.LoginAttempt, error) {
+	col := db.Collection(loginAttemptsCollection)
+	now := time.Now()
+
+	var attempt domain.LoginAttempt
+	err := col.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$inc":         bson.M{"failures": 1},
+			"$set":         bson.M{"lastFailureAt": now, "expiresAt": now.Add(loginAttemptRetention)},
+			"$setOnInsert": bson.M{"kind": kind, "value": value, "firstFailureAt": now, "lockouts": 0},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	if attempt.Failures < maxAttempts {
+		return nil, nil
+	}
+
+	// Bloqueo temporal; el contador vuelve a cero para la siguiente ronda
+	until := now.Add(cfg.LoginLockoutDuration)
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"lockedUntil": until, "failures": 0, "expiresAt": until.Add(loginAttemptRetention)},
+		"$inc": bson.M{"lockouts": 1},
+	}); err != nil {
+		return nil, err
+	}
+	logging.Printf(ctx, "🔒 Login bloqueado para %s hasta %s", id, until.Format(time.RFC3339))
+
+	attempt.LockedUntil = &until
+	return &attempt, nil
+}
+
+// clearLoginFailures borra el contador del email tras un login correcto
+func clearLoginFailures(ctx context.Context, db *mongo.Database, email string) {
+	_, emailKey := loginAttemptKeys("", email)
+	db.Collection(loginAttemptsCollection).DeleteOne(ctx, bson.M{"_id": emailKey})
+}
+
+// writeTooManyAttempts responde 429 indicando cuándo se puede reintentar
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(wait.Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeError(w, http.StatusTooManyRequests, "too_many_attempts",
+		"demasiados intentos fallidos; inténtalo de nuevo en "+strconv.Itoa(seconds)+" segundos")
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Las métricas de negocio salen de consultas a MongoDB; se recalculan como mucho cada 30s
+// aunque Prometheus haga scrape más a menudo
+const businessMetricsTTL = 30 * time.Second
+
+var (
+	businessMetricsMu      sync.Mutex
+	businessMetricsUpdated time.Time
+)
+
+// MetricsHandler - Expone las métricas en el formato de Prometheus. Si METRICS_TOKEN está
+// configurado exige "Authorization: Bearer <token>"
+func MetricsHandler(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.MetricsToken != "" {
+			auth := r.Header.Get("Authorization")
+			token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+			if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.MetricsToken)) != 1 {
+				writeError(w, http.StatusUnauthorized, "unauthorized", "token de métricas no válido")
+				return
+			}
+		}
+
+		refreshBusinessMetrics(r.Context(), cfg)
+
+		metrics.Handler().ServeHTTP(w, r)
+	}
+}
+
+// refreshBusinessMetrics recalcula los gauges de negocio si han caducado. Si MongoDB falla se
+// mantienen los últimos valores para no interrumpir el scrape
+func refreshBusinessMetrics(ctx context.Context, cfg config.Config) {
+	businessMetricsMu.Lock()
+	defer businessMetricsMu.Unlock()
+
+	if time.Since(businessMetricsUpdated) < businessMetricsTTL {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	client, err := getMongoClient(ctx, cfg)
+	if err != nil {
+		logging.Printf(ctx, "⚠️ Métricas - Error conectando a MongoDB: %v", err)
+		return
+	}
+	defer client.Disconnect(context.Background())
+
+	if err := collectBusinessMetrics(ctx, client.Database(cfg.DBName)); err != nil {
+		logging.Printf(ctx, "⚠️ Métricas - Error calculando métricas de negocio: %v", err)
+		return
+	}
+	businessMetricsUpdated = time.Now()
+}
+
+func collectBusinessMetrics(ctx context.Context, db *mongo.Database) error {
+	// Topics por área
+	cursor, err := db.Collection("topics_uuid_map").Aggregate(ctx, []bson.M{
+		{"$group": bson.M{"_id": "$area", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	var topicsByArea []struct {
+		Area  int   `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &topicsByArea); err != nil {
+		return err
+	}
+
+	// Preguntas
+	questions, err := db.Collection("questions").EstimatedDocumentCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Usuarios de la app, con la lógica invertida de appUserDisabled
+	usersCol := db.Collection("users")
+	totalUsers, err := usersCol.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	disabledUsers, err := usersCol.CountDocuments(ctx, bson.M{"enabled": appUserDisabled})
+	if err != nil {
+		return err
+	}
+
+	// Trabajos pendientes: publicaciones y despublicaciones programadas que el scheduler aún no ha hecho
+	topicsCol := db.Collection("topics_uuid_map")
+	pendingPublish, err := topicsCol.CountDocuments(ctx, bson.M{"publishAt": bson.M{"$ne": nil}, "publishedAt": nil})
+	if err != nil {
+		return err
+	}
+	pendingUnpublish, err := topicsCol.CountDocuments(ctx, bson.M{"unpublishAt": bson.M{"$ne": nil}, "unpublishedAt": nil})
+	if err != nil {
+		return err
+	}
+
+	// Trabajos de la cola (vectorización) pendientes o en curso
+	pendingVectorize, err := db.Collection(services.JobsCollection).CountDocuments(ctx, bson.M{
+		"type":   domain.JobTypeVectorize,
+		"status": bson.M{"$in": []string{domain.JobStatusQueued, domain.JobStatusRunning}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Se reinicia para que desaparezcan las áreas sin topics
+	metrics.TopicsByArea.Reset()
+	for _, area := range topicsByArea {
+		metrics.TopicsByArea.WithLabelValues(strconv.Itoa(area.Area)).Set(float64(area.Count))
+	}
+	metrics.Questions.Set(float64(questions))
+	metrics.Users.WithLabelValues("enabled").Set(float64(totalUsers - disabledUsers))
+	metrics.Users.WithLabelValues("disabled").Set(float64(disabledUsers))
+	metrics.PendingJobs.WithLabelValues("topic_publish").Set(float64(pendingPublish))
+	metrics.PendingJobs.WithLabelValues("topic_unpublish").Set(float64(pendingUnpublish))
+	metrics.PendingJobs.WithLabelValues(domain.JobTypeVectorize).Set(float64(pendingVectorize))
+	return nil
+}
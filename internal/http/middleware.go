@@ -2,32 +2,215 @@ package http
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/tracing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// maxRequestIDLength limita el X-Request-ID aceptado del cliente
+const maxRequestIDLength = 64
+
+// RequestID - Middleware que asigna un id a cada petición (o reutiliza el X-Request-ID recibido),
+// lo devuelve en la respuesta y lo añade al contexto para que aparezca en los logs
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// RequestLogger - Middleware que registra cada petición con su estado y duración
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= 500:
+			level = slog.LevelError
+		case recorder.status >= 400:
+			level = slog.LevelWarn
+		case r.Method == http.MethodOptions || isProbePath(r.URL.Path):
+			level = slog.LevelDebug
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", recorder.bytes),
+			slog.String("ip", clientIP(r)),
+			slog.String("origin", r.Header.Get("Origin")),
+		)
+	})
+}
+
+// Metrics - Middleware que mide cada petición por patrón de ruta de chi
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		metrics.RequestStarted()
+		defer metrics.RequestFinished()
+		next.ServeHTTP(recorder, r)
+
+		metrics.ObserveRequest(r.Method, routePattern(r), recorder.status, time.Since(start))
+	})
+}
+
+// Tracing - Middleware que abre un span por petición con otelhttp (continuando la traza del
+// traceparent recibido y respetando su flag sampled) del que cuelgan los spans de MongoDB y de
+// los servicios externos
+func Tracing(next http.Handler) http.Handler {
+	traced := otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(attribute.String("request.id", logging.RequestID(r.Context())))
+
+		next.ServeHTTP(w, r)
+
+		// El patrón de la ruta solo se conoce cuando chi ha resuelto la petición
+		route := routePattern(r)
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+	}), "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		traced.ServeHTTP(w, r)
+	})
+}
+
+// routePattern devuelve el patrón de chi que atendió la petición (p. ej. /admin/topics/{id})
+// para no crear una serie o un nombre de span por cada id
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
+
+// isProbePath indica si la petición es de una sonda (se registra solo en debug)
+func isProbePath(path string) bool {
+	return strings.HasSuffix(path, "/healthz") || strings.HasSuffix(path, "/livez") || strings.HasSuffix(path, "/readyz") || path == "/metrics"
+}
+
+// logf registra un mensaje con el request ID de la petición (nivel según el prefijo ❌/⚠️/🔍)
+func logf(r *http.Request, format string, args ...interface{}) {
+	logging.Printf(r.Context(), format, args...)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder guarda el código de estado y los bytes escritos
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // AuthJWT - Middleware de autenticación JWT
 func AuthJWT(cfg config.Config) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Permitir peticiones OPTIONS sin autenticación (preflight CORS)
 			if r.Method == "OPTIONS" {
-				log.Printf("✅ AuthJWT Middleware - OPTIONS request, skipping auth")
+				logf(r, "✅ AuthJWT Middleware - OPTIONS request, skipping auth")
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			// Alternativa al JWT: API key para scripts y otros servicios
+			if key := apiKeyFromRequest(r); key != "" {
+				ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+				apiKey, err := checkAPIKey(ctx, cfg, key, r)
+				cancel()
+				if err != nil {
+					if failure, ok := err.(*authFailure); ok {
+						logf(r, "❌ AuthJWT Middleware - API key rechazada: %s", failure.message)
+						writeError(w, failure.status, failure.code, failure.message)
+						return
+					}
+					logf(r, "❌ AuthJWT Middleware - Error comprobando API key: %v", err)
+					writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+					return
+				}
+
+				logf(r, "✅ AuthJWT Middleware - API key válida: %s (%s)", apiKey.Name, apiKey.Prefix)
+				reqCtx := context.WithValue(r.Context(), "user_id", "apikey:"+apiKey.ID)
+				reqCtx = context.WithValue(reqCtx, "user_email", "apikey:"+apiKey.Name)
+				reqCtx = context.WithValue(reqCtx, "user_role", domain.RoleAPIKey)
+				reqCtx = context.WithValue(reqCtx, "user_areas", apiKey.Areas)
+				reqCtx = context.WithValue(reqCtx, "api_key_scopes", apiKey.Scopes)
+				next.ServeHTTP(w, r.WithContext(reqCtx))
+				return
+			}
+
 			auth := r.Header.Get("Authorization")
-			log.Printf("🔍 AuthJWT Middleware - URL: %s", r.URL.Path)
-			log.Printf("🔍 AuthJWT Middleware - Authorization header: %s", auth)
+			logf(r, "🔍 AuthJWT Middleware - URL: %s", r.URL.Path)
 
 			if !strings.HasPrefix(auth, "Bearer ") {
-				log.Printf("❌ AuthJWT Middleware - Missing Bearer token")
+				logf(r, "❌ AuthJWT Middleware - Missing Bearer token")
 				writeError(w, http.StatusUnauthorized, "unauthorized", "missing bearer token")
 				return
 			}
@@ -41,21 +224,140 @@ func AuthJWT(cfg config.Config) func(http.Handler) http.Handler {
 			})
 
 			if err != nil || !token.Valid {
-				log.Printf("❌ AuthJWT Middleware - Invalid token: %v", err)
+				logf(r, "❌ AuthJWT Middleware - Invalid token: %v", err)
 				writeError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
 				return
 			}
 
-			// Extraer información del usuario del token
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				log.Printf("✅ AuthJWT Middleware - Token valid, user: %s", claims["email"])
-				// Agregar información del usuario al contexto
-				ctx := context.WithValue(r.Context(), "user_id", claims["sub"])
-				ctx = context.WithValue(ctx, "user_email", claims["email"])
-				r = r.WithContext(ctx)
+			claims, _ := token.Claims.(jwt.MapClaims)
+
+			// Comprobar en servidor que la sesión no ha sido revocada
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			user, err := checkAdminSession(ctx, cfg, claims)
+			cancel()
+			if err != nil {
+				if failure, ok := err.(*authFailure); ok {
+					logf(r, "❌ AuthJWT Middleware - Sesión rechazada: %s", failure.message)
+					writeError(w, failure.status, failure.code, failure.message)
+					return
+				}
+				logf(r, "❌ AuthJWT Middleware - Error comprobando sesión: %v", err)
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
 			}
 
+			logf(r, "✅ AuthJWT Middleware - Token valid, user: %s", user.Email)
+			// Agregar información del usuario al contexto (rol y áreas actuales, no los del token)
+			reqCtx := context.WithValue(r.Context(), "user_id", user.ID)
+			reqCtx = context.WithValue(reqCtx, "user_email", user.Email)
+			reqCtx = context.WithValue(reqCtx, "user_role", user.EffectiveRole())
+			reqCtx = context.WithValue(reqCtx, "user_areas", user.AssignedAreas())
+			reqCtx = context.WithValue(reqCtx, "session_id", claims["sid"])
+			r = r.WithContext(reqCtx)
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// RequirePermission - Middleware que exige un permiso al rol del token
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role, _ := r.Context().Value("user_role").(string)
+			allowed := domain.RoleHasPermission(role, permission)
+			if role == domain.RoleAPIKey {
+				scopes, _ := r.Context().Value("api_key_scopes").([]string)
+				allowed = containsString(scopes, permission)
+			}
+			if !allowed {
+				logf(r, "❌ RequirePermission - Rol '%s' sin permiso '%s' (%s %s)", role, permission, r.Method, r.URL.Path)
+				writeError(w, http.StatusForbidden, "forbidden", "permiso requerido: "+permission)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectAPIKey - Middleware para las rutas del propio administrador (perfil, sesiones, 2FA...):
+// una API key no es una cuenta de administrador, así que se rechaza con 403 en vez de dejar que
+// el handler busque el usuario "apikey:<id>" y responda 404
+func RejectAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value("user_role").(string); role == domain.RoleAPIKey {
+			logf(r, "❌ RejectAPIKey - API key en una ruta de cuenta de administrador (%s %s)", r.Method, r.URL.Path)
+			writeError(w, http.StatusForbidden, "api_key_not_allowed", "esta ruta requiere la sesión de un administrador; no admite API keys")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireTopicArea - Middleware que comprueba que el topic {id} pertenece a un área del administrador
+func RequireTopicArea(cfg config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := areaScopeFromRequest(r)
+			if r.Method == "OPTIONS" || scope.all {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Si el id no es válido o el topic no existe, el handler responde con el error adecuado
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			defer cancel()
+
+			client, err := getMongoClient(ctx, cfg)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			defer client.Disconnect(context.Background())
+
+			var topic struct {
+				Area int `bson:"area"`
+			}
+			col := client.Database(cfg.DBName).Collection("topics_uuid_map")
+			err = col.FindOne(ctx, bson.M{"id": id}, options.FindOne().SetProjection(bson.M{"area": 1})).Decode(&topic)
+			if err == mongo.ErrNoDocuments {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+
+			if !scope.Allows(topic.Area) {
+				logf(r, "❌ RequireTopicArea - Topic %d del área %d fuera del alcance del administrador", id, topic.Area)
+				writeAreaForbidden(w, topic.Area)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// containsString indica si el valor está en la lista
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
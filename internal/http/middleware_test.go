@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// permissionRoles es la tabla de roles que debe aceptar cada grupo de rutas (superadmin, todos)
+var permissionRoles = map[string][]string{
+	domain.PermTopicsRead:         {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer, domain.RoleSupport},
+	domain.PermTopicsWrite:        {domain.RoleAreaAdmin, domain.RoleEditor},
+	domain.PermQuestionsWrite:     {domain.RoleAreaAdmin, domain.RoleEditor},
+	domain.PermAreasRead:          {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer, domain.RoleSupport},
+	domain.PermAreasWrite:         {},
+	domain.PermUsersRead:          {domain.RoleAreaAdmin, domain.RoleViewer, domain.RoleSupport},
+	domain.PermUsersWrite:         {domain.RoleAreaAdmin, domain.RoleSupport},
+	domain.PermProvidersRead:      {domain.RoleAreaAdmin, domain.RoleViewer},
+	domain.PermProvidersWrite:     {},
+	domain.PermStatsRead:          {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer, domain.RoleSupport},
+	domain.PermDatabaseRead:       {},
+	domain.PermNotificationsRead:  {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer, domain.RoleSupport},
+	domain.PermNotificationsWrite: {domain.RoleAreaAdmin, domain.RoleEditor},
+	domain.PermPrivacyRead:        {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer},
+	domain.PermPrivacyWrite:       {domain.RoleAreaAdmin, domain.RoleEditor},
+	domain.PermDocumentsRead:      {domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer},
+	domain.PermDocumentsWrite:     {domain.RoleAreaAdmin, domain.RoleEditor},
+	domain.PermAdminsManage:       {},
+}
+
+// callAs ejecuta el handler con el rol, las áreas y los scopes indicados en el contexto y
+// devuelve el código HTTP y el de error
+func callAs(handler http.Handler, method, path, role string, areas []int, scopes []string) (int, string) {
+	req := withPrincipal(httptest.NewRequest(method, path, nil), role, areas)
+	if scopes != nil {
+		req = req.WithContext(context.WithValue(req.Context(), "api_key_scopes", scopes))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response.Code
+}
+
+func TestRequirePermissionRoles(t *testing.T) {
+	roles := []string{domain.RoleSuperadmin, domain.RoleAreaAdmin, domain.RoleEditor, domain.RoleViewer, domain.RoleSupport, "", "desconocido"}
+	for permission, allowedRoles := range permissionRoles {
+		for _, role := range roles {
+			want := http.StatusForbidden
+			if role == domain.RoleSuperadmin || containsString(allowedRoles, role) {
+				want = http.StatusOK
+			}
+			t.Run(permission+"/"+role, func(t *testing.T) {
+				handler := RequirePermission(permission)(http.HandlerFunc(okHandler))
+				status, code := callAs(handler, http.MethodGet, "/admin/resource", role, nil, nil)
+				if status != want {
+					t.Errorf("rol %q, permiso %s: %d, se esperaba %d", role, permission, status, want)
+				}
+				if status == http.StatusForbidden && code != "forbidden" {
+					t.Errorf("código de error %q, se esperaba forbidden", code)
+				}
+			})
+		}
+	}
+}
+
+func TestRequirePermissionAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		scopes     []string
+		permission string
+		wantStatus int
+	}{
+		{"scope concedido", []string{domain.PermTopicsRead}, domain.PermTopicsRead, http.StatusOK},
+		{"scope no concedido", []string{domain.PermTopicsRead}, domain.PermTopicsWrite, http.StatusForbidden},
+		{"sin scopes", []string{}, domain.PermTopicsRead, http.StatusForbidden},
+		// Los scopes sustituyen a los permisos del rol: ni siquiera una key con todos los scopes gestiona administradores
+		{"gestión de administradores", []string{domain.PermTopicsRead, domain.PermDatabaseRead}, domain.PermAdminsManage, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequirePermission(tt.permission)(http.HandlerFunc(okHandler))
+			if status, _ := callAs(handler, http.MethodGet, "/admin/resource", domain.RoleAPIKey, []int{}, tt.scopes); status != tt.wantStatus {
+				t.Errorf("%d, se esperaba %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionOptions(t *testing.T) {
+	handler := RequirePermission(domain.PermAdminsManage)(http.HandlerFunc(okHandler))
+	if status, _ := callAs(handler, http.MethodOptions, "/admin/admins", domain.RoleViewer, nil, nil); status != http.StatusOK {
+		t.Errorf("preflight OPTIONS: %d, se esperaba 200", status)
+	}
+}
+
+// Un área pedida con ?area= fuera de las asignadas se rechaza antes de consultar MongoDB
+func TestAreaParamOutsideScope(t *testing.T) {
+	cfg := config.Config{DBURL: "mongodb://127.0.0.1:27017", DBName: "opo_admin_test"}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		role    string
+		areas   []int
+	}{
+		{"topics de area-admin", AdminTopicsList(cfg), "/admin/topics?area=2", domain.RoleAreaAdmin, []int{1}},
+		{"topics de area-admin sin áreas", AdminTopicsList(cfg), "/admin/topics?area=1", domain.RoleAreaAdmin, []int{}},
+		{"topics de api key con áreas", AdminTopicsList(cfg), "/admin/topics?area=1", domain.RoleAPIKey, []int{2}},
+		{"usuarios de area-admin", AdminUsersList(cfg), "/admin/users?area=3", domain.RoleAreaAdmin, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := callAs(tt.handler, http.MethodGet, tt.path, tt.role, tt.areas, nil)
+			if status != http.StatusForbidden || code != "forbidden_area" {
+				t.Errorf("%d %s, se esperaba 403 forbidden_area", status, code)
+			}
+		})
+	}
+}
+
+func TestRequireTopicArea(t *testing.T) {
+	cfg, db := mongoTestEnv(t)
+	if _, err := db.Collection("topics_uuid_map").InsertMany(context.Background(), []interface{}{
+		domain.Topic{ID: uuid.NewString(), TopicID: 10, UUID: uuid.NewString(), Area: 1},
+		domain.Topic{ID: uuid.NewString(), TopicID: 20, UUID: uuid.NewString(), Area: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.With(RequireTopicArea(cfg)).Get("/admin/topics/{id}", okHandler)
+
+	tests := []struct {
+		name       string
+		role       string
+		areas      []int
+		topicID    string
+		wantStatus int
+	}{
+		{"superadmin", domain.RoleSuperadmin, nil, "20", http.StatusOK},
+		{"area-admin en su área", domain.RoleAreaAdmin, []int{1}, "10", http.StatusOK},
+		{"area-admin fuera de su área", domain.RoleAreaAdmin, []int{1}, "20", http.StatusForbidden},
+		{"area-admin sin áreas", domain.RoleAreaAdmin, []int{}, "10", http.StatusForbidden},
+		{"editor con varias áreas", domain.RoleEditor, []int{1, 2}, "20", http.StatusOK},
+		{"api key sin áreas", domain.RoleAPIKey, []int{}, "20", http.StatusOK},
+		{"api key con áreas", domain.RoleAPIKey, []int{1}, "20", http.StatusForbidden},
+		// Id inválido o topic inexistente: decide el handler (404 o 400)
+		{"topic inexistente", domain.RoleAreaAdmin, []int{1}, "99", http.StatusOK},
+		{"id no numérico", domain.RoleAreaAdmin, []int{1}, "abc", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := callAs(router, http.MethodGet, "/admin/topics/"+tt.topicID, tt.role, tt.areas, nil)
+			if status != tt.wantStatus {
+				t.Errorf("topic %s: %d %s, se esperaba %d", tt.topicID, status, code, tt.wantStatus)
+			}
+			if status == http.StatusForbidden && code != "forbidden_area" {
+				t.Errorf("código de error %q, se esperaba forbidden_area", code)
+			}
+		})
+	}
+}
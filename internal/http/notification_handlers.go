@@ -3,7 +3,6 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -46,8 +45,14 @@ func AdminNotificationsList(cfg config.Config) http.HandlerFunc {
 
 		col := client.Database(cfg.DBName).Collection("notifications")
 
+		// Las notificaciones globales (área 0) son visibles para todos los administradores
+		filter := bson.M{}
+		if scope := areaScopeFromRequest(r); !scope.all {
+			filter["area"] = bson.M{"$in": append([]int{0}, scope.areas...)}
+		}
+
 		// Contar total de notificaciones
-		total, err := col.CountDocuments(ctx, bson.M{})
+		total, err := col.CountDocuments(ctx, filter)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
@@ -58,7 +63,7 @@ func AdminNotificationsList(cfg config.Config) http.HandlerFunc {
 		opts := options.Find().SetSkip(int64(skip)).SetLimit(int64(limit)).SetSort(bson.D{{Key: "createdAt", Value: -1}})
 
 		// Obtener notificaciones
-		cur, err := col.Find(ctx, bson.M{}, opts)
+		cur, err := col.Find(ctx, filter, opts)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
@@ -118,6 +123,11 @@ func AdminNotificationsGetByID(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if notification.Area != 0 && !areaScopeFromRequest(r).Allows(notification.Area) {
+			writeAreaForbidden(w, notification.Area)
+			return
+		}
+
 		writeJSON(w, http.StatusOK, notification)
 	}
 }
@@ -152,6 +162,11 @@ func AdminNotificationsCreate(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusUnprocessableEntity, "validation_error", "area debe ser 0 (todas), 1 (PN) o 2 (PS)")
 			return
 		}
+		// Solo un superadmin puede crear notificaciones globales (área 0)
+		if !areaScopeFromRequest(r).Allows(req.Area) {
+			writeAreaForbidden(w, req.Area)
+			return
+		}
 
 		// Si es tipo "fixed", validar que tenga actionType
 		if req.Type == "fixed" && req.ActionType == "" {
@@ -219,7 +234,7 @@ func AdminNotificationsCreate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminNotificationsCreate - Notificación creada: ID=%s, Title=%s, Area=%d", notification.ID, notification.Title, notification.Area)
+		logf(r, "✅ AdminNotificationsCreate - Notificación creada: ID=%s, Title=%s, Area=%d", notification.ID, notification.Title, notification.Area)
 
 		writeJSON(w, http.StatusCreated, notification)
 	}
@@ -259,6 +274,12 @@ func AdminNotificationsUpdate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		scope := areaScopeFromRequest(r)
+		if !scope.Allows(existing.Area) {
+			writeAreaForbidden(w, existing.Area)
+			return
+		}
+
 		// Construir update
 		update := bson.M{
 			"$set": bson.M{
@@ -294,6 +315,10 @@ func AdminNotificationsUpdate(cfg config.Config) http.HandlerFunc {
 				writeError(w, http.StatusUnprocessableEntity, "validation_error", "area debe ser 0 (todas), 1 (PN) o 2 (PS)")
 				return
 			}
+			if !scope.Allows(*req.Area) {
+				writeAreaForbidden(w, *req.Area)
+				return
+			}
 			update["$set"].(bson.M)["area"] = *req.Area
 		}
 		if req.ActionType != nil {
@@ -324,7 +349,7 @@ func AdminNotificationsUpdate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminNotificationsUpdate - Notificación actualizada: ID=%s", id)
+		logf(r, "✅ AdminNotificationsUpdate - Notificación actualizada: ID=%s", id)
 
 		writeJSON(w, http.StatusOK, updated)
 	}
@@ -358,6 +383,11 @@ func AdminNotificationsDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if !areaScopeFromRequest(r).Allows(existing.Area) {
+			writeAreaForbidden(w, existing.Area)
+			return
+		}
+
 		// Eliminar
 		if _, err := col.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
@@ -367,10 +397,10 @@ func AdminNotificationsDelete(cfg config.Config) http.HandlerFunc {
 		// También eliminar todos los registros de lectura asociados
 		readsCol := client.Database(cfg.DBName).Collection("notification_reads")
 		if _, err := readsCol.DeleteMany(ctx, bson.M{"notificationId": id}); err != nil {
-			log.Printf("⚠️ AdminNotificationsDelete - Error eliminando registros de lectura: %v", err)
+			logf(r, "⚠️ AdminNotificationsDelete - Error eliminando registros de lectura: %v", err)
 		}
 
-		log.Printf("✅ AdminNotificationsDelete - Notificación eliminada: ID=%s", id)
+		logf(r, "✅ AdminNotificationsDelete - Notificación eliminada: ID=%s", id)
 
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -404,6 +434,11 @@ func AdminNotificationsToggleEnabled(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if !areaScopeFromRequest(r).Allows(notification.Area) {
+			writeAreaForbidden(w, notification.Area)
+			return
+		}
+
 		// Cambiar estado
 		update := bson.M{
 			"$set": bson.M{
@@ -419,7 +454,7 @@ func AdminNotificationsToggleEnabled(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ AdminNotificationsToggleEnabled - Notificación %s: enabled=%v", id, updated.Enabled)
+		logf(r, "✅ AdminNotificationsToggleEnabled - Notificación %s: enabled=%v", id, updated.Enabled)
 
 		writeJSON(w, http.StatusOK, updated)
 	}
@@ -452,6 +487,11 @@ func AdminNotificationsStats(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
+		if notification.Area != 0 && !areaScopeFromRequest(r).Allows(notification.Area) {
+			writeAreaForbidden(w, notification.Area)
+			return
+		}
+
 		readsCol := client.Database(cfg.DBName).Collection("notification_reads")
 
 		// Contar total de lecturas
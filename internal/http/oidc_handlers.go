@@ -0,0 +1,255 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	oidcStatesCollection = "oidc_states"
+
+	// Tiempo máximo para completar el login en el proveedor
+	oidcStateTTL = 10 * time.Minute
+
+	// oidcStateCookie liga el state al navegador que inició el login (evita el login CSRF)
+	oidcStateCookie = "oidc_state"
+)
+
+// ========== Inicio de sesión con OpenID Connect (SSO) ==========
+
+// AuthOIDCLogin - Redirige al proveedor OIDC (authorization code + PKCE)
+func AuthOIDCLogin(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oidc := services.NewOIDCClient(cfg)
+		if !oidc.Enabled() {
+			writeError(w, http.StatusNotFound, "oidc_disabled", "el inicio de sesión con SSO no está configurado")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		defer cancel()
+
+		metadata, err := oidc.Discover(ctx)
+		if err != nil {
+			logf(r, "❌ AuthOIDCLogin - %v", err)
+			writeError(w, http.StatusBadGateway, "oidc_unavailable", "no se pudo contactar con el proveedor de identidad")
+			return
+		}
+
+		state, errState := newRefreshToken()
+		nonce, errNonce := newRefreshToken()
+		verifier, errVerifier := newRefreshToken()
+		if errState != nil || errNonce != nil || errVerifier != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", "no se pudo iniciar el login")
+			return
+		}
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		now := time.Now()
+		if _, err := client.Database(cfg.DBName).Collection(oidcStatesCollection).InsertOne(ctx, domain.OIDCLoginState{
+			ID:           state,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			CreatedAt:    now,
+			ExpiresAt:    now.Add(oidcStateTTL),
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// SameSite=Lax: el navegador la envía en la redirección de vuelta desde el proveedor
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    hashRefreshToken(state),
+			Path:     oidcCookiePath(cfg),
+			MaxAge:   int(oidcStateTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(cfg.AppBaseURL, "https://") || r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, oidc.AuthCodeURL(metadata, state, nonce, verifier), http.StatusFound)
+	}
+}
+
+// AuthOIDCCallback - Recibe el código del proveedor, valida el ID token y emite la sesión del
+// administrador con el mismo email verificado
+func AuthOIDCCallback(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oidc := services.NewOIDCClient(cfg)
+		if !oidc.Enabled() {
+			writeError(w, http.StatusNotFound, "oidc_disabled", "el inicio de sesión con SSO no está configurado")
+			return
+		}
+
+		query := r.URL.Query()
+		if providerError := query.Get("error"); providerError != "" {
+			logf(r, "⚠️ AuthOIDCCallback - El proveedor devolvió error: %s (%s)", providerError, query.Get("error_description"))
+			writeOIDCError(w, r, cfg, http.StatusUnauthorized, "oidc_denied", "el proveedor de identidad rechazó el inicio de sesión")
+			return
+		}
+		state, code := query.Get("state"), query.Get("code")
+		if state == "" || code == "" {
+			writeOIDCError(w, r, cfg, http.StatusBadRequest, "invalid_request", "faltan state o code")
+			return
+		}
+
+		// El state debe venir del mismo navegador que inició el login
+		cookie, err := r.Cookie(oidcStateCookie)
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: oidcCookiePath(cfg), MaxAge: -1, HttpOnly: true})
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashRefreshToken(state))) != 1 {
+			logf(r, "⚠️ AuthOIDCCallback - El state no corresponde a este navegador")
+			writeOIDCError(w, r, cfg, http.StatusBadRequest, "invalid_state", "el inicio de sesión no se inició en este navegador; vuelve a intentarlo")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		// El state solo puede usarse una vez
+		var loginState domain.OIDCLoginState
+		if err := db.Collection(oidcStatesCollection).FindOneAndDelete(ctx, bson.M{"_id": state}).Decode(&loginState); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeOIDCError(w, r, cfg, http.StatusBadRequest, "invalid_state", "state desconocido o ya utilizado; vuelve a iniciar sesión")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if loginState.ExpiresAt.Before(time.Now()) {
+			writeOIDCError(w, r, cfg, http.StatusBadRequest, "invalid_state", "el inicio de sesión ha caducado; vuelve a intentarlo")
+			return
+		}
+
+		metadata, err := oidc.Discover(ctx)
+		if err != nil {
+			logf(r, "❌ AuthOIDCCallback - %v", err)
+			writeOIDCError(w, r, cfg, http.StatusBadGateway, "oidc_unavailable", "no se pudo contactar con el proveedor de identidad")
+			return
+		}
+
+		rawIDToken, err := oidc.Exchange(ctx, metadata, code, loginState.CodeVerifier)
+		if err != nil {
+			logf(r, "❌ AuthOIDCCallback - %v", err)
+			writeOIDCError(w, r, cfg, http.StatusUnauthorized, "oidc_exchange_failed", "no se pudo completar el inicio de sesión")
+			return
+		}
+
+		identity, err := oidc.VerifyIDToken(ctx, metadata, rawIDToken, loginState.Nonce)
+		if err != nil {
+			logf(r, "❌ AuthOIDCCallback - %v", err)
+			writeOIDCError(w, r, cfg, http.StatusUnauthorized, "invalid_id_token", "el token del proveedor no es válido")
+			return
+		}
+		if identity.Email == "" || !identity.EmailVerified {
+			logf(r, "⚠️ AuthOIDCCallback - Email no verificado por el proveedor: %q", identity.Email)
+			writeOIDCError(w, r, cfg, http.StatusForbidden, "email_not_verified", "el proveedor no ha verificado el email")
+			return
+		}
+		if !oidc.EmailDomainAllowed(identity) {
+			logf(r, "⚠️ AuthOIDCCallback - Dominio no permitido: %s", identity.Email)
+			writeOIDCError(w, r, cfg, http.StatusForbidden, "domain_not_allowed", "el dominio del email no está autorizado")
+			return
+		}
+
+		// Solo se permite el acceso a administradores ya dados de alta
+		var user domain.User
+		if err := db.Collection("user").FindOne(ctx, bson.M{"email": identity.Email}).Decode(&user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				logf(r, "⚠️ AuthOIDCCallback - No existe administrador para %s", identity.Email)
+				writeOIDCError(w, r, cfg, http.StatusForbidden, "not_an_admin", "no hay ninguna cuenta de administrador con ese email")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if user.Disabled {
+			logf(r, "⚠️ AuthOIDCCallback - Cuenta deshabilitada: %s", identity.Email)
+			writeOIDCError(w, r, cfg, http.StatusForbidden, "account_disabled", "la cuenta está deshabilitada")
+			return
+		}
+
+		// Mismo segundo factor que el login con contraseña: el SSO no lo sustituye
+		purpose, err := twoFactorPurpose(ctx, db, user)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		var response map[string]interface{}
+		if purpose != "" {
+			response, err = createTwoFactorChallenge(ctx, db, user, purpose)
+		} else {
+			response, err = completeAdminLogin(ctx, db, user, r, cfg)
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if purpose != "" {
+			logf(r, "🔐 AuthOIDCCallback - Login SSO de %s pendiente de 2FA (%s)", user.Email, purpose)
+		} else {
+			logf(r, "✅ AuthOIDCCallback - Login SSO exitoso para %s (sub %s)", user.Email, identity.Subject)
+		}
+
+		if cfg.OIDCFrontendRedirectURL == "" {
+			writeJSON(w, http.StatusOK, response)
+			return
+		}
+
+		// Los tokens viajan en el fragmento para que no lleguen a logs de servidores intermedios
+		fragment := url.Values{}
+		fields := []string{"token", "refreshToken", "expiresIn"}
+		if purpose != "" {
+			fields = []string{"twoFactorRequired", "twoFactorSetupRequired", "challengeToken", "expiresIn"}
+		}
+		for _, field := range fields {
+			fragment.Set(field, fmt.Sprint(response[field]))
+		}
+		http.Redirect(w, r, cfg.OIDCFrontendRedirectURL+"#"+fragment.Encode(), http.StatusFound)
+	}
+}
+
+// oidcCookiePath limita la cookie del state a las rutas del login OIDC
+func oidcCookiePath(cfg config.Config) string {
+	return cfg.APIBasePath + "/auth/oidc"
+}
+
+// writeOIDCError devuelve el error al panel (en el fragmento) o como JSON si no hay panel configurado
+func writeOIDCError(w http.ResponseWriter, r *http.Request, cfg config.Config, status int, code, message string) {
+	if cfg.OIDCFrontendRedirectURL == "" {
+		writeError(w, status, code, message)
+		return
+	}
+	fragment := url.Values{}
+	fragment.Set("error", code)
+	fragment.Set("error_description", message)
+	http.Redirect(w, r, strings.TrimSuffix(cfg.OIDCFrontendRedirectURL, "#")+"#"+fragment.Encode(), http.StatusFound)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"html/template"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -39,7 +38,8 @@ func AdminPrivacyList(cfg config.Config) http.HandlerFunc {
 
 		// Obtener todas las políticas ordenadas por área
 		opts := options.Find().SetSort(bson.D{{Key: "area", Value: 1}})
-		cur, err := col.Find(ctx, bson.M{}, opts)
+		filter := areaScopeFromRequest(r).Restrict(bson.M{}, "area")
+		cur, err := col.Find(ctx, filter, opts)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
@@ -66,6 +66,10 @@ func AdminPrivacyGetByArea(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "invalid_area", "area debe ser 1 o 2")
 			return
 		}
+		if !areaScopeFromRequest(r).Allows(areaInt) {
+			writeAreaForbidden(w, areaInt)
+			return
+		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -108,6 +112,10 @@ func AdminPrivacyCreate(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusUnprocessableEntity, "validation_error", "area debe ser 1 (PN) o 2 (PS)")
 			return
 		}
+		if !areaScopeFromRequest(r).Allows(req.Area) {
+			writeAreaForbidden(w, req.Area)
+			return
+		}
 
 		req.HTML = strings.TrimSpace(req.HTML)
 		if req.HTML == "" {
@@ -153,7 +161,7 @@ func AdminPrivacyCreate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ Política de privacidad creada: Área %d (ID: %s)", req.Area, policy.ID)
+		logf(r, "✅ Política de privacidad creada: Área %d (ID: %s)", req.Area, policy.ID)
 
 		writeJSON(w, http.StatusCreated, policy)
 	}
@@ -169,6 +177,10 @@ func AdminPrivacyUpdate(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "invalid_area", "area debe ser 1 o 2")
 			return
 		}
+		if !areaScopeFromRequest(r).Allows(areaInt) {
+			writeAreaForbidden(w, areaInt)
+			return
+		}
 
 		var req domain.UpdatePrivacyPolicyRequest
 
@@ -232,7 +244,7 @@ func AdminPrivacyUpdate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ Política de privacidad actualizada: Área %d (ID: %s)", areaInt, updated.ID)
+		logf(r, "✅ Política de privacidad actualizada: Área %d (ID: %s)", areaInt, updated.ID)
 
 		writeJSON(w, http.StatusOK, updated)
 	}
@@ -248,6 +260,10 @@ func AdminPrivacyDelete(cfg config.Config) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "invalid_area", "area debe ser 1 o 2")
 			return
 		}
+		if !areaScopeFromRequest(r).Allows(areaInt) {
+			writeAreaForbidden(w, areaInt)
+			return
+		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -272,7 +288,7 @@ func AdminPrivacyDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ Política de privacidad eliminada: Área %d", areaInt)
+		logf(r, "✅ Política de privacidad eliminada: Área %d", areaInt)
 
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -290,7 +306,7 @@ func PrivacyPolicyPublic(cfg config.Config) http.HandlerFunc {
 		} else if areaParam == "ps" {
 			areaInt = 2
 		} else {
-			renderPrivacyPolicyError(w, "Área inválida. Use 'pn' o 'ps'")
+			renderPrivacyPolicyError(w, r, "Área inválida. Use 'pn' o 'ps'")
 			return
 		}
 
@@ -299,8 +315,8 @@ func PrivacyPolicyPublic(cfg config.Config) http.HandlerFunc {
 
 		client, err := getMongoClient(ctx, cfg)
 		if err != nil {
-			log.Printf("❌ Error conectando a MongoDB: %v", err)
-			renderPrivacyPolicyError(w, "Error interno del servidor")
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
+			renderPrivacyPolicyError(w, r, "Error interno del servidor")
 			return
 		}
 		defer client.Disconnect(context.Background())
@@ -310,11 +326,11 @@ func PrivacyPolicyPublic(cfg config.Config) http.HandlerFunc {
 		var policy domain.PrivacyPolicy
 		if err := col.FindOne(ctx, bson.M{"area": areaInt}).Decode(&policy); err != nil {
 			if err == mongo.ErrNoDocuments {
-				renderPrivacyPolicyError(w, "Política de privacidad no encontrada para esta área")
+				renderPrivacyPolicyError(w, r, "Política de privacidad no encontrada para esta área")
 				return
 			}
-			log.Printf("❌ Error buscando política: %v", err)
-			renderPrivacyPolicyError(w, "Error interno del servidor")
+			logf(r, "❌ Error buscando política: %v", err)
+			renderPrivacyPolicyError(w, r, "Error interno del servidor")
 			return
 		}
 
@@ -326,7 +342,7 @@ func PrivacyPolicyPublic(cfg config.Config) http.HandlerFunc {
 }
 
 // renderPrivacyPolicyError renderiza una página de error HTML
-func renderPrivacyPolicyError(w http.ResponseWriter, message string) {
+func renderPrivacyPolicyError(w http.ResponseWriter, r *http.Request, message string) {
 	tmpl := `<!DOCTYPE html>
 <html lang="es">
 <head>
@@ -404,7 +420,7 @@ func renderPrivacyPolicyError(w http.ResponseWriter, message string) {
 	}
 
 	if err := t.Execute(w, data); err != nil {
-		log.Printf("❌ Error ejecutando template: %v", err)
+		logf(r, "❌ Error ejecutando template: %v", err)
 		http.Error(w, "Error interno", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,301 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/lifecycle"
+	"opo_admin_server/internal/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// topicStatusMigration es la migración de scripts/migrate-topic-status.js (enabled -> status)
+const topicStatusMigration = "topic-status-v1"
+
+// requiredMigrations son los scripts de scripts/ que registran su ejecución en la colección migrations
+var requiredMigrations = []string{topicStatusMigration}
+
+// requiredIndex es un índice de scripts/init-mongo.js del que depende la aplicación
+// (unicidad o caducidad automática de documentos)
+type requiredIndex struct {
+	collection string
+	keys       string // Claves en orden, p. ej. "email:1"
+	unique     bool
+	ttl        bool
+}
+
+var requiredIndexes = []requiredIndex{
+	{collection: "user", keys: "email:1", unique: true},
+	{collection: "topics_uuid_map", keys: "id:1", unique: true},
+	{collection: "topics_uuid_map", keys: "uuid:1", unique: true},
+	{collection: "admin_sessions", keys: "tokenHash:1", unique: true},
+	{collection: "admin_sessions", keys: "expiresAt:1", ttl: true},
+	{collection: "admin_login_challenges", keys: "expiresAt:1", ttl: true},
+	{collection: "login_attempts", keys: "expiresAt:1", ttl: true},
+	{collection: "api_keys", keys: "keyHash:1", unique: true},
+	{collection: "oidc_states", keys: "expiresAt:1", ttl: true},
+	{collection: "jobs", keys: "documentId:1", unique: true},
+	{collection: "jobs", keys: "finishedAt:1", ttl: true},
+}
+
+// Livez - Sonda de vida: el proceso responde. No comprueba dependencias para que un fallo de
+// MongoDB no provoque reinicios
+func Livez(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"ts":     time.Now().Format(time.RFC3339),
+	})
+}
+
+// Readyz - Sonda de disponibilidad: MongoDB accesible, índices y migraciones aplicados y,
+// si se configuran en READYZ_OPTIONAL_CHECKS, SMTP y vector store. Responde 503 si falla
+// alguna comprobación crítica
+func Readyz(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Durante el apagado se deja de recibir tráfico nuevo
+		if lifecycle.ShuttingDown() {
+			writeJSON(w, http.StatusServiceUnavailable, domain.ReadinessResponse{
+				Status: "shutting_down",
+				Checks: map[string]domain.ReadinessCheck{},
+				TS:     time.Now().Format(time.RFC3339),
+			})
+			return
+		}
+
+		checks := []func(context.Context) map[string]domain.ReadinessCheck{
+			func(ctx context.Context) map[string]domain.ReadinessCheck {
+				return checkMongo(ctx, cfg)
+			},
+		}
+		for _, name := range cfg.ReadinessOptionalChecks {
+			switch strings.ToLower(name) {
+			case "smtp":
+				checks = append(checks, func(ctx context.Context) map[string]domain.ReadinessCheck {
+					return map[string]domain.ReadinessCheck{"smtp": checkSMTP(ctx, cfg)}
+				})
+			case "vectorstore", "pinecone":
+				checks = append(checks, func(ctx context.Context) map[string]domain.ReadinessCheck {
+					return map[string]domain.ReadinessCheck{"vectorstore": checkVectorStore(ctx, cfg)}
+				})
+			}
+		}
+
+		// Las comprobaciones se ejecutan en paralelo, cada una con su límite de tiempo
+		response := domain.ReadinessResponse{Status: "ready", Checks: map[string]domain.ReadinessCheck{}, TS: time.Now().Format(time.RFC3339)}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, check := range checks {
+			wg.Add(1)
+			go func(check func(context.Context) map[string]domain.ReadinessCheck) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(r.Context(), cfg.ReadinessTimeout)
+				defer cancel()
+				results := check(ctx)
+
+				mu.Lock()
+				defer mu.Unlock()
+				for name, result := range results {
+					response.Checks[name] = result
+				}
+			}(check)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		for name, check := range response.Checks {
+			if check.Status == "ok" {
+				continue
+			}
+			if check.Critical {
+				response.Status = "not_ready"
+				status = http.StatusServiceUnavailable
+			} else if response.Status == "ready" {
+				response.Status = "degraded"
+			}
+			logf(r, "⚠️ Readyz - %s: %s %v", name, check.Error, check.Missing)
+		}
+
+		writeJSON(w, status, response)
+	}
+}
+
+// checkMongo hace ping al primario y comprueba que init-mongo.js y las migraciones se han aplicado
+func checkMongo(ctx context.Context, cfg config.Config) map[string]domain.ReadinessCheck {
+	start := time.Now()
+	client, err := getMongoClient(ctx, cfg)
+	if err == nil {
+		defer client.Disconnect(context.Background())
+		err = client.Ping(ctx, nil)
+	}
+	results := map[string]domain.ReadinessCheck{"mongodb": readinessResult(start, true, err)}
+	if err != nil {
+		return results
+	}
+	db := client.Database(cfg.DBName)
+
+	start = time.Now()
+	missing, err := missingIndexes(ctx, db)
+	results["indexes"] = withMissing(readinessResult(start, true, err), missing, "faltan índices (ejecuta scripts/init-mongo.js)")
+
+	start = time.Now()
+	missing, err = missingMigrations(ctx, db)
+	results["migrations"] = withMissing(readinessResult(start, true, err), missing, "hay migraciones de scripts/ sin aplicar")
+
+	return results
+}
+
+func missingIndexes(ctx context.Context, db *mongo.Database) ([]string, error) {
+	existing := map[string][]*mongo.IndexSpecification{}
+	var missing []string
+	for _, required := range requiredIndexes {
+		specs, ok := existing[required.collection]
+		if !ok {
+			var err error
+			specs, err = db.Collection(required.collection).Indexes().ListSpecifications(ctx)
+			// Una colección que aún no existe no tiene índices (NamespaceNotFound en servidores antiguos)
+			if err != nil && !isNamespaceNotFound(err) {
+				return nil, err
+			}
+			existing[required.collection] = specs
+		}
+		if !hasIndex(specs, required) {
+			missing = append(missing, required.collection+" {"+required.keys+"}")
+		}
+	}
+	return missing, nil
+}
+
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 26
+}
+
+func hasIndex(specs []*mongo.IndexSpecification, required requiredIndex) bool {
+	for _, spec := range specs {
+		if indexKeys(spec.KeysDocument) != required.keys {
+			continue
+		}
+		if required.unique && (spec.Unique == nil || !*spec.Unique) {
+			continue
+		}
+		if required.ttl && spec.ExpireAfterSeconds == nil {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// indexKeys convierte {email: 1, createdAt: -1} en "email:1,createdAt:-1"
+func indexKeys(keys bson.Raw) string {
+	elements, err := keys.Elements()
+	if err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(elements))
+	for _, e := range elements {
+		if n, ok := e.Value().AsInt64OK(); ok {
+			parts = append(parts, fmt.Sprintf("%s:%d", e.Key(), n))
+		} else if kind, ok := e.Value().StringValueOK(); ok {
+			parts = append(parts, e.Key()+":"+kind) // text, 2dsphere, hashed...
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func missingMigrations(ctx context.Context, db *mongo.Database) ([]string, error) {
+	cursor, err := db.Collection("migrations").Find(ctx, bson.M{"_id": bson.M{"$in": requiredMigrations}})
+	if err != nil {
+		return nil, err
+	}
+	var applied []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+
+	done := map[string]bool{}
+	for _, m := range applied {
+		done[m.ID] = true
+	}
+
+	// Sin topics no hay nada que migrar (instalación nueva creada sin scripts/init-mongo.js)
+	if !done[topicStatusMigration] {
+		topics, err := db.Collection("topics_uuid_map").CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		done[topicStatusMigration] = topics == 0
+	}
+	var missing []string
+	for _, id := range requiredMigrations {
+		if !done[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// checkSMTP comprueba que el servidor SMTP responde (no crítico: sin él solo fallan los emails)
+func checkSMTP(ctx context.Context, cfg config.Config) domain.ReadinessCheck {
+	start := time.Now()
+	emailService := services.NewEmailService(cfg)
+	if !emailService.Configured() {
+		return readinessResult(start, false, fmt.Errorf("SMTP no configurado"))
+	}
+	return readinessResult(start, false, emailService.Ping(ctx))
+}
+
+// checkVectorStore comprueba que el vector store responde; con Pinecone, que acepta la API key
+// y que el índice existe (no crítico)
+func checkVectorStore(ctx context.Context, cfg config.Config) domain.ReadinessCheck {
+	start := time.Now()
+	var db *mongo.Database
+	if cfg.VectorStore == services.VectorStoreMongo {
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			return readinessResult(start, false, err)
+		}
+		defer client.Disconnect(context.Background())
+		db = client.Database(cfg.DBName)
+	}
+	store, err := services.NewVectorStore(cfg, db)
+	if err != nil {
+		return readinessResult(start, false, err)
+	}
+	return readinessResult(start, false, store.Ping(ctx))
+}
+
+func readinessResult(start time.Time, critical bool, err error) domain.ReadinessCheck {
+	check := domain.ReadinessCheck{
+		Status:    "ok",
+		Critical:  critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		check.Status = "error"
+		check.Error = config.RedactString(err.Error())
+	}
+	return check
+}
+
+// withMissing marca como fallida una comprobación a la que le faltan elementos
+func withMissing(check domain.ReadinessCheck, missing []string, message string) domain.ReadinessCheck {
+	check.Missing = missing
+	if len(missing) > 0 && check.Status == "ok" {
+		check.Status = "error"
+		check.Error = message
+	}
+	return check
+}
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
 
 	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -11,32 +13,34 @@ import (
 
 func NewRouter(cfg config.Config) http.Handler {
 	r := chi.NewRouter()
-	println("CORSAllowedOrigins: ", cfg.CORSAllowedOrigins)
+	slog.Info("CORS configurado", "origins", cfg.CORSAllowedOrigins)
+	trustedProxyHops = cfg.TrustedProxyHops
+
+	// Request ID, traza y log de cada petición (antes de CORS para registrar también los preflight)
+	r.Use(RequestID)
+	r.Use(Tracing)
+	r.Use(RequestLogger)
+	r.Use(Metrics)
 
 	// Middleware CORS mejorado
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORSAllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With", "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
-		ExposedHeaders:   []string{"Link", "Content-Length", "Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "X-API-Key", "X-Request-ID", "traceparent", "Content-Type", "X-CSRF-Token", "X-Requested-With", "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		ExposedHeaders:   []string{"Link", "Content-Length", "Content-Type", "Authorization", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           86400, // 24 horas
 	}))
 
-	// Middleware de logging mejorado
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			println("Request:", r.Method, r.URL.Path, "Origin:", r.Header.Get("Origin"))
-			next.ServeHTTP(w, r)
-		})
-	})
-
 	// Handler global para OPTIONS (captura todas las rutas)
 	r.Options("/*", func(w http.ResponseWriter, r *http.Request) {
 		// El middleware CORS ya configuró los headers
 		w.WriteHeader(http.StatusNoContent)
 	})
 
+	// Métricas de Prometheus (fuera de la ruta base, protegidas con METRICS_TOKEN si se configura)
+	r.Get("/metrics", MetricsHandler(cfg))
+
 	// Rutas públicas
 	r.Route(cfg.APIBasePath, func(r chi.Router) {
 		// Handler para OPTIONS dentro del grupo API
@@ -44,17 +48,40 @@ func NewRouter(cfg config.Config) http.Handler {
 			w.WriteHeader(http.StatusNoContent)
 		})
 
-		// Health check
+		// Health check y sondas de vida / disponibilidad
 		r.Get("/healthz", Healthz)
+		r.Get("/livez", Livez)
+		r.Get("/readyz", Readyz(cfg))
 
-		// Test CORS endpoint
-		r.Get("/test-cors", TestCORS)
+		// Endpoints de prueba: solo en desarrollo
+		if cfg.IsDevelopment() {
+			// Test CORS endpoint
+			r.Get("/test-cors", TestCORS)
 
-		// Test login endpoint (sin base de datos)
-		r.Post("/test-login", TestLogin)
+			// Test login endpoint (sin base de datos)
+			r.Post("/test-login", TestLogin)
+		}
 
-		// Autenticación (solo login)
+		// Autenticación
 		r.Post("/auth/login", AuthLogin(cfg))
+		r.Post("/auth/refresh", AuthRefresh(cfg))
+
+		// Segundo factor (TOTP) del login
+		r.Post("/auth/2fa/verify", AuthTwoFactorVerify(cfg))
+		r.Post("/auth/2fa/enroll", AuthTwoFactorEnrollStart(cfg))
+		r.Post("/auth/2fa/enroll/confirm", AuthTwoFactorEnrollConfirm(cfg))
+
+		// Inicio de sesión con OpenID Connect (SSO)
+		r.Get("/auth/oidc/login", AuthOIDCLogin(cfg))
+		r.Get("/auth/oidc/callback", AuthOIDCCallback(cfg))
+
+		// Establecer contraseña de administrador (invitación / restablecimiento)
+		r.Get("/auth/password-setup", AdminPasswordSetupForm(cfg))
+		r.Post("/auth/password-setup", AdminPasswordSetupSubmit(cfg))
+
+		// Recuperación de contraseña de administrador
+		r.Get("/auth/forgot-password", AdminForgotPasswordForm(cfg))
+		r.Post("/auth/forgot-password", AdminForgotPasswordRequest(cfg))
 
 		// Topics públicos (filtrados por área)
 		r.Get("/topics/area/{areaId}", TopicListByArea(cfg))
@@ -71,6 +98,7 @@ func NewRouter(cfg config.Config) http.Handler {
 	// Rutas protegidas (requieren JWT)
 	r.Route(cfg.APIBasePath+"/admin", func(r chi.Router) {
 		r.Use(AuthJWT(cfg))
+		r.Use(AuditLog(cfg))
 		
 		// Middleware para aumentar límite de body size en rutas de upload
 		r.Route("/ia-works", func(r chi.Router) {
@@ -81,8 +109,24 @@ func NewRouter(cfg config.Config) http.Handler {
 					next.ServeHTTP(w, r)
 				})
 			})
-			r.Post("/upload", AdminIAWorksUploadFile(cfg))
-			r.Post("/process", AdminIAWorksProcessVector(cfg))
+			r.Group(func(r chi.Router) {
+				r.Use(RequirePermission(domain.PermDocumentsRead))
+				r.Get("/documents", AdminIAWorksDocumentsList(cfg))
+				r.Get("/documents/{id}", AdminIAWorksDocumentGet(cfg))
+				r.Get("/vector-store/stats", AdminIAWorksVectorStoreStats(cfg))
+				r.Get("/jobs", AdminIAWorksJobsList(cfg))
+				r.Get("/jobs/{id}", AdminIAWorksJobGet(cfg))
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(RequirePermission(domain.PermDocumentsWrite))
+				r.Post("/upload", AdminIAWorksUploadFile(cfg))
+				r.Post("/process", AdminIAWorksProcessVector(cfg))
+				r.Patch("/documents/{id}", AdminIAWorksDocumentUpdate(cfg))
+				r.Delete("/documents/{id}", AdminIAWorksDocumentDelete(cfg))
+				r.Post("/documents/{id}/extract", AdminIAWorksDocumentExtract(cfg))
+				r.Post("/documents/{id}/process", AdminIAWorksDocumentProcess(cfg))
+				r.Post("/jobs/{id}/cancel", AdminIAWorksJobCancel(cfg))
+			})
 		})
 
 		// Handler para OPTIONS en rutas protegidas
@@ -90,72 +134,163 @@ func NewRouter(cfg config.Config) http.Handler {
 			w.WriteHeader(http.StatusNoContent)
 		})
 
-		// Gestión del usuario administrador
-		r.Get("/user", AdminUserGet(cfg))
-		r.Put("/user", AdminUserUpdate(cfg))
-		r.Post("/user/reset-password", AdminUserResetPassword(cfg))
-
-		// Administración de topics
-		r.Get("/topics", AdminTopicsList(cfg))
-		r.Get("/topics/{id}", AdminTopicsGetByID(cfg))
-		r.Get("/topics/{id}/subtopics", AdminTopicsGetSubtopics(cfg))
-		r.Post("/topics/{id}/subtopics", AdminTopicsCreateSubtopic(cfg))
-		r.Post("/topics", AdminTopicsCreate(cfg))
-		r.Put("/topics/{id}", AdminTopicsUpdate(cfg))
-		r.Patch("/topics/{id}/enabled", AdminTopicsToggleEnabled(cfg))
-		r.Patch("/topics/{id}/premium", AdminTopicsTogglePremium(cfg))
-		r.Delete("/topics/{id}", AdminTopicsDelete(cfg))
-		
+		// Cuenta del administrador autenticado (no disponible con API key)
+		r.Group(func(r chi.Router) {
+			r.Use(RejectAPIKey)
+
+			// Sesiones del administrador autenticado
+			r.Post("/auth/logout", AuthLogout(cfg))
+			r.Get("/auth/sessions", AuthSessionsList(cfg))
+			r.Post("/auth/sessions/revoke-all", AuthSessionsRevokeAll(cfg))
+
+			// Verificación en dos pasos del administrador autenticado
+			r.Get("/auth/2fa", AdminTwoFactorStatus(cfg))
+			r.Post("/auth/2fa/enroll", AdminTwoFactorEnroll(cfg))
+			r.Post("/auth/2fa/confirm", AdminTwoFactorConfirm(cfg))
+			r.Post("/auth/2fa/recovery-codes", AdminTwoFactorRecoveryCodes(cfg))
+			r.Delete("/auth/2fa", AdminTwoFactorDisable(cfg))
+
+			// Gestión del usuario administrador (perfil propio, cualquier rol)
+			r.Get("/user", AdminUserGet(cfg))
+			r.Put("/user", AdminUserUpdate(cfg))
+			r.Post("/user/reset-password", AdminUserResetPassword(cfg))
+		})
+
+		// Gestión de cuentas de administrador
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermAdminsManage))
+			r.Get("/admins", AdminAccountsList(cfg))
+			r.Post("/admins", AdminAccountsCreate(cfg))
+			r.Put("/admins/{id}", AdminAccountsUpdate(cfg))
+			r.Patch("/admins/{id}/disabled", AdminAccountsSetDisabled(cfg))
+			r.Delete("/admins/{id}", AdminAccountsDelete(cfg))
+			r.Post("/admins/{id}/invite", AdminAccountsInvite(cfg))
+			r.Post("/admins/{id}/force-password-reset", AdminAccountsForcePasswordReset(cfg))
+			r.Post("/admins/{id}/revoke-sessions", AdminAccountsRevokeSessions(cfg))
+			r.Post("/admins/{id}/2fa/reset", AdminAccountsResetTwoFactor(cfg))
+			r.Get("/security/settings", AdminSecuritySettingsGet(cfg))
+			r.Put("/security/settings", AdminSecuritySettingsUpdate(cfg))
+			r.Get("/security/lockouts", AdminLoginLockoutsList(cfg))
+			r.Delete("/security/lockouts/{id}", AdminLoginLockoutsClear(cfg))
+			r.Get("/api-keys", AdminAPIKeysList(cfg))
+			r.Post("/api-keys", AdminAPIKeysCreate(cfg))
+			r.Delete("/api-keys/{id}", AdminAPIKeysRevoke(cfg))
+			r.Get("/audit", AdminAuditLogSearch(cfg))
+			r.Get("/system/log-level", AdminLogLevelGet(cfg))
+			r.Put("/system/log-level", AdminLogLevelUpdate(cfg))
+		})
+
+		// Administración de topics (las rutas con {id} comprueban el área del topic)
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermTopicsRead))
+			r.Get("/topics", AdminTopicsList(cfg))
+			r.Group(func(r chi.Router) {
+				r.Use(RequireTopicArea(cfg))
+				r.Get("/topics/{id}", AdminTopicsGetByID(cfg))
+				r.Get("/topics/{id}/subtopics", AdminTopicsGetSubtopics(cfg))
+				r.Get("/topics/{id}/available-sources", AdminGetAvailableSourceTopics(cfg))
+			})
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermTopicsWrite))
+			r.Post("/topics", AdminTopicsCreate(cfg))
+			r.Post("/topics/bulk", AdminTopicsBulk(cfg))
+			r.Group(func(r chi.Router) {
+				r.Use(RequireTopicArea(cfg))
+				r.Post("/topics/{id}/subtopics", AdminTopicsCreateSubtopic(cfg))
+				r.Put("/topics/{id}", AdminTopicsUpdate(cfg))
+				r.Patch("/topics/{id}/enabled", AdminTopicsToggleEnabled(cfg))
+				r.Patch("/topics/{id}/status", AdminTopicsSetStatus(cfg))
+				r.Patch("/topics/{id}/premium", AdminTopicsTogglePremium(cfg))
+				r.Put("/topics/{id}/schedule", AdminTopicsSchedule(cfg))
+				r.Delete("/topics/{id}", AdminTopicsDelete(cfg))
+				r.Post("/topics/{id}/clone", AdminTopicsClone(cfg))
+			})
+		})
+
 		// Gestión de preguntas de topics
-		r.Get("/topics/{id}/available-sources", AdminGetAvailableSourceTopics(cfg))
-		r.Post("/topics/{id}/copy-questions", AdminCopyQuestionsFromTopics(cfg))
-		r.Post("/topics/{id}/upload-questions", AdminUploadQuestionsToTopic(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermQuestionsWrite))
+			r.Use(RequireTopicArea(cfg))
+			r.Post("/topics/{id}/copy-questions", AdminCopyQuestionsFromTopics(cfg))
+			r.Post("/topics/{id}/upload-questions", AdminUploadQuestionsToTopic(cfg))
+		})
 
 		// Administración de áreas
-		r.Get("/areas", AdminAreasList(cfg))
-		r.Get("/areas/{id}", AdminAreasGetByID(cfg))
-		r.Post("/areas", AdminAreasCreate(cfg))
-		r.Put("/areas/{id}", AdminAreasUpdate(cfg))
-		r.Patch("/areas/{id}/enabled", AdminAreasToggleEnabled(cfg))
-		r.Delete("/areas/{id}", AdminAreasDelete(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermAreasRead))
+			r.Get("/areas", AdminAreasList(cfg))
+			r.Get("/areas/{id}", AdminAreasGetByID(cfg))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermAreasWrite))
+			r.Post("/areas", AdminAreasCreate(cfg))
+			r.Put("/areas/{id}", AdminAreasUpdate(cfg))
+			r.Patch("/areas/{id}/enabled", AdminAreasToggleEnabled(cfg))
+			r.Delete("/areas/{id}", AdminAreasDelete(cfg))
+		})
 
 		// Administración de usuarios
-		r.Get("/users", AdminUsersList(cfg))
-		r.Patch("/users/{id}/enabled", AdminUsersToggleEnabled(cfg))
+		r.With(RequirePermission(domain.PermUsersRead)).Get("/users", AdminUsersList(cfg))
+		r.With(RequirePermission(domain.PermUsersWrite)).Patch("/users/{id}/enabled", AdminUsersToggleEnabled(cfg))
 
 		// Administración de proveedores de publicidad
-		r.Get("/providers", AdminProvidersList(cfg))
-		r.Get("/providers/{id}", AdminProvidersGetByID(cfg))
-		r.Post("/providers", AdminProvidersCreate(cfg))
-		r.Put("/providers/{id}", AdminProvidersUpdate(cfg))
-		r.Patch("/providers/{id}/enabled", AdminProvidersToggleEnabled(cfg))
-		r.Delete("/providers/{id}", AdminProvidersDelete(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermProvidersRead))
+			r.Get("/providers", AdminProvidersList(cfg))
+			r.Get("/providers/{id}", AdminProvidersGetByID(cfg))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermProvidersWrite))
+			r.Post("/providers", AdminProvidersCreate(cfg))
+			r.Put("/providers/{id}", AdminProvidersUpdate(cfg))
+			r.Patch("/providers/{id}/enabled", AdminProvidersToggleEnabled(cfg))
+			r.Delete("/providers/{id}", AdminProvidersDelete(cfg))
+		})
 
 		// Estadísticas
-		r.Get("/stats/user", AdminStatsUser(cfg))
-		r.Get("/stats/topics", AdminStatsTopics(cfg))
-		r.Get("/stats/area/{areaId}", AdminStatsArea(cfg))
-		r.Get("/stats/areas", AdminStatsAllAreas(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermStatsRead))
+			r.With(RejectAPIKey).Get("/stats/user", AdminStatsUser(cfg))
+			r.Get("/stats/topics", AdminStatsTopics(cfg))
+			r.Get("/stats/area/{areaId}", AdminStatsArea(cfg))
+			r.Get("/stats/areas", AdminStatsAllAreas(cfg))
+		})
 
 		// Gestión de base de datos
-		r.Get("/database/stats", AdminDatabaseStats(cfg))
-		r.Get("/database/download", AdminDatabaseDownload(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermDatabaseRead))
+			r.Get("/database/stats", AdminDatabaseStats(cfg))
+			r.Get("/database/download", AdminDatabaseDownload(cfg))
+		})
 
 		// Administración de notificaciones
-		r.Get("/notifications", AdminNotificationsList(cfg))
-		r.Get("/notifications/{id}", AdminNotificationsGetByID(cfg))
-		r.Post("/notifications", AdminNotificationsCreate(cfg))
-		r.Put("/notifications/{id}", AdminNotificationsUpdate(cfg))
-		r.Delete("/notifications/{id}", AdminNotificationsDelete(cfg))
-		r.Patch("/notifications/{id}/enabled", AdminNotificationsToggleEnabled(cfg))
-		r.Get("/notifications/{id}/stats", AdminNotificationsStats(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermNotificationsRead))
+			r.Get("/notifications", AdminNotificationsList(cfg))
+			r.Get("/notifications/{id}", AdminNotificationsGetByID(cfg))
+			r.Get("/notifications/{id}/stats", AdminNotificationsStats(cfg))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermNotificationsWrite))
+			r.Post("/notifications", AdminNotificationsCreate(cfg))
+			r.Put("/notifications/{id}", AdminNotificationsUpdate(cfg))
+			r.Delete("/notifications/{id}", AdminNotificationsDelete(cfg))
+			r.Patch("/notifications/{id}/enabled", AdminNotificationsToggleEnabled(cfg))
+		})
 
 		// Administración de políticas de privacidad
-		r.Get("/privacy-policies", AdminPrivacyList(cfg))
-		r.Get("/privacy-policies/area/{areaId}", AdminPrivacyGetByArea(cfg))
-		r.Post("/privacy-policies", AdminPrivacyCreate(cfg))
-		r.Put("/privacy-policies/area/{areaId}", AdminPrivacyUpdate(cfg))
-		r.Delete("/privacy-policies/area/{areaId}", AdminPrivacyDelete(cfg))
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermPrivacyRead))
+			r.Get("/privacy-policies", AdminPrivacyList(cfg))
+			r.Get("/privacy-policies/area/{areaId}", AdminPrivacyGetByArea(cfg))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(RequirePermission(domain.PermPrivacyWrite))
+			r.Post("/privacy-policies", AdminPrivacyCreate(cfg))
+			r.Put("/privacy-policies/area/{areaId}", AdminPrivacyUpdate(cfg))
+			r.Delete("/privacy-policies/area/{areaId}", AdminPrivacyDelete(cfg))
+		})
 	})
 
 	return r
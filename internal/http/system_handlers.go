@@ -0,0 +1,45 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/logging"
+)
+
+// ========== Operación del servidor ==========
+
+// AdminLogLevelGet - Consultar el nivel de log actual
+func AdminLogLevelGet(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"level": logging.Level(),
+		})
+	}
+}
+
+// AdminLogLevelUpdate - Cambiar el nivel de log sin reiniciar (solo afecta a esta instancia)
+func AdminLogLevelUpdate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		previous := logging.Level()
+		if err := logging.SetLevel(req.Level); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", err.Error())
+			return
+		}
+
+		logf(r, "⚙️ AdminLogLevelUpdate - Nivel de log cambiado de %s a %s por %s", previous, logging.Level(), userIDFromRequest(r))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"level":    logging.Level(),
+			"previous": previous,
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxBulkTopics limita el número de topics afectados por una operación masiva
+const maxBulkTopics = 500
+
+// errBulkAborted indica que la transacción se abortó porque algún topic falló
+var errBulkAborted = errors.New("operación masiva abortada")
+
+// ========== Operaciones masivas sobre topics ==========
+
+// AdminTopicsBulk - Aplicar una acción a varios topics de forma atómica
+func AdminTopicsBulk(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req domain.BulkTopicsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		// Validar acción
+		switch req.Action {
+		case "enable", "disable", "delete":
+		case "set_status":
+			if !domain.IsValidTopicStatus(req.Status) {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "status debe ser 'active', 'hidden' o 'draft'")
+				return
+			}
+		case "set_premium":
+			if req.Premium == nil {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "premium es requerido para la acción 'set_premium'")
+				return
+			}
+		case "set_type":
+			if req.Type != "topic" && req.Type != "exam" && req.Type != "misc" {
+				writeError(w, http.StatusUnprocessableEntity, "validation_error", "type debe ser 'topic', 'exam' o 'misc'")
+				return
+			}
+		default:
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "action debe ser 'enable', 'disable', 'set_status', 'set_premium', 'set_type' o 'delete'")
+			return
+		}
+
+		// Validar selección: ids o filtro, pero no ambos
+		if (len(req.IDs) == 0) == (req.Filter == nil) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "debe indicar 'ids' o 'filter' (solo uno de ellos)")
+			return
+		}
+		if req.Filter != nil && req.Filter.Area == 0 {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "filter.area es requerido")
+			return
+		}
+		scope := areaScopeFromRequest(r)
+		if req.Filter != nil && !scope.Allows(req.Filter.Area) {
+			writeAreaForbidden(w, req.Filter.Area)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
+
+		// Resolver los IDs a partir del filtro si es necesario
+		ids := req.IDs
+		if req.Filter != nil {
+			filter := buildTopicsListFilter(r, *req.Filter)
+			cur, err := col.Find(ctx, filter, options.Find().SetProjection(bson.M{"id": 1}).SetSort(bson.D{{Key: "order", Value: 1}}))
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			var matched []struct {
+				TopicID int `bson:"id"`
+			}
+			if err := cur.All(ctx, &matched); err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			ids = make([]int, len(matched))
+			for i, m := range matched {
+				ids[i] = m.TopicID
+			}
+		}
+
+		// Eliminar duplicados manteniendo el orden
+		seen := make(map[int]bool)
+		uniqueIDs := make([]int, 0, len(ids))
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				uniqueIDs = append(uniqueIDs, id)
+			}
+		}
+
+		if len(uniqueIDs) == 0 {
+			writeError(w, http.StatusUnprocessableEntity, "no_topics", "ningún topic coincide con la selección")
+			return
+		}
+		if len(uniqueIDs) > maxBulkTopics {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "como máximo se pueden procesar 500 topics por operación")
+			return
+		}
+
+		// Todos los topics seleccionados deben pertenecer a áreas del administrador
+		if !scope.all {
+			forbidden := bson.M{
+				"id":   bson.M{"$in": uniqueIDs},
+				"area": bson.M{"$nin": scope.areas},
+			}
+			var outOfScope domain.Topic
+			err := col.FindOne(ctx, forbidden).Decode(&outOfScope)
+			if err == nil {
+				writeAreaForbidden(w, outOfScope.Area)
+				return
+			}
+			if err != mongo.ErrNoDocuments {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+		}
+
+		logf(r, "🔍 AdminTopicsBulk - Acción %s sobre %d topics", req.Action, len(uniqueIDs))
+
+		// Auditoría por topic, incluidos los subtemas a los que se propaga el tipo
+		auditTrackInts(r.Context(), uniqueIDs...)
+		if req.Action == "set_type" {
+			var subtopics []struct {
+				TopicID int `bson:"id"`
+			}
+			cur, err := col.Find(ctx, bson.M{"rootId": bson.M{"$in": uniqueIDs}, "id": bson.M{"$nin": uniqueIDs}}, options.Find().SetProjection(bson.M{"id": 1}))
+			if err == nil {
+				err = cur.All(ctx, &subtopics)
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			subtopicIDs := make([]int, len(subtopics))
+			for i, st := range subtopics {
+				subtopicIDs[i] = st.TopicID
+			}
+			auditTrackInts(r.Context(), subtopicIDs...)
+		}
+
+		session, err := client.StartSession()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer session.EndSession(context.Background())
+
+		var results []domain.BulkTopicResult
+		_, txErr := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			// La transacción puede reintentarse: reiniciar resultados en cada intento
+			results = make([]domain.BulkTopicResult, 0, len(uniqueIDs))
+			failed := false
+
+			for _, id := range uniqueIDs {
+				found, err := applyBulkTopicAction(sc, col, id, req)
+				switch {
+				case err != nil:
+					// Los errores de la transacción se propagan para que el driver pueda reintentar
+					if mongo.IsNetworkError(err) || isTransientTransactionError(err) {
+						return nil, err
+					}
+					results = append(results, domain.BulkTopicResult{ID: id, Status: "error", Error: err.Error()})
+					failed = true
+				case !found:
+					results = append(results, domain.BulkTopicResult{ID: id, Status: "not_found", Error: "topic no encontrado"})
+					failed = true
+				default:
+					results = append(results, domain.BulkTopicResult{ID: id, Status: "ok"})
+				}
+			}
+
+			if failed {
+				return nil, errBulkAborted
+			}
+			return nil, nil
+		})
+
+		response := domain.BulkTopicsResponse{
+			Action:  req.Action,
+			Total:   len(uniqueIDs),
+			Results: results,
+		}
+
+		if txErr != nil {
+			if !errors.Is(txErr, errBulkAborted) {
+				logf(r, "❌ AdminTopicsBulk - Error en la transacción: %v", txErr)
+				writeError(w, http.StatusInternalServerError, "server_error", txErr.Error())
+				return
+			}
+
+			// Ningún cambio se ha aplicado: marcar los correctos como revertidos
+			for i := range response.Results {
+				if response.Results[i].Status == "ok" {
+					response.Results[i].Status = "rolled_back"
+				} else {
+					response.Failed++
+				}
+			}
+			logf(r, "⚠️ AdminTopicsBulk - Acción %s abortada: %d topics con error", req.Action, response.Failed)
+			writeJSON(w, http.StatusUnprocessableEntity, response)
+			return
+		}
+
+		response.Applied = true
+		response.Succeeded = len(results)
+
+		logf(r, "✅ AdminTopicsBulk - Acción %s aplicada a %d topics", req.Action, response.Succeeded)
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// applyBulkTopicAction aplica la acción a un topic dentro de la transacción.
+// Devuelve false si el topic no existe.
+func applyBulkTopicAction(ctx context.Context, col *mongo.Collection, id int, req domain.BulkTopicsRequest) (bool, error) {
+	if req.Action == "delete" {
+		result, err := col.DeleteOne(ctx, bson.M{"id": id})
+		if err != nil {
+			return false, err
+		}
+		return result.DeletedCount > 0, nil
+	}
+
+	now := time.Now()
+	set := bson.M{"updatedAt": now}
+	switch req.Action {
+	case "enable":
+		set["status"] = domain.TopicStatusActive
+		set["enabled"] = true
+	case "disable":
+		set["status"] = domain.TopicStatusHidden
+		set["enabled"] = false
+	case "set_status":
+		set["status"] = req.Status
+		set["enabled"] = req.Status == domain.TopicStatusActive
+	case "set_premium":
+		set["premium"] = *req.Premium
+	case "set_type":
+		set["type"] = req.Type
+	}
+
+	var topic domain.Topic
+	err := col.FindOneAndUpdate(ctx, bson.M{"id": id}, bson.M{"$set": set}).Decode(&topic)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Igual que AdminTopicsUpdate: el tipo de un tema principal se propaga a sus subtemas
+	if req.Action == "set_type" && topic.IsMainTopic() {
+		subtopicsFilter := bson.M{
+			"rootId": id,
+			"id":     bson.M{"$ne": id},
+		}
+		if _, err := col.UpdateMany(ctx, subtopicsFilter, bson.M{"$set": bson.M{"type": req.Type, "updatedAt": now}}); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isTransientTransactionError indica si el driver marcó el error como transitorio
+func isTransientTransactionError(err error) bool {
+	var labeled mongo.LabeledError
+	if errors.As(err, &labeled) {
+		return labeled.HasErrorLabel("TransientTransactionError") || labeled.HasErrorLabel("UnknownTransactionCommitResult")
+	}
+	return false
+}
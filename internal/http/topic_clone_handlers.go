@@ -0,0 +1,395 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ========== Clonación de árboles de temas ==========
+
+// AdminTopicsClone - Clonar un tema principal con todos sus subtemas en otra área
+func AdminTopicsClone(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "id debe ser un número")
+			return
+		}
+
+		var req domain.CloneTopicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		if req.TargetArea < 1 || req.TargetArea > 10 {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "targetArea debe ser entre 1 y 10")
+			return
+		}
+		if !areaScopeFromRequest(r).Allows(req.TargetArea) {
+			writeAreaForbidden(w, req.TargetArea)
+			return
+		}
+
+		if req.Questions == "" {
+			req.Questions = "none"
+		}
+		if req.Questions != "none" && req.Questions != "link" && req.Questions != "copy" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "questions debe ser 'none', 'link' o 'copy'")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		topicsCol := db.Collection("topics_uuid_map")
+		questionsCol := db.Collection("questions")
+		questionsUnitsCol := db.Collection("questions_units_uuid")
+
+		// 1. Obtener el tema principal origen
+		var source domain.Topic
+		if err := topicsCol.FindOne(ctx, bson.M{"id": id}).Decode(&source); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "tema principal no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		if !source.IsMainTopic() {
+			writeError(w, http.StatusUnprocessableEntity, "not_main_topic", "solo se pueden clonar temas principales")
+			return
+		}
+
+		// 2. Obtener subtemas ordenados
+		subtopicsFilter := bson.M{
+			"rootId": id,
+			"id":     bson.M{"$ne": id},
+		}
+		cur, err := topicsCol.Find(ctx, subtopicsFilter, options.Find().SetSort(bson.D{{Key: "order", Value: 1}}))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		var subtopics []domain.Topic
+		if err := cur.All(ctx, &subtopics); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "🔍 AdminTopicsClone - Clonando topic %d (%d subtemas) al área %d, modo preguntas: %s", id, len(subtopics), req.TargetArea, req.Questions)
+
+		// 3. Reservar IDs a partir del máximo global (igual que AdminTopicsCreate)
+		var maxTopic domain.Topic
+		err = topicsCol.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})).Decode(&maxTopic)
+		nextID := 1
+		if err == nil && maxTopic.TopicID > 0 {
+			nextID = maxTopic.TopicID + 1
+		} else if err != nil && err != mongo.ErrNoDocuments {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		// 4. Construir las copias
+		now := time.Now()
+		newRootUUID := uuid.NewString()
+		newRoot := domain.Topic{
+			ID:          uuid.NewString(),
+			TopicID:     nextID,
+			UUID:        newRootUUID,
+			RootID:      nextID,
+			RootUUID:    newRootUUID,
+			Area:        req.TargetArea,
+			Title:       source.Title,
+			Description: source.Description,
+			ImageURL:    source.ImageURL,
+			Status:      clonedTopicStatus(source),
+			Enabled:     source.Enabled,
+			Premium:     source.Premium,
+			Type:        source.Type,
+			Order:       source.Order,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		clones := []domain.Topic{newRoot}
+		mapping := []domain.CloneTopicMapping{{
+			SourceID:   source.TopicID,
+			SourceUUID: source.UUID,
+			NewID:      newRoot.TopicID,
+			NewUUID:    newRoot.UUID,
+		}}
+		byUUID := map[string]domain.Topic{source.UUID: newRoot}
+
+		for i, st := range subtopics {
+			clone := domain.Topic{
+				ID:          uuid.NewString(),
+				TopicID:     nextID + i + 1,
+				UUID:        uuid.NewString(),
+				RootID:      newRoot.TopicID,
+				RootUUID:    newRoot.RootUUID,
+				Area:        req.TargetArea,
+				Title:       st.Title,
+				Description: st.Description,
+				ImageURL:    st.ImageURL,
+				Status:      clonedTopicStatus(st),
+				Enabled:     st.Enabled,
+				Premium:     st.Premium,
+				Type:        st.Type,
+				Order:       st.Order,
+				ParentUUID:  newRoot.UUID,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			clones = append(clones, clone)
+			mapping = append(mapping, domain.CloneTopicMapping{
+				SourceID:   st.TopicID,
+				SourceUUID: st.UUID,
+				NewID:      clone.TopicID,
+				NewUUID:    clone.UUID,
+			})
+			byUUID[st.UUID] = clone
+		}
+
+		docs := make([]interface{}, len(clones))
+		for i, t := range clones {
+			docs[i] = t
+		}
+
+		// Si falla la copia, eliminar todo lo creado (temas, preguntas copiadas y
+		// relaciones nuevas) para no dejar árboles a medias ni preguntas huérfanas
+		var copiedQuestionIDs []int
+		rollback := func() {
+			rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer rollbackCancel()
+
+			newUUIDs := make([]string, len(clones))
+			for i, t := range clones {
+				newUUIDs[i] = t.UUID
+			}
+			if _, err := questionsUnitsCol.DeleteMany(rollbackCtx, bson.M{"topicUuid": bson.M{"$in": newUUIDs}}); err != nil {
+				logf(r, "❌ AdminTopicsClone - Error revirtiendo relaciones clonadas: %v", err)
+			}
+			if len(copiedQuestionIDs) > 0 {
+				if _, err := questionsCol.DeleteMany(rollbackCtx, bson.M{"questionId": bson.M{"$in": copiedQuestionIDs}}); err != nil {
+					logf(r, "❌ AdminTopicsClone - Error revirtiendo preguntas copiadas: %v", err)
+				}
+			}
+			if _, err := topicsCol.DeleteMany(rollbackCtx, bson.M{"uuid": bson.M{"$in": newUUIDs}}); err != nil {
+				logf(r, "❌ AdminTopicsClone - Error revirtiendo temas clonados: %v", err)
+			}
+		}
+
+		// Auditoría: una entrada por cada tema creado
+		cloneIDs := make([]int, len(clones))
+		for i, t := range clones {
+			cloneIDs[i] = t.TopicID
+		}
+		auditTrackInts(r.Context(), cloneIDs...)
+
+		if _, err := topicsCol.InsertMany(ctx, docs); err != nil {
+			rollback()
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		response := domain.CloneTopicResponse{
+			Topic:         newRoot,
+			Mapping:       mapping,
+			QuestionsMode: req.Questions,
+		}
+
+		// 5. Preguntas: enlazar las existentes o copiarlas
+		if req.Questions != "none" {
+			sourceUUIDs := make([]string, 0, len(byUUID))
+			for u := range byUUID {
+				sourceUUIDs = append(sourceUUIDs, u)
+			}
+
+			unitsCur, err := questionsUnitsCol.Find(ctx, bson.M{"topicUuid": bson.M{"$in": sourceUUIDs}})
+			if err != nil {
+				rollback()
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+			var units []domain.QuestionUnit
+			if err := unitsCur.All(ctx, &units); err != nil {
+				rollback()
+				writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+				return
+			}
+
+			// questionId origen -> questionId destino
+			questionIDMap := make(map[int]int)
+			for _, u := range units {
+				questionIDMap[u.QuestionID] = u.QuestionID
+			}
+
+			if req.Questions == "copy" && len(questionIDMap) > 0 {
+				sourceIDs := make([]int, 0, len(questionIDMap))
+				for qid := range questionIDMap {
+					sourceIDs = append(sourceIDs, qid)
+				}
+
+				qCur, err := questionsCol.Find(ctx, bson.M{"questionId": bson.M{"$in": sourceIDs}}, options.Find().SetSort(bson.D{{Key: "questionId", Value: 1}}))
+				if err != nil {
+					rollback()
+					writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+					return
+				}
+				var questions []domain.Question
+				if err := qCur.All(ctx, &questions); err != nil {
+					rollback()
+					writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+					return
+				}
+
+				maxQuestionID, maxAnswerID, err := maxQuestionAndAnswerIDs(ctx, questionsCol)
+				if err != nil {
+					rollback()
+					writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+					return
+				}
+
+				var questionOps []mongo.WriteModel
+				for _, q := range questions {
+					maxQuestionID++
+					answers := make([]domain.QuestionAnswer, len(q.Answers))
+					for i, a := range q.Answers {
+						maxAnswerID++
+						answers[i] = domain.QuestionAnswer{ID: maxAnswerID, Text: a.Text, Correct: a.Correct}
+					}
+
+					questionDoc := bson.M{
+						"questionId":  maxQuestionID,
+						"question":    q.Question,
+						"provider":    q.Provider,
+						"created":     time.Now().Format("2006-01-02 15:04:05"),
+						"enabled":     q.Enabled,
+						"explanation": q.Explanation,
+						"answers":     answers,
+					}
+					questionOps = append(questionOps, mongo.NewInsertOneModel().SetDocument(questionDoc))
+					questionIDMap[q.QuestionID] = maxQuestionID
+					copiedQuestionIDs = append(copiedQuestionIDs, maxQuestionID)
+				}
+
+				// Las unidades que apuntan a preguntas inexistentes no se copian
+				for qid, newID := range questionIDMap {
+					if newID == qid {
+						delete(questionIDMap, qid)
+					}
+				}
+
+				if len(questionOps) > 0 {
+					if _, err := questionsCol.BulkWrite(ctx, questionOps); err != nil {
+						rollback()
+						writeError(w, http.StatusInternalServerError, "server_error", "error copiando preguntas: "+err.Error())
+						return
+					}
+				}
+
+				response.QuestionsCopied = len(questionOps)
+				response.QuestionIDMap = questionIDMap
+			}
+
+			// Crear las relaciones topic-pregunta para los temas nuevos
+			var unitOps []mongo.WriteModel
+			for _, u := range units {
+				target, ok := byUUID[u.TopicUuid]
+				if !ok {
+					continue
+				}
+				questionID, ok := questionIDMap[u.QuestionID]
+				if !ok {
+					continue
+				}
+				unitDoc := bson.M{
+					"topicId":       target.TopicID,
+					"topicUuid":     target.UUID,
+					"rootTopicId":   target.RootID,
+					"rootTopicUuid": target.RootUUID,
+					"area":          target.Area,
+					"questionId":    questionID,
+				}
+				unitOps = append(unitOps, mongo.NewInsertOneModel().SetDocument(unitDoc))
+			}
+
+			if len(unitOps) > 0 {
+				if _, err := questionsUnitsCol.BulkWrite(ctx, unitOps); err != nil {
+					rollback()
+					writeError(w, http.StatusInternalServerError, "server_error", "error enlazando preguntas: "+err.Error())
+					return
+				}
+			}
+			response.QuestionsLinked = len(unitOps)
+		}
+
+		response.Message = fmt.Sprintf("Se clonaron %d temas en el área %d", len(clones), req.TargetArea)
+
+		logf(r, "✅ AdminTopicsClone - Topic %d clonado como %d en área %d (%d temas, %d relaciones, %d preguntas copiadas)", id, newRoot.TopicID, req.TargetArea, len(clones), response.QuestionsLinked, response.QuestionsCopied)
+		writeJSON(w, http.StatusCreated, response)
+	}
+}
+
+// clonedTopicStatus conserva el estado del tema origen; los temas anteriores al campo status
+// solo tienen el booleano enabled
+func clonedTopicStatus(t domain.Topic) string {
+	if t.Status == "" {
+		return domain.TopicStatusFromEnabled(t.Enabled)
+	}
+	return t.Status
+}
+
+// maxQuestionAndAnswerIDs obtiene el máximo questionId y el máximo id de respuesta
+// para poder asignar identificadores nuevos sin colisiones
+func maxQuestionAndAnswerIDs(ctx context.Context, questionsCol *mongo.Collection) (int, int, error) {
+	maxQuestionID := 0
+	var lastQuestion domain.Question
+	err := questionsCol.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "questionId", Value: -1}})).Decode(&lastQuestion)
+	if err == nil {
+		maxQuestionID = lastQuestion.QuestionID
+	} else if err != mongo.ErrNoDocuments {
+		return 0, 0, err
+	}
+
+	maxAnswerID := 0
+	var qWithAnswers struct {
+		Answers []domain.QuestionAnswer `bson:"answers"`
+	}
+	err = questionsCol.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "answers.id", Value: -1}})).Decode(&qWithAnswers)
+	if err == nil {
+		for _, ans := range qWithAnswers.Answers {
+			if ans.ID > maxAnswerID {
+				maxAnswerID = ans.ID
+			}
+		}
+	} else if err != mongo.ErrNoDocuments {
+		return 0, 0, err
+	}
+
+	return maxQuestionID, maxAnswerID, nil
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AdminTopicsSchedule - Programar la publicación/despublicación de un topic
+func AdminTopicsSchedule(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_id", "id debe ser un número")
+			return
+		}
+
+		var req domain.ScheduleTopicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		if req.PublishAt != nil && req.UnpublishAt != nil && !req.UnpublishAt.After(*req.PublishAt) {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "unpublishAt debe ser posterior a publishAt")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		col := client.Database(cfg.DBName).Collection("topics_uuid_map")
+
+		// Al reprogramar se limpian las marcas del scheduler para que vuelva a actuar
+		set := bson.M{"updatedAt": time.Now()}
+		unset := bson.M{}
+		if req.PublishAt != nil {
+			set["publishAt"] = *req.PublishAt
+		} else {
+			unset["publishAt"] = ""
+		}
+		unset["publishedAt"] = ""
+		if req.UnpublishAt != nil {
+			set["unpublishAt"] = *req.UnpublishAt
+		} else {
+			unset["unpublishAt"] = ""
+		}
+		unset["unpublishedAt"] = ""
+
+		update := bson.M{"$set": set, "$unset": unset}
+
+		var topic domain.Topic
+		if err := col.FindOneAndUpdate(ctx, bson.M{"id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&topic); err != nil {
+			if err == mongo.ErrNoDocuments {
+				writeError(w, http.StatusNotFound, "not_found", "topic no encontrado")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminTopicsSchedule - Topic %d programado (publishAt: %v, unpublishAt: %v)", id, req.PublishAt, req.UnpublishAt)
+		writeJSON(w, http.StatusOK, topic)
+	}
+}
@@ -0,0 +1,804 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	adminSettingsCollection   = "admin_settings"
+	loginChallengesCollection = "admin_login_challenges"
+
+	loginChallengeVerify = "verify" // El administrador tiene 2FA y debe introducir el código
+	loginChallengeEnroll = "enroll" // El 2FA es obligatorio y el administrador debe configurarlo
+
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+	recoveryCodesCount        = 10
+)
+
+var (
+	errInvalidTwoFactorCode = errors.New("código de verificación incorrecto")
+	errNoPendingEnrollment  = errors.New("no hay ningún alta de 2FA pendiente; inicia el proceso de nuevo")
+	errInvalidChallenge     = errors.New("el inicio de sesión ha caducado; vuelve a introducir tus credenciales")
+)
+
+// AuthTwoFactorVerify - Completar el login con el código TOTP (o un código de recuperación)
+func AuthTwoFactorVerify(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ChallengeToken string `json:"challengeToken"`
+			Code           string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+		if req.ChallengeToken == "" || strings.TrimSpace(req.Code) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "challengeToken y code requeridos")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		challenge, user, err := loadLoginChallenge(ctx, db, req.ChallengeToken, loginChallengeVerify)
+		if err != nil {
+			writeChallengeError(w, err)
+			return
+		}
+
+		// Los fallos del segundo factor cuentan para el bloqueo del email igual que los de contraseña
+		ip := clientIP(r)
+		if wait, err := loginRetryAfter(ctx, db, ip, user.Email, cfg); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		} else if wait > 0 {
+			logf(r, "🔒 Segundo factor rechazado por intentos fallidos: %s (IP %s)", user.Email, ip)
+			writeTooManyAttempts(w, wait)
+			return
+		}
+
+		if err := verifyTwoFactorCode(ctx, db.Collection("user"), user, req.Code); err != nil {
+			registerChallengeFailure(ctx, db, challenge)
+			registerLoginFailure(ctx, db, ip, user.Email, &user, cfg)
+			writeTwoFactorCodeError(w, err)
+			return
+		}
+
+		db.Collection(loginChallengesCollection).DeleteOne(ctx, bson.M{"_id": challenge.ID})
+		clearLoginFailures(ctx, db, user.Email)
+
+		response, err := completeAdminLogin(ctx, db, user, r, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AuthTwoFactorVerify - Login con 2FA completado: %s", user.Email)
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// AuthTwoFactorEnrollStart - Generar el secreto TOTP durante un login con 2FA obligatorio
+func AuthTwoFactorEnrollStart(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ChallengeToken string `json:"challengeToken"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		_, user, err := loadLoginChallenge(ctx, db, req.ChallengeToken, loginChallengeEnroll)
+		if err != nil {
+			writeChallengeError(w, err)
+			return
+		}
+
+		secret, uri, err := startTwoFactorEnrollment(ctx, db.Collection("user"), user, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"secret":          secret,
+			"provisioningUri": uri,
+		})
+	}
+}
+
+// AuthTwoFactorEnrollConfirm - Verificar el primer código, activar el 2FA y completar el login
+func AuthTwoFactorEnrollConfirm(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ChallengeToken string `json:"challengeToken"`
+			Code           string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		challenge, user, err := loadLoginChallenge(ctx, db, req.ChallengeToken, loginChallengeEnroll)
+		if err != nil {
+			writeChallengeError(w, err)
+			return
+		}
+
+		// Los fallos del segundo factor cuentan para el bloqueo del email igual que los de contraseña
+		ip := clientIP(r)
+		if wait, err := loginRetryAfter(ctx, db, ip, user.Email, cfg); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		} else if wait > 0 {
+			logf(r, "🔒 Segundo factor rechazado por intentos fallidos: %s (IP %s)", user.Email, ip)
+			writeTooManyAttempts(w, wait)
+			return
+		}
+
+		recoveryCodes, err := confirmTwoFactorEnrollment(ctx, db.Collection("user"), user, req.Code)
+		if err != nil {
+			registerChallengeFailure(ctx, db, challenge)
+			registerLoginFailure(ctx, db, ip, user.Email, &user, cfg)
+			writeTwoFactorCodeError(w, err)
+			return
+		}
+
+		db.Collection(loginChallengesCollection).DeleteOne(ctx, bson.M{"_id": challenge.ID})
+		clearLoginFailures(ctx, db, user.Email)
+
+		response, err := completeAdminLogin(ctx, db, user, r, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		response["recoveryCodes"] = recoveryCodes
+
+		logf(r, "✅ AuthTwoFactorEnrollConfirm - 2FA activado durante el login: %s", user.Email)
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// AdminTwoFactorStatus - Estado del 2FA del administrador autenticado
+func AdminTwoFactorStatus(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		user, ok := findCurrentAdmin(ctx, w, r, db)
+		if !ok {
+			return
+		}
+		settings, err := loadSecuritySettings(ctx, db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		enabled := user.TwoFactor != nil && user.TwoFactor.Enabled
+		remaining := 0
+		var enabledAt *time.Time
+		if enabled {
+			remaining = len(user.TwoFactor.RecoveryCodes)
+			enabledAt = user.TwoFactor.EnabledAt
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"enabled":                enabled,
+			"enabledAt":              enabledAt,
+			"required":               settings.RequireTwoFactor,
+			"recoveryCodesRemaining": remaining,
+		})
+	}
+}
+
+// AdminTwoFactorEnroll - Iniciar el alta del 2FA (devuelve secreto y URI para el QR)
+func AdminTwoFactorEnroll(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		user, ok := findCurrentAdmin(ctx, w, r, db)
+		if !ok {
+			return
+		}
+		if user.TwoFactor != nil && user.TwoFactor.Enabled {
+			writeError(w, http.StatusConflict, "already_enabled", "el 2FA ya está activado; desactívalo antes de volver a configurarlo")
+			return
+		}
+
+		secret, uri, err := startTwoFactorEnrollment(ctx, db.Collection("user"), user, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"secret":          secret,
+			"provisioningUri": uri,
+		})
+	}
+}
+
+// AdminTwoFactorConfirm - Verificar el primer código y activar el 2FA
+func AdminTwoFactorConfirm(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		user, ok := findCurrentAdmin(ctx, w, r, db)
+		if !ok {
+			return
+		}
+
+		recoveryCodes, err := confirmTwoFactorEnrollment(ctx, db.Collection("user"), user, req.Code)
+		if err != nil {
+			writeTwoFactorCodeError(w, err)
+			return
+		}
+
+		logf(r, "✅ AdminTwoFactorConfirm - 2FA activado: %s", user.Email)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message":       "Verificación en dos pasos activada",
+			"recoveryCodes": recoveryCodes,
+		})
+	}
+}
+
+// AdminTwoFactorRecoveryCodes - Regenerar los códigos de recuperación (invalida los anteriores)
+func AdminTwoFactorRecoveryCodes(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		users := db.Collection("user")
+
+		user, ok := findCurrentAdmin(ctx, w, r, db)
+		if !ok {
+			return
+		}
+		if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+			writeError(w, http.StatusConflict, "not_enabled", "el 2FA no está activado")
+			return
+		}
+		if err := verifyTwoFactorCode(ctx, users, user, req.Code); err != nil {
+			writeTwoFactorCodeError(w, err)
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+			"$set": bson.M{"twoFactor.recoveryCodes": hashes, "updatedAt": time.Now()},
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"recoveryCodes": codes,
+		})
+	}
+}
+
+// AdminTwoFactorDisable - Desactivar el 2FA propio (no permitido si es obligatorio)
+func AdminTwoFactorDisable(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+		users := db.Collection("user")
+
+		user, ok := findCurrentAdmin(ctx, w, r, db)
+		if !ok {
+			return
+		}
+		if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+			writeError(w, http.StatusConflict, "not_enabled", "el 2FA no está activado")
+			return
+		}
+
+		settings, err := loadSecuritySettings(ctx, db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if settings.RequireTwoFactor {
+			writeError(w, http.StatusUnprocessableEntity, "two_factor_required", "el 2FA es obligatorio para todos los administradores")
+			return
+		}
+
+		if err := verifyTwoFactorCode(ctx, users, user, req.Code); err != nil {
+			writeTwoFactorCodeError(w, err)
+			return
+		}
+
+		if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+			"$unset": bson.M{"twoFactor": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		}); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminTwoFactorDisable - 2FA desactivado: %s", user.Email)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Verificación en dos pasos desactivada",
+		})
+	}
+}
+
+// AdminAccountsResetTwoFactor - Quitar el 2FA de un administrador (p. ej. ha perdido el dispositivo)
+func AdminAccountsResetTwoFactor(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		result, err := db.Collection("user").UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+			"$unset": bson.M{"twoFactor": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.MatchedCount == 0 {
+			writeError(w, http.StatusNotFound, "not_found", "administrador no encontrado")
+			return
+		}
+
+		// Las sesiones abiertas con el segundo factor anterior dejan de ser válidas
+		if err := revokeAdminSessions(ctx, db, id); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminAccountsResetTwoFactor - 2FA eliminado para el administrador %s", id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "2FA del administrador restablecido; deberá configurarlo de nuevo si es obligatorio",
+		})
+	}
+}
+
+// AdminSecuritySettingsGet - Obtener la configuración global de seguridad
+func AdminSecuritySettingsGet(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		settings, err := loadSecuritySettings(ctx, client.Database(cfg.DBName))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, settings)
+	}
+}
+
+// AdminSecuritySettingsUpdate - Actualizar la configuración global de seguridad
+func AdminSecuritySettingsUpdate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RequireTwoFactor *bool `json:"requireTwoFactor"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "invalid json")
+			return
+		}
+		if req.RequireTwoFactor == nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "requireTwoFactor requerido")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		client, err := getMongoClient(ctx, cfg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		defer client.Disconnect(context.Background())
+
+		db := client.Database(cfg.DBName)
+
+		update := bson.M{
+			"requireTwoFactor": *req.RequireTwoFactor,
+			"updatedAt":        time.Now(),
+			"updatedBy":        userIDFromRequest(r),
+		}
+		if _, err := db.Collection(adminSettingsCollection).UpdateOne(ctx,
+			bson.M{"_id": "security"},
+			bson.M{"$set": update},
+			options.Update().SetUpsert(true),
+		); err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		settings, err := loadSecuritySettings(ctx, db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		logf(r, "✅ AdminSecuritySettingsUpdate - requireTwoFactor=%v", settings.RequireTwoFactor)
+		writeJSON(w, http.StatusOK, settings)
+	}
+}
+
+// loadSecuritySettings devuelve la configuración de seguridad (valores por defecto si no existe)
+func loadSecuritySettings(ctx context.Context, db *mongo.Database) (domain.SecuritySettings, error) {
+	var settings domain.SecuritySettings
+	err := db.Collection(adminSettingsCollection).FindOne(ctx, bson.M{"_id": "security"}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return domain.SecuritySettings{}, nil
+	}
+	return settings, err
+}
+
+// twoFactorPurpose indica qué segundo factor exige el login de un administrador: verificar su
+// TOTP, configurarlo (si es obligatorio y aún no lo tiene) o ninguno ("")
+func twoFactorPurpose(ctx context.Context, db *mongo.Database, user domain.User) (string, error) {
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		return loginChallengeVerify, nil
+	}
+	settings, err := loadSecuritySettings(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if settings.RequireTwoFactor {
+		return loginChallengeEnroll, nil
+	}
+	return "", nil
+}
+
+// writeTwoFactorChallenge crea un login pendiente y responde con su token
+func writeTwoFactorChallenge(ctx context.Context, w http.ResponseWriter, db *mongo.Database, user domain.User, purpose string) {
+	response, err := createTwoFactorChallenge(ctx, db, user, purpose)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// createTwoFactorChallenge guarda un login pendiente de 2FA y devuelve la respuesta para el panel
+func createTwoFactorChallenge(ctx context.Context, db *mongo.Database, user domain.User, purpose string) (map[string]interface{}, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	challenge := domain.LoginChallenge{
+		ID:        hashRefreshToken(token),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(loginChallengeTTL),
+	}
+	if _, err := db.Collection(loginChallengesCollection).InsertOne(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	logging.Printf(ctx, "🔐 Login pendiente de 2FA (%s): %s", purpose, user.Email)
+	return map[string]interface{}{
+		"twoFactorRequired":      purpose == loginChallengeVerify,
+		"twoFactorSetupRequired": purpose == loginChallengeEnroll,
+		"challengeToken":         token,
+		"expiresIn":              int64(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// loadLoginChallenge valida el token de un login pendiente y devuelve el administrador
+func loadLoginChallenge(ctx context.Context, db *mongo.Database, token, purpose string) (domain.LoginChallenge, domain.User, error) {
+	var challenge domain.LoginChallenge
+	if err := db.Collection(loginChallengesCollection).FindOne(ctx, bson.M{
+		"_id":       hashRefreshToken(token),
+		"purpose":   purpose,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&challenge); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return challenge, domain.User{}, errInvalidChallenge
+		}
+		return challenge, domain.User{}, err
+	}
+
+	var user domain.User
+	if err := db.Collection("user").FindOne(ctx, bson.M{"_id": challenge.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return challenge, user, errInvalidChallenge
+		}
+		return challenge, user, err
+	}
+	if user.Disabled {
+		return challenge, user, errInvalidChallenge
+	}
+
+	return challenge, user, nil
+}
+
+// registerChallengeFailure cuenta un código fallido; al superar el máximo se anula el login pendiente
+func registerChallengeFailure(ctx context.Context, db *mongo.Database, challenge domain.LoginChallenge) {
+	col := db.Collection(loginChallengesCollection)
+	if challenge.Attempts+1 >= loginChallengeMaxAttempts {
+		col.DeleteOne(ctx, bson.M{"_id": challenge.ID})
+		return
+	}
+	col.UpdateOne(ctx, bson.M{"_id": challenge.ID}, bson.M{"$inc": bson.M{"attempts": 1}})
+}
+
+// startTwoFactorEnrollment guarda un secreto pendiente y devuelve la URI de aprovisionamiento
+func startTwoFactorEnrollment(ctx context.Context, users *mongo.Collection, user domain.User, cfg config.Config) (string, string, error) {
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{"twoFactor.enabled": false, "twoFactor.pendingSecret": secret},
+	}); err != nil {
+		return "", "", err
+	}
+	return secret, services.TOTPProvisioningURI(cfg.TOTPIssuer, user.Email, secret), nil
+}
+
+// confirmTwoFactorEnrollment activa el 2FA si el código corresponde al secreto pendiente
+// y devuelve los códigos de recuperación en claro (solo se muestran esta vez)
+func confirmTwoFactorEnrollment(ctx context.Context, users *mongo.Collection, user domain.User, code string) ([]string, error) {
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, errNoPendingEnrollment
+	}
+	secret := user.TwoFactor.PendingSecret
+
+	step, ok := services.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := users.UpdateOne(ctx, bson.M{"_id": user.ID, "twoFactor.pendingSecret": secret}, bson.M{
+		"$set": bson.M{
+			"twoFactor": domain.TwoFactor{
+				Enabled:       true,
+				Secret:        secret,
+				RecoveryCodes: hashes,
+				LastStep:      step,
+				EnabledAt:     &now,
+			},
+			"updatedAt": now,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errNoPendingEnrollment
+	}
+	return codes, nil
+}
+
+// verifyTwoFactorCode acepta un código TOTP no usado antes o un código de recuperación (que se consume)
+func verifyTwoFactorCode(ctx context.Context, users *mongo.Collection, user domain.User, code string) error {
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		return errInvalidTwoFactorCode
+	}
+	code = strings.TrimSpace(code)
+
+	if step, ok := services.ValidateTOTPAfter(user.TwoFactor.Secret, code, time.Now(), user.TwoFactor.LastStep); ok {
+		// Solo se acepta si el paso es posterior al último usado (evita reutilizar el código);
+		// el filtro lo garantiza también frente a dos peticiones simultáneas
+		result, err := users.UpdateOne(ctx, bson.M{"_id": user.ID, "twoFactor.lastStep": bson.M{"$lt": step}}, bson.M{
+			"$set": bson.M{"twoFactor.lastStep": step},
+		})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	result, err := users.UpdateOne(ctx, bson.M{"_id": user.ID, "twoFactor.recoveryCodes": hashRefreshToken(strings.ToLower(code))}, bson.M{
+		"$pull": bson.M{"twoFactor.recoveryCodes": hashRefreshToken(strings.ToLower(code))},
+	})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errInvalidTwoFactorCode
+	}
+	logging.Printf(ctx, "⚠️ Código de recuperación 2FA utilizado por %s", user.Email)
+	return nil
+}
+
+// newRecoveryCodes genera los códigos de recuperación y sus hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := services.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashRefreshToken(c)
+	}
+	return codes, hashes, nil
+}
+
+// findCurrentAdmin carga el administrador autenticado; si falla responde con el error
+func findCurrentAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request, db *mongo.Database) (domain.User, bool) {
+	var user domain.User
+	if err := db.Collection("user").FindOne(ctx, bson.M{"_id": userIDFromRequest(r)}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			writeError(w, http.StatusNotFound, "not_found", "usuario no encontrado")
+			return user, false
+		}
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return user, false
+	}
+	return user, true
+}
+
+func writeChallengeError(w http.ResponseWriter, err error) {
+	if err == errInvalidChallenge {
+		writeError(w, http.StatusUnauthorized, "invalid_challenge", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+}
+
+func writeTwoFactorCodeError(w http.ResponseWriter, err error) {
+	switch err {
+	case errInvalidTwoFactorCode:
+		writeError(w, http.StatusUnauthorized, "invalid_code", err.Error())
+	case errNoPendingEnrollment:
+		writeError(w, http.StatusConflict, "no_pending_enrollment", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+	}
+}
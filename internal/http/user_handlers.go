@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"html/template"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -18,10 +17,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Los usuarios de la app (colección "users") mantienen la lógica invertida de la app:
+// enabled:false = Habilitado, enabled:true = Deshabilitado. Migrarlos a un campo explícito
+// como el status de los topics queda fuera de alcance
+const appUserDisabled = true
+
 // UserDeactivateForm - Muestra el formulario HTML para solicitar baja (endpoint público GET)
 func UserDeactivateForm(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		renderDeactivationFormPage(w, cfg)
+		renderDeactivationFormPage(w, r, cfg)
 	}
 }
 
@@ -65,33 +69,33 @@ func UserDeactivateRequest(cfg config.Config) http.HandlerFunc {
 
 		client, err := getMongoClient(ctx, cfg)
 		if err != nil {
-			log.Printf("❌ Error conectando a MongoDB: %v", err)
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 		defer client.Disconnect(context.Background())
 
-		users := client.Database(cfg.DBName).Collection("user")
+		users := client.Database(cfg.DBName).Collection("users")
 
 		// Verificar si el usuario existe
 		var user domain.User
 		if err := users.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user); err != nil {
 			if err == mongo.ErrNoDocuments {
 				// Por seguridad, no revelamos si el email existe o no
-				log.Printf("⚠️ Solicitud de baja para email no registrado: %s", req.Email)
+				logf(r, "⚠️ Solicitud de baja para email no registrado: %s", req.Email)
 				writeJSON(w, http.StatusOK, map[string]interface{}{
 					"message": "Si el email existe, recibirás un correo con las instrucciones para confirmar la baja.",
 				})
 				return
 			}
-			log.Printf("❌ Error buscando usuario: %v", err)
+			logf(r, "❌ Error buscando usuario: %v", err)
 			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 			return
 		}
 
 		// Verificar si el usuario ya está desactivado
-		if !user.Enabled {
-			log.Printf("⚠️ Usuario ya desactivado: %s", req.Email)
+		if user.Enabled == appUserDisabled {
+			logf(r, "⚠️ Usuario ya desactivado: %s", req.Email)
 			writeJSON(w, http.StatusOK, map[string]interface{}{
 				"message": "Si el email existe, recibirás un correo con las instrucciones para confirmar la baja.",
 			})
@@ -103,8 +107,8 @@ func UserDeactivateRequest(cfg config.Config) http.HandlerFunc {
 
 		// Enviar email de confirmación
 		emailService := services.NewEmailService(cfg)
-		if err := emailService.SendDeactivationEmail(user.Email, token); err != nil {
-			log.Printf("❌ Error enviando email de desactivación: %v", err)
+		if err := emailService.SendDeactivationEmail(r.Context(), user.Email, token); err != nil {
+			logf(r, "❌ Error enviando email de desactivación: %v", err)
 			// No revelamos el error al usuario por seguridad
 			writeJSON(w, http.StatusOK, map[string]interface{}{
 				"message": "Si el email existe, recibirás un correo con las instrucciones para confirmar la baja.",
@@ -112,7 +116,7 @@ func UserDeactivateRequest(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ Email de desactivación enviado a: %s", req.Email)
+		logf(r, "✅ Email de desactivación enviado a: %s", req.Email)
 
 		writeJSON(w, http.StatusOK, map[string]interface{}{
 			"message": "Si el email existe, recibirás un correo con las instrucciones para confirmar la baja.",
@@ -125,21 +129,21 @@ func UserDeactivateConfirm(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := r.URL.Query().Get("token")
 		if token == "" {
-			renderDeactivationPage(w, false, "Token de confirmación no proporcionado")
+			renderDeactivationPage(w, r, false, "Token de confirmación no proporcionado")
 			return
 		}
 
 		// Validar y decodificar token
 		claims, err := validateDeactivationToken(token, cfg)
 		if err != nil {
-			log.Printf("❌ Token inválido: %v", err)
-			renderDeactivationPage(w, false, "Token inválido o expirado. Por favor, solicita una nueva baja.")
+			logf(r, "❌ Token inválido: %v", err)
+			renderDeactivationPage(w, r, false, "Token inválido o expirado. Por favor, solicita una nueva baja.")
 			return
 		}
 
 		userID, ok := claims["sub"].(string)
 		if !ok {
-			renderDeactivationPage(w, false, "Token inválido")
+			renderDeactivationPage(w, r, false, "Token inválido")
 			return
 		}
 
@@ -148,49 +152,49 @@ func UserDeactivateConfirm(cfg config.Config) http.HandlerFunc {
 
 		client, err := getMongoClient(ctx, cfg)
 		if err != nil {
-			log.Printf("❌ Error conectando a MongoDB: %v", err)
-			renderDeactivationPage(w, false, "Error interno del servidor. Por favor, intenta más tarde.")
+			logf(r, "❌ Error conectando a MongoDB: %v", err)
+			renderDeactivationPage(w, r, false, "Error interno del servidor. Por favor, intenta más tarde.")
 			return
 		}
 		defer client.Disconnect(context.Background())
 
-		users := client.Database(cfg.DBName).Collection("user")
+		users := client.Database(cfg.DBName).Collection("users")
 
 		// Buscar usuario
 		var user domain.User
 		if err := users.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
 			if err == mongo.ErrNoDocuments {
-				renderDeactivationPage(w, false, "Usuario no encontrado")
+				renderDeactivationPage(w, r, false, "Usuario no encontrado")
 				return
 			}
-			log.Printf("❌ Error buscando usuario: %v", err)
-			renderDeactivationPage(w, false, "Error interno del servidor. Por favor, intenta más tarde.")
+			logf(r, "❌ Error buscando usuario: %v", err)
+			renderDeactivationPage(w, r, false, "Error interno del servidor. Por favor, intenta más tarde.")
 			return
 		}
 
 		// Desactivar usuario
 		update := bson.M{
 			"$set": bson.M{
-				"enabled":   false,
+				"enabled":   appUserDisabled,
 				"updatedAt": time.Now(),
 			},
 		}
 
 		result, err := users.UpdateOne(ctx, bson.M{"_id": userID}, update)
 		if err != nil {
-			log.Printf("❌ Error desactivando usuario: %v", err)
-			renderDeactivationPage(w, false, "Error al procesar la solicitud. Por favor, intenta más tarde.")
+			logf(r, "❌ Error desactivando usuario: %v", err)
+			renderDeactivationPage(w, r, false, "Error al procesar la solicitud. Por favor, intenta más tarde.")
 			return
 		}
 
 		if result.MatchedCount == 0 {
-			renderDeactivationPage(w, false, "Usuario no encontrado")
+			renderDeactivationPage(w, r, false, "Usuario no encontrado")
 			return
 		}
 
-		log.Printf("✅ Usuario desactivado: %s (ID: %s)", user.Email, userID)
+		logf(r, "✅ Usuario desactivado: %s (ID: %s)", user.Email, userID)
 
-		renderDeactivationPage(w, true, "Tu cuenta ha sido desactivada correctamente.")
+		renderDeactivationPage(w, r, true, "Tu cuenta ha sido desactivada correctamente.")
 	}
 }
 
@@ -239,7 +243,7 @@ func validateDeactivationToken(tokenString string, cfg config.Config) (jwt.MapCl
 }
 
 // renderDeactivationPage renderiza la página HTML de confirmación
-func renderDeactivationPage(w http.ResponseWriter, success bool, message string) {
+func renderDeactivationPage(w http.ResponseWriter, r *http.Request, success bool, message string) {
 	tmpl := `<!DOCTYPE html>
 <html lang="es">
 <head>
@@ -337,13 +341,13 @@ func renderDeactivationPage(w http.ResponseWriter, success bool, message string)
 	}
 
 	if err := t.Execute(w, data); err != nil {
-		log.Printf("❌ Error ejecutando template: %v", err)
+		logf(r, "❌ Error ejecutando template: %v", err)
 		http.Error(w, "Error interno", http.StatusInternalServerError)
 	}
 }
 
 // renderDeactivationFormPage renderiza el formulario HTML para solicitar baja
-func renderDeactivationFormPage(w http.ResponseWriter, cfg config.Config) {
+func renderDeactivationFormPage(w http.ResponseWriter, r *http.Request, cfg config.Config) {
 	tmpl := `<!DOCTYPE html>
 <html lang="es">
 <head>
@@ -587,7 +591,7 @@ func renderDeactivationFormPage(w http.ResponseWriter, cfg config.Config) {
 	}
 
 	if err := t.Execute(w, data); err != nil {
-		log.Printf("❌ Error ejecutando template: %v", err)
+		logf(r, "❌ Error ejecutando template: %v", err)
 		http.Error(w, "Error interno", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,85 @@
+// Package lifecycle registra los trabajos en segundo plano del servidor para poder esperar a
+// que terminen durante el apagado (SIGTERM en Cloud Run).
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	running      = map[string]int{}
+	shuttingDown atomic.Bool
+)
+
+// Go ejecuta fn en una goroutine registrada; Wait espera a que termine. Los pánicos se
+// registran en el log para no tumbar el servidor.
+func Go(name string, fn func()) {
+	wg.Add(1)
+	mu.Lock()
+	running[name]++
+	mu.Unlock()
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Error("❌ Trabajo en segundo plano falló", "job", name, "panic", err)
+			}
+			mu.Lock()
+			if running[name]--; running[name] == 0 {
+				delete(running, name)
+			}
+			mu.Unlock()
+			wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// BeginShutdown marca el inicio del apagado (las sondas de disponibilidad dejan de responder ok)
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown indica si el servidor se está apagando
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Wait espera a que terminen todos los trabajos registrados o a que venza el contexto
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending devuelve los trabajos que siguen en ejecución (nombre y número de instancias)
+func Pending() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(running))
+	for name, n := range running {
+		if n > 1 {
+			name = fmt.Sprintf("%s x%d", name, n)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,166 @@
+// Package logging configura el logger estructurado (log/slog) del servidor: salida JSON,
+// nivel modificable en caliente, request ID en cada línea y redacción de secretos.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/tracing"
+)
+
+type ctxKey struct{}
+
+var (
+	level = new(slog.LevelVar)
+
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// Setup instala el logger por defecto según la configuración. Lo que aún se escriba con el
+// paquete log (librerías) pasa también por él: el nivel se deduce del prefijo (❌ error, ⚠️ aviso, 🔍 debug).
+// El código del servidor usa Printf con el contexto de la petición o slog con nivel explícito.
+func Setup(cfg config.Config) {
+	if err := SetLevel(cfg.LogLevel); err != nil {
+		level.Set(slog.LevelInfo)
+	}
+	AddSecrets(cfg.Secrets()...)
+
+	handler := newHandler(os.Stderr, cfg.LogFormat)
+	slog.SetDefault(slog.New(handler))
+
+	// slog.SetDefault redirige el paquete log con nivel INFO fijo; se sustituye por un puente
+	// que respeta el nivel de cada mensaje
+	log.SetFlags(0)
+	log.SetOutput(&legacyWriter{handler: handler})
+}
+
+func newHandler(out io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	var base slog.Handler
+	if strings.EqualFold(format, "text") {
+		base = slog.NewTextHandler(out, opts)
+	} else {
+		base = slog.NewJSONHandler(out, opts)
+	}
+	return &handler{next: base}
+}
+
+// SetLevel cambia el nivel mínimo de log (debug, info, warn, error) sin reiniciar
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return fmt.Errorf("nivel de log no válido: %q (usa debug, info, warn o error)", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level devuelve el nivel actual en minúsculas
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// AddSecrets registra valores que deben ocultarse en cualquier línea de log
+func AddSecrets(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+}
+
+// WithRequestID guarda el request ID en el contexto
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+// RequestID devuelve el request ID del contexto (vacío si no hay)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Printf registra un mensaje con el formato habitual (prefijos ❌/⚠️/🔍) incluyendo el
+// request ID del contexto
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	slog.Default().Log(ctx, levelFromMessage(msg), msg)
+}
+
+// levelFromMessage deduce el nivel del prefijo con el que se escriben los mensajes en el proyecto
+func levelFromMessage(msg string) slog.Level {
+	switch {
+	case strings.HasPrefix(msg, "❌"):
+		return slog.LevelError
+	case strings.HasPrefix(msg, "⚠"):
+		return slog.LevelWarn
+	case strings.HasPrefix(msg, "🔍"):
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// handler añade el request ID y oculta secretos, tokens, contraseñas y emails
+type handler struct {
+	next slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	if id := RequestID(ctx); id != "" {
+		out.AddAttrs(slog.String("request_id", id))
+	}
+	if id := tracing.TraceID(ctx); id != "" {
+		out.AddAttrs(slog.String("trace_id", id))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &handler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name)}
+}
+
+// legacyWriter convierte cada línea de log.Printf en un registro de slog
+type legacyWriter struct {
+	handler slog.Handler
+}
+
+func (w *legacyWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	l := levelFromMessage(msg)
+	ctx := context.Background()
+	if w.handler.Enabled(ctx, l) {
+		if err := w.handler.Handle(ctx, slog.NewRecord(time.Now(), l, msg, 0)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
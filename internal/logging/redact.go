@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"opo_admin_server/internal/config"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+	// password=..., "token": "..." y similares dentro de mensajes
+	credentialPattern = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key)(?:\s*=\s*["']?|["']\s*:\s*["']?|:\s+))[^\s"',&]+`)
+)
+
+// Atributos cuyo valor nunca se escribe
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "apikey", "api_key", "cookie"}
+
+// Redact oculta en un texto los secretos registrados, tokens, contraseñas y emails
+func Redact(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "****")
+	}
+	mu.RUnlock()
+
+	s = config.RedactString(s)
+	s = credentialPattern.ReplaceAllString(s, "${1}****")
+	return emailPattern.ReplaceAllString(s, "${1}***@${2}")
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, "****")
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]any, len(group))
+		for i, g := range group {
+			redacted[i] = redactAttr(g)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny, slog.KindLogValuer:
+		if err, ok := a.Value.Resolve().Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// ========== HTTP ==========
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Peticiones HTTP atendidas por ruta, método y código de estado",
+	}, []string{"method", "route", "status"})
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duración de las peticiones HTTP por ruta y método",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	httpInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Peticiones HTTP en curso",
+	})
+)
+
+// RequestStarted incrementa las peticiones en curso
+func RequestStarted() {
+	httpInFlight.Inc()
+}
+
+// RequestFinished decrementa las peticiones en curso; se llama con defer para que el gauge
+// baje aunque el handler entre en pánico
+func RequestFinished() {
+	httpInFlight.Dec()
+}
+
+// ObserveRequest registra una petición terminada; route es el patrón de chi (p. ej. /admin/topics/{id})
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// ========== MongoDB ==========
+
+var mongoDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "mongodb_command_duration_seconds",
+	Help:    "Duración de los comandos enviados a MongoDB por comando y resultado",
+	Buckets: prometheus.DefBuckets,
+}, []string{"command", "outcome"})
+
+// MongoMonitor devuelve un monitor de comandos para options.Client().SetMonitor que mide cada operación
+func MongoMonitor() *event.CommandMonitor {
+	// El nombre del comando solo llega en el evento de inicio
+	var commands sync.Map
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			commands.Store(e.RequestID, e.CommandName)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observeMongo(&commands, e.RequestID, e.CommandName, e.Duration, "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observeMongo(&commands, e.RequestID, e.CommandName, e.Duration, "error")
+		},
+	}
+}
+
+func observeMongo(commands *sync.Map, requestID int64, name string, elapsed time.Duration, outcome string) {
+	if started, ok := commands.LoadAndDelete(requestID); ok {
+		name = started.(string)
+	}
+	mongoDuration.WithLabelValues(name, outcome).Observe(elapsed.Seconds())
+}
+
+// ========== Servicios externos ==========
+
+var (
+	externalCalls = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_calls_total",
+		Help: "Llamadas a servicios externos por servicio, operación y resultado",
+	}, []string{"service", "operation", "outcome"})
+	externalDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "external_call_duration_seconds",
+		Help:    "Duración de las llamadas a servicios externos",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "operation"})
+)
+
+// ObserveExternal registra una llamada a un servicio externo (openai, pinecone, smtp, oidc...)
+// iniciada en start; outcome es "success" si err es nil y "error" en caso contrario
+func ObserveExternal(service, operation string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	externalCalls.WithLabelValues(service, operation, outcome).Inc()
+	externalDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+}
+
+// ========== Cola de trabajos ==========
+
+var (
+	jobsFinished = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_finished_total",
+		Help: "Ejecuciones de trabajos en segundo plano por tipo y resultado",
+	}, []string{"type", "status"})
+	jobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_duration_seconds",
+		Help:    "Duración de cada ejecución de un trabajo en segundo plano",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"type"})
+)
+
+// ObserveJob registra una ejecución de un trabajo; status es el estado en que queda
+// (succeeded, failed, canceled o queued si se reintentará)
+func ObserveJob(jobType, status string, elapsed time.Duration) {
+	jobsFinished.WithLabelValues(jobType, status).Inc()
+	jobDuration.WithLabelValues(jobType).Observe(elapsed.Seconds())
+}
+
+// ========== Negocio ==========
+
+var (
+	// TopicsByArea - Temas por área (se recalcula en cada exposición)
+	TopicsByArea = factory.NewGaugeVec(prometheus.GaugeOpts{Name: "opo_topics", Help: "Temas por área"}, []string{"area"})
+	// Questions - Preguntas almacenadas
+	Questions = factory.NewGauge(prometheus.GaugeOpts{Name: "opo_questions", Help: "Preguntas almacenadas"})
+	// Users - Usuarios por estado (enabled/disabled)
+	Users = factory.NewGaugeVec(prometheus.GaugeOpts{Name: "opo_users", Help: "Usuarios por estado"}, []string{"state"})
+	// PendingJobs - Trabajos pendientes por tipo
+	PendingJobs = factory.NewGaugeVec(prometheus.GaugeOpts{Name: "opo_pending_jobs", Help: "Trabajos pendientes por tipo"}, []string{"type"})
+)
@@ -0,0 +1,32 @@
+// Package metrics define las métricas del servidor con el cliente oficial de Prometheus
+// (prometheus/client_golang) y el registro que se expone en /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry agrupa las métricas que se exponen en /metrics. Es un registro propio (no el global
+// de client_golang) con las métricas del runtime de Go y del proceso además de las del servidor
+var Registry = prometheus.NewRegistry()
+
+// factory registra en Registry cada métrica que crea
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler devuelve el handler que escribe las métricas de Registry en el formato de exposición
+// negociado con el scraper
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -2,13 +2,18 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
-	"log"
+	"net"
 	"net/smtp"
 	"strconv"
+	"time"
 
 	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/logging"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // EmailService maneja el envío de emails
@@ -22,10 +27,12 @@ func NewEmailService(cfg config.Config) *EmailService {
 }
 
 // SendDeactivationEmail envía un email de confirmación para darse de baja
-func (s *EmailService) SendDeactivationEmail(email, token string) error {
+func (s *EmailService) SendDeactivationEmail(ctx context.Context, email, token string) error {
 	if s.cfg.SMTPHost == "" || s.cfg.SMTPUser == "" || s.cfg.SMTPPassword == "" {
-		log.Printf("⚠️ SMTP no configurado, simulando envío de email a %s", email)
-		log.Printf("📧 Token de desactivación: %s", token)
+		logging.Printf(ctx, "⚠️ SMTP no configurado, simulando envío de email a %s", email)
+		if s.cfg.IsDevelopment() {
+			logging.Printf(ctx, "📧 Token de desactivación: %s", token)
+		}
 		return nil
 	}
 
@@ -38,6 +45,111 @@ func (s *EmailService) SendDeactivationEmail(email, token string) error {
 		return fmt.Errorf("error renderizando template: %v", err)
 	}
 
+	if err := s.sendHTML(ctx, email, "Confirmación de baja de cuenta", emailHTML); err != nil {
+		return err
+	}
+
+	logging.Printf(ctx, "✅ Email de desactivación enviado a %s", email)
+	return nil
+}
+
+// SendAdminInvitationEmail envía la invitación para que un nuevo administrador establezca su contraseña
+func (s *EmailService) SendAdminInvitationEmail(ctx context.Context, email, name, token string) error {
+	return s.sendAdminPasswordEmail(ctx, email, token, adminPasswordEmail{
+		Subject: "Invitación al panel de administración",
+		Title:   "Bienvenido/a al panel de administración",
+		Intro:   fmt.Sprintf("Hola %s, se ha creado una cuenta de administrador para ti. Para activarla, establece tu contraseña:", name),
+		Button:  "Establecer contraseña",
+		Expiry:  "Este enlace expirará en 72 horas.",
+	})
+}
+
+// SendAdminPasswordResetEmail envía el enlace para restablecer la contraseña de un administrador
+func (s *EmailService) SendAdminPasswordResetEmail(ctx context.Context, email, token string) error {
+	return s.sendAdminPasswordEmail(ctx, email, token, adminPasswordEmail{
+		Subject: "Restablecimiento de contraseña",
+		Title:   "Restablece tu contraseña",
+		Intro:   "Es necesario que establezcas una nueva contraseña para tu cuenta de administrador:",
+		Button:  "Restablecer contraseña",
+		Expiry:  "Este enlace expirará en 24 horas y solo puede usarse una vez.",
+	})
+}
+
+// SendAdminForgotPasswordEmail envía el enlace solicitado desde "olvidé mi contraseña"
+func (s *EmailService) SendAdminForgotPasswordEmail(ctx context.Context, email, token string) error {
+	return s.sendAdminPasswordEmail(ctx, email, token, adminPasswordEmail{
+		Subject: "Recuperación de contraseña",
+		Title:   "Recupera tu contraseña",
+		Intro:   "Hemos recibido una solicitud para restablecer la contraseña de tu cuenta de administrador. Si no has sido tú, ignora este mensaje: tu contraseña actual sigue siendo válida.",
+		Button:  "Elegir nueva contraseña",
+		Expiry:  "Este enlace expirará en 1 hora y solo puede usarse una vez.",
+	})
+}
+
+// adminPasswordEmail contiene los textos de los emails de contraseña de administradores
+type adminPasswordEmail struct {
+	Subject string
+	Title   string
+	Intro   string
+	Button  string
+	Expiry  string
+	URL     string
+}
+
+// sendAdminPasswordEmail construye el enlace al formulario de contraseña y envía el email
+func (s *EmailService) sendAdminPasswordEmail(ctx context.Context, email, token string, content adminPasswordEmail) error {
+	if s.cfg.SMTPHost == "" || s.cfg.SMTPUser == "" || s.cfg.SMTPPassword == "" {
+		logging.Printf(ctx, "⚠️ SMTP no configurado, simulando envío de email a %s", email)
+		if s.cfg.IsDevelopment() {
+			logging.Printf(ctx, "📧 Token de contraseña: %s", token)
+		}
+		return nil
+	}
+
+	content.URL = fmt.Sprintf("%s%s/auth/password-setup?token=%s", s.cfg.AppBaseURL, s.cfg.APIBasePath, token)
+
+	emailHTML, err := renderAdminPasswordEmailTemplate(content)
+	if err != nil {
+		return fmt.Errorf("error renderizando template: %v", err)
+	}
+
+	if err := s.sendHTML(ctx, email, content.Subject, emailHTML); err != nil {
+		return err
+	}
+
+	logging.Printf(ctx, "✅ Email '%s' enviado a %s", content.Subject, email)
+	return nil
+}
+
+// Configured indica si hay servidor SMTP configurado (si no, los envíos se simulan)
+func (s *EmailService) Configured() bool {
+	return s.cfg.SMTPHost != "" && s.cfg.SMTPUser != "" && s.cfg.SMTPPassword != ""
+}
+
+// Ping comprueba que el servidor SMTP acepta conexiones (saludo 220) sin enviar nada
+func (s *EmailService) Ping(ctx context.Context) (err error) {
+	ctx, end := startExternalCall(ctx, "smtp", "ping", attribute.String("server.address", s.cfg.SMTPHost))
+	defer func() { end(err) }()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.cfg.SMTPHost, s.cfg.SMTPPort))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Quit()
+}
+
+// sendHTML envía un email HTML mediante SMTP
+func (s *EmailService) sendHTML(ctx context.Context, email, subject, body string) error {
 	// Configurar mensaje
 	from := s.cfg.SMTPFrom
 	if from == "" {
@@ -45,8 +157,6 @@ func (s *EmailService) SendDeactivationEmail(email, token string) error {
 	}
 
 	to := []string{email}
-	subject := "Confirmación de baja de cuenta"
-	body := emailHTML
 
 	// Construir mensaje MIME
 	message := fmt.Sprintf("From: %s\r\n", from)
@@ -66,12 +176,13 @@ func (s *EmailService) SendDeactivationEmail(email, token string) error {
 	addr := fmt.Sprintf("%s:%d", s.cfg.SMTPHost, port)
 	auth := smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, s.cfg.SMTPHost)
 
+	_, end := startExternalCall(ctx, "smtp", "send", attribute.String("server.address", s.cfg.SMTPHost), attribute.String("email.subject", subject))
 	err = smtp.SendMail(addr, auth, from, to, []byte(message))
+	end(err)
 	if err != nil {
 		return fmt.Errorf("error enviando email: %v", err)
 	}
 
-	log.Printf("✅ Email de desactivación enviado a %s", email)
 	return nil
 }
 
@@ -193,3 +304,198 @@ func (s *EmailService) renderDeactivationEmailTemplate(confirmURL string) (strin
 	return buf.String(), nil
 }
 
+// renderAdminPasswordEmailTemplate renderiza el template HTML de invitación/restablecimiento
+func renderAdminPasswordEmailTemplate(content adminPasswordEmail) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        body {
+            font-family: Arial, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+            background-color: #f4f4f4;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            padding: 30px;
+            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .header h1 {
+            color: #2c3e50;
+            margin: 0;
+        }
+        .button-container {
+            text-align: center;
+            margin: 30px 0;
+        }
+        .button {
+            display: inline-block;
+            padding: 12px 30px;
+            background-color: #667eea;
+            color: #ffffff;
+            text-decoration: none;
+            border-radius: 5px;
+            font-weight: bold;
+        }
+        .footer {
+            margin-top: 30px;
+            padding-top: 20px;
+            border-top: 1px solid #eee;
+            font-size: 12px;
+            color: #777;
+            text-align: center;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.Title}}</h1>
+        </div>
+        <div class="content">
+            <p>{{.Intro}}</p>
+
+            <div class="button-container">
+                <a href="{{.URL}}" class="button">{{.Button}}</a>
+            </div>
+
+            <p>Si el botón no funciona, copia y pega el siguiente enlace en tu navegador:</p>
+            <p style="word-break: break-all; color: #3498db;">{{.URL}}</p>
+
+            <p><strong>{{.Expiry}}</strong></p>
+        </div>
+        <div class="footer">
+            <p>Este es un email automático, por favor no respondas a este mensaje.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("admin-password-email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, content); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// SendAdminLockoutEmail avisa al administrador de que su cuenta se ha bloqueado por intentos fallidos
+func (s *EmailService) SendAdminLockoutEmail(ctx context.Context, email, ip string, attempts int, until time.Time) error {
+	if s.cfg.SMTPHost == "" || s.cfg.SMTPUser == "" || s.cfg.SMTPPassword == "" {
+		logging.Printf(ctx, "⚠️ SMTP no configurado, simulando envío de aviso de bloqueo a %s", email)
+		return nil
+	}
+
+	emailHTML, err := renderAdminNoticeEmailTemplate(adminNoticeEmail{
+		Title: "Cuenta bloqueada temporalmente",
+		Paragraphs: []string{
+			fmt.Sprintf("Se han producido %d intentos fallidos de inicio de sesión en tu cuenta de administrador desde la IP %s.", attempts, ip),
+			fmt.Sprintf("Por seguridad, el acceso queda bloqueado hasta las %s (UTC).", until.UTC().Format("15:04 del 02/01/2006")),
+			"Si no has sido tú, te recomendamos cambiar tu contraseña y avisar a un superadministrador.",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error renderizando template: %v", err)
+	}
+
+	if err := s.sendHTML(ctx, email, "Cuenta bloqueada temporalmente", emailHTML); err != nil {
+		return err
+	}
+
+	logging.Printf(ctx, "✅ Aviso de bloqueo enviado a %s", email)
+	return nil
+}
+
+// adminNoticeEmail contiene los textos de un email informativo (sin enlace) para administradores
+type adminNoticeEmail struct {
+	Title      string
+	Paragraphs []string
+}
+
+// renderAdminNoticeEmailTemplate renderiza el template HTML de un email informativo
+func renderAdminNoticeEmailTemplate(content adminNoticeEmail) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        body {
+            font-family: Arial, sans-serif;
+            line-height: 1.6;
+            color: #333;
+            max-width: 600px;
+            margin: 0 auto;
+            padding: 20px;
+            background-color: #f4f4f4;
+        }
+        .container {
+            background-color: #ffffff;
+            border-radius: 8px;
+            padding: 30px;
+            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
+        }
+        .header {
+            text-align: center;
+            margin-bottom: 30px;
+        }
+        .header h1 {
+            color: #2c3e50;
+            margin: 0;
+        }
+        .footer {
+            margin-top: 30px;
+            padding-top: 20px;
+            border-top: 1px solid #eee;
+            font-size: 12px;
+            color: #777;
+            text-align: center;
+        }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>{{.Title}}</h1>
+        </div>
+        <div class="content">
+            {{range .Paragraphs}}<p>{{.}}</p>
+            {{end}}
+        </div>
+        <div class="footer">
+            <p>Este es un email automático, por favor no respondas a este mensaje.</p>
+        </div>
+    </div>
+</body>
+</html>`
+
+	t, err := template.New("admin-notice-email").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, content); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
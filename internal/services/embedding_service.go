@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/tracing"
 
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ChunkText divide el texto en chunks según la estrategia configurada
@@ -189,54 +192,79 @@ func chunkBySections(text string, chunkSize, overlap int) []string {
 	return chunks
 }
 
+// openAIEmbeddingBatch es el número de chunks que se envían en cada llamada a OpenAI
+const openAIEmbeddingBatch = 64
+
 // GenerateEmbeddings genera embeddings para los chunks de texto
-func GenerateEmbeddings(chunks []string, config domain.EmbeddingConfig) ([][]float32, error) {
+func GenerateEmbeddings(ctx context.Context, chunks []string, config domain.EmbeddingConfig) ([][]float32, error) {
+	return GenerateEmbeddingsWithProgress(ctx, chunks, config, nil)
+}
+
+// GenerateEmbeddingsWithProgress genera los embeddings e informa de los chunks completados
+// tras cada llamada; si progress devuelve un error se detiene (p. ej. trabajo cancelado)
+func GenerateEmbeddingsWithProgress(ctx context.Context, chunks []string, config domain.EmbeddingConfig, progress func(done int) error) ([][]float32, error) {
 	if len(chunks) == 0 {
 		return nil, fmt.Errorf("no hay chunks para generar embeddings")
 	}
 
 	switch config.EmbeddingModel {
 	case "openai":
-		return generateOpenAIEmbeddings(chunks, config.OpenAIAPIKey)
+		return generateOpenAIEmbeddings(ctx, chunks, config.OpenAIAPIKey, progress)
 	case "huggingface":
 		return generateHuggingFaceEmbeddings(chunks, config.HuggingFaceAPIKey)
 	default:
 		// Por defecto usar OpenAI
-		return generateOpenAIEmbeddings(chunks, config.OpenAIAPIKey)
+		return generateOpenAIEmbeddings(ctx, chunks, config.OpenAIAPIKey, progress)
 	}
 }
 
-// generateOpenAIEmbeddings genera embeddings usando OpenAI
-func generateOpenAIEmbeddings(chunks []string, apiKey string) ([][]float32, error) {
+// generateOpenAIEmbeddings genera embeddings usando OpenAI, en lotes de openAIEmbeddingBatch chunks
+func generateOpenAIEmbeddings(ctx context.Context, chunks []string, apiKey string, progress func(done int) error) ([][]float32, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key de OpenAI no configurada")
 	}
 
-	client := openai.NewClient(apiKey)
-	var embeddings [][]float32
-	ctx := context.Background()
+	// El transporte instrumentado envía traceparent a OpenAI
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.HTTPClient = &http.Client{Transport: tracing.Transport(nil)}
+	client := openai.NewClientWithConfig(clientConfig)
+	embeddings := make([][]float32, 0, len(chunks))
 
-	for i, chunk := range chunks {
-		// Usar text-embedding-ada-002 como modelo por defecto (más común y económico)
-		// O usar text-embedding-3-small/3-large para modelos más nuevos
+	for first := 0; first < len(chunks); first += openAIEmbeddingBatch {
+		batch := chunks[first:min(first+openAIEmbeddingBatch, len(chunks))]
+
+		// text-embedding-3-small: más nuevo y económico que text-embedding-ada-002
 		req := openai.EmbeddingRequest{
-			Input: []string{chunk},
-			Model: openai.SmallEmbedding3, // text-embedding-3-small
+			Input: batch,
+			Model: openai.SmallEmbedding3,
 		}
 
-		resp, err := client.CreateEmbeddings(ctx, req)
+		callCtx, end := startExternalCall(ctx, "openai", "embeddings", attribute.Int("chunk.first", first), attribute.Int("chunk.count", len(batch)))
+		resp, err := client.CreateEmbeddings(callCtx, req)
+		end(err)
 		if err != nil {
-			return nil, fmt.Errorf("error al generar embedding para chunk %d: %w", i, err)
+			return nil, fmt.Errorf("error al generar embeddings para los chunks %d-%d: %w", first, first+len(batch)-1, err)
 		}
 
-		if len(resp.Data) == 0 {
-			return nil, fmt.Errorf("no se recibió embedding para chunk %d", i)
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("se esperaban %d embeddings y se recibieron %d (chunks %d-%d)", len(batch), len(resp.Data), first, first+len(batch)-1)
 		}
 
-		// Los embeddings de OpenAI vienen como []float32 directamente
-		// El SDK de go-openai devuelve []float32 en Embedding
-		embedding := resp.Data[0].Embedding
-		embeddings = append(embeddings, embedding)
+		// La respuesta no garantiza el orden: cada embedding indica la posición de su chunk
+		batchEmbeddings := make([][]float32, len(batch))
+		for _, data := range resp.Data {
+			if data.Index < 0 || data.Index >= len(batch) {
+				return nil, fmt.Errorf("índice de embedding fuera de rango: %d", data.Index)
+			}
+			batchEmbeddings[data.Index] = data.Embedding
+		}
+		embeddings = append(embeddings, batchEmbeddings...)
+
+		if progress != nil {
+			if err := progress(len(embeddings)); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return embeddings, nil
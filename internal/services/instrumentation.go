@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startExternalCall abre el span de una llamada a un servicio externo. La función devuelta
+// cierra el span y registra la métrica de la llamada con su resultado.
+func startExternalCall(ctx context.Context, service, operation string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	start := time.Now()
+	attrs = append([]attribute.KeyValue{attribute.String("peer.service", service)}, attrs...)
+	ctx, span := tracing.Start(ctx, service+"."+operation, trace.SpanKindClient, attrs...)
+	return ctx, func(err error) {
+		tracing.End(span, err)
+		metrics.ObserveExternal(service, operation, start, err)
+	}
+}
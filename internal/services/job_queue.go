@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/lifecycle"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JobsCollection es la colección donde se guardan los trabajos en segundo plano
+const JobsCollection = "jobs"
+
+const (
+	// jobLease es el tiempo que una instancia se reserva un trabajo; se renueva con cada avance.
+	// Si vence (instancia caída), otra instancia lo retoma
+	jobLease = 2 * time.Minute
+	// maxRetryBackoff limita la espera entre reintentos
+	maxRetryBackoff = 30 * time.Minute
+)
+
+// ErrJobCanceled indica que un administrador ha cancelado el trabajo
+var ErrJobCanceled = errors.New("trabajo cancelado")
+
+// errLeaseLost indica que otra instancia ha retomado el trabajo (esta dejó de renovar la reserva)
+var errLeaseLost = errors.New("el trabajo lo ha retomado otra instancia")
+
+// Estado de la cola en esta instancia, compartido entre los handlers HTTP y los workers
+var (
+	jobWake    = make(chan struct{}, 1)
+	jobsMu     sync.Mutex
+	jobKeys    = map[string]domain.EmbeddingConfig{} // API keys de cada petición (nunca se guardan en MongoDB)
+	jobCancels = map[string]context.CancelCauseFunc{}
+	instanceID = uuid.NewString()
+)
+
+// permanentError marca los errores que no se resuelven reintentando
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// isPermanent indica si un error no merece reintento: errores marcados como permanentes y
+// errores 4xx de OpenAI y Pinecone (salvo límite de peticiones y timeout)
+func isPermanent(err error) bool {
+	var p permanentError
+	if errors.As(err, &p) {
+		return true
+	}
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var pineconeErr *PineconeStatusError
+	if errors.As(err, &apiErr) {
+		status = apiErr.HTTPStatusCode
+	} else if errors.As(err, &reqErr) {
+		status = reqErr.HTTPStatusCode
+	} else if errors.As(err, &pineconeErr) {
+		status = pineconeErr.StatusCode
+	}
+	return status >= 400 && status < 500 && status != 429 && status != 408
+}
+
+// NotifyJobQueued guarda en memoria las API keys del trabajo y despierta a un worker de esta instancia
+func NotifyJobQueued(jobID string, keys domain.EmbeddingConfig) {
+	if keys.OpenAIAPIKey != "" || keys.HuggingFaceAPIKey != "" {
+		jobsMu.Lock()
+		jobKeys[jobID] = domain.EmbeddingConfig{OpenAIAPIKey: keys.OpenAIAPIKey, HuggingFaceAPIKey: keys.HuggingFaceAPIKey}
+		jobsMu.Unlock()
+	}
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// CancelLocalJob interrumpe el trabajo si se está ejecutando en esta instancia. Los que se
+// ejecutan en otra instancia se detienen en su siguiente avance (cancelRequested)
+func CancelLocalJob(jobID string) bool {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	cancel, ok := jobCancels[jobID]
+	if ok {
+		cancel(ErrJobCanceled)
+	}
+	return ok
+}
+
+// JobQueue ejecuta los trabajos pendientes de la colección jobs con un pool de workers.
+// La reserva de cada trabajo es atómica, por lo que varias instancias pueden compartir la cola
+type JobQueue struct {
+	cfg config.Config
+	// jobsCtx es el contexto de los trabajos en curso. No depende de la señal de parada: solo
+	// se cancela con Abort, al agotarse el límite del apagado
+	jobsCtx   context.Context
+	abortJobs context.CancelFunc
+}
+
+// NewJobQueue crea la cola de trabajos
+func NewJobQueue(cfg config.Config) *JobQueue {
+	jobsCtx, abortJobs := context.WithCancel(context.Background())
+	return &JobQueue{cfg: cfg, jobsCtx: jobsCtx, abortJobs: abortJobs}
+}
+
+// Start lanza JOB_WORKERS workers hasta que se cancele el contexto. Al cancelarse dejan de
+// reservar trabajos nuevos y terminan cuando acaban los que tienen en curso
+func (q *JobQueue) Start(ctx context.Context) {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(q.cfg.DBURL).SetMonitor(tracing.MongoMonitor(metrics.MongoMonitor())))
+	if err != nil {
+		logging.Printf(ctx, "❌ Cola de trabajos - Error conectando a MongoDB: %v", err)
+		return
+	}
+	col := client.Database(q.cfg.DBName).Collection(JobsCollection)
+
+	logging.Printf(ctx, "🧵 Cola de trabajos iniciada (%d workers, intervalo: %s)", q.cfg.JobWorkers, q.cfg.JobPollInterval)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= q.cfg.JobWorkers; i++ {
+		wg.Add(1)
+		lifecycle.Go(fmt.Sprintf("job-worker-%d", i), func() {
+			defer wg.Done()
+			q.work(ctx, col)
+		})
+	}
+
+	wg.Wait()
+	client.Disconnect(context.Background())
+	logging.Printf(ctx, "🧵 Cola de trabajos detenida")
+}
+
+// Abort interrumpe los trabajos en curso, que vuelven a la cola para que los retome otra
+// instancia o el siguiente arranque. Se llama al agotarse el límite del apagado
+func (q *JobQueue) Abort() {
+	q.abortJobs()
+}
+
+func (q *JobQueue) work(ctx context.Context, col *mongo.Collection) {
+	ticker := time.NewTicker(q.cfg.JobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Procesar trabajos mientras queden pendientes
+		for ctx.Err() == nil {
+			ran, err := q.runNext(ctx, col)
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.Printf(ctx, "❌ Cola de trabajos - Error: %v", err)
+				}
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-jobWake:
+		}
+	}
+}
+
+// runNext reserva el trabajo pendiente más antiguo y lo ejecuta. Devuelve false si no había ninguno
+func (q *JobQueue) runNext(ctx context.Context, col *mongo.Collection) (bool, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var job domain.Job
+	err := col.FindOneAndUpdate(claimCtx, bson.M{
+		"$or": []bson.M{
+			{"status": domain.JobStatusQueued, "runAfter": bson.M{"$lte": now}},
+			// Reserva vencida: la instancia que lo ejecutaba se ha caído
+			{"status": domain.JobStatusRunning, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"status":      domain.JobStatusRunning,
+			"lockedBy":    instanceID,
+			"lockedUntil": now.Add(jobLease),
+			"startedAt":   now,
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}, options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After)).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	q.run(col, &job)
+	return true, nil
+}
+
+// run ejecuta un trabajo reservado y guarda su resultado
+func (q *JobQueue) run(col *mongo.Collection, job *domain.Job) {
+	// Los logs del trabajo llevan el request ID de la petición que lo encoló
+	jobCtx, cancel := context.WithCancelCause(logging.WithRequestID(q.jobsCtx, job.RequestID))
+	defer cancel(nil)
+
+	jobsMu.Lock()
+	jobCancels[job.ID] = cancel
+	jobsMu.Unlock()
+	defer func() {
+		jobsMu.Lock()
+		delete(jobCancels, job.ID)
+		jobsMu.Unlock()
+	}()
+
+	jobCtx, span := tracing.Start(jobCtx, "job."+job.Type, trace.SpanKindInternal,
+		attribute.String("job.id", job.ID), attribute.Int("job.attempt", job.Attempts), attribute.String("document.id", job.DocumentID))
+	start := time.Now()
+	logging.Printf(jobCtx, "🧵 Trabajo %s (%s) - intento %d de %d", job.ID, job.Type, job.Attempts, job.MaxAttempts)
+
+	var result *domain.ProcessVectorResponse
+	var err error
+	switch {
+	case job.CancelRequested:
+		// Se pidió cancelarlo mientras lo ejecutaba una instancia que se ha caído
+		err = ErrJobCanceled
+	case job.Attempts > job.MaxAttempts:
+		err = permanent(fmt.Errorf("superado el número máximo de intentos (%d)", job.MaxAttempts))
+	case job.Type == domain.JobTypeVectorize:
+		result, err = q.vectorize(jobCtx, col.Database(), job)
+	default:
+		err = permanent(fmt.Errorf("tipo de trabajo desconocido: %s", job.Type))
+	}
+
+	if err != nil && errors.Is(context.Cause(jobCtx), ErrJobCanceled) {
+		err = ErrJobCanceled
+	}
+	status := q.finish(jobCtx, col, job, result, err, q.jobsCtx.Err() != nil)
+
+	span.SetAttributes(attribute.String("job.status", status))
+	tracing.End(span, err)
+	if status != "" {
+		metrics.ObserveJob(job.Type, status, time.Since(start))
+	}
+}
+
+// finish guarda el estado final del trabajo (o lo devuelve a la cola para reintentarlo) y
+// devuelve el estado en que queda
+func (q *JobQueue) finish(ctx context.Context, col *mongo.Collection, job *domain.Job, result *domain.ProcessVectorResponse, err error, shuttingDown bool) string {
+	// Aunque se esté apagando el servidor o se haya cancelado el trabajo, debe quedar en un estado coherente
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if errors.Is(err, errLeaseLost) {
+		logging.Printf(ctx, "⚠️ Trabajo %s - %v", job.ID, err)
+		return ""
+	}
+
+	now := time.Now()
+	set := bson.M{"updatedAt": now}
+	update := bson.M{"$set": set, "$unset": bson.M{"lockedBy": "", "lockedUntil": ""}}
+	var status string
+	switch {
+	case err == nil:
+		status = domain.JobStatusSucceeded
+		set["result"] = result
+		set["finishedAt"] = now
+		logging.Printf(ctx, "✅ Trabajo %s completado", job.ID)
+	case errors.Is(err, ErrJobCanceled):
+		status = domain.JobStatusCanceled
+		set["lastError"] = err.Error()
+		set["finishedAt"] = now
+		logging.Printf(ctx, "🛑 Trabajo %s cancelado", job.ID)
+	case shuttingDown:
+		// Interrumpido por el apagado: no cuenta como intento
+		status = domain.JobStatusQueued
+		set["runAfter"] = now
+		set["lastError"] = "interrumpido por el apagado del servidor"
+		update["$inc"] = bson.M{"attempts": -1}
+		logging.Printf(ctx, "⏸️ Trabajo %s interrumpido por el apagado, vuelve a la cola", job.ID)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		status = domain.JobStatusFailed
+		set["lastError"] = err.Error()
+		set["finishedAt"] = now
+		logging.Printf(ctx, "❌ Trabajo %s fallido: %v", job.ID, err)
+	default:
+		status = domain.JobStatusQueued
+		backoff := retryBackoff(q.cfg.JobRetryBackoff, job.Attempts)
+		set["runAfter"] = now.Add(backoff)
+		set["lastError"] = err.Error()
+		logging.Printf(ctx, "⚠️ Trabajo %s - intento %d fallido, se reintenta en %s: %v", job.ID, job.Attempts, backoff, err)
+	}
+	set["status"] = status
+
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": job.ID, "lockedBy": instanceID}, update); err != nil {
+		logging.Printf(ctx, "❌ Trabajo %s - Error guardando el estado %s: %v", job.ID, status, err)
+	}
+
+	if status != domain.JobStatusQueued {
+		jobsMu.Lock()
+		delete(jobKeys, job.ID)
+		jobsMu.Unlock()
+		if job.Type == domain.JobTypeVectorize {
+			if status != domain.JobStatusSucceeded {
+				markDocumentNotProcessed(ctx, col.Database(), job, status, err)
+			}
+			q.removeVectorsOfDeletedDocument(ctx, col.Database(), job)
+		}
+	}
+	return status
+}
+
+// retryBackoff duplica la espera base en cada intento, hasta maxRetryBackoff
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// reportProgress guarda la fase y el avance del trabajo, renueva la reserva y comprueba si se
+// ha pedido cancelarlo desde otra instancia
+func reportProgress(ctx context.Context, col *mongo.Collection, jobID string, progress domain.JobProgress) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var current struct {
+		CancelRequested bool `bson:"cancelRequested"`
+	}
+	err := col.FindOneAndUpdate(dbCtx, bson.M{
+		"_id":      jobID,
+		"status":   domain.JobStatusRunning,
+		"lockedBy": instanceID,
+	}, bson.M{
+		"$set": bson.M{
+			"progress":    progress,
+			"lockedUntil": now.Add(jobLease),
+			"updatedAt":   now,
+		},
+	}, options.FindOneAndUpdate().SetProjection(bson.M{"cancelRequested": 1})).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return errLeaseLost
+	}
+	if err != nil {
+		return err
+	}
+	if current.CancelRequested {
+		return ErrJobCanceled
+	}
+	return nil
+}
+
+// jobAPIKeys devuelve las API keys del trabajo: las de la petición si esta instancia las
+// conserva y, si no, las del servidor
+func (q *JobQueue) jobAPIKeys(job *domain.Job) domain.EmbeddingConfig {
+	config := job.EmbeddingConfig
+	jobsMu.Lock()
+	keys := jobKeys[job.ID]
+	jobsMu.Unlock()
+
+	config.OpenAIAPIKey = keys.OpenAIAPIKey
+	if config.OpenAIAPIKey == "" {
+		config.OpenAIAPIKey = q.cfg.OpenAIAPIKey
+	}
+	config.HuggingFaceAPIKey = keys.HuggingFaceAPIKey
+	return config
+}
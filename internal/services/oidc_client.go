@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/tracing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Tiempo que se reutilizan el documento de discovery y las claves públicas del proveedor
+const oidcCacheTTL = time.Hour
+
+// OIDCProviderMetadata es la parte del documento de discovery que se utiliza
+type OIDCProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIdentity son los datos del usuario extraídos de un ID token válido
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	HostedDomain  string
+	Name          string
+}
+
+// OIDCClient implementa el flujo authorization code con PKCE contra el proveedor configurado
+type OIDCClient struct {
+	cfg        config.Config
+	httpClient *http.Client
+}
+
+type oidcCache struct {
+	mu        sync.Mutex
+	metadata  *OIDCProviderMetadata
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	keysAt    time.Time
+}
+
+// La caché es compartida entre peticiones (el cliente se crea en cada handler)
+var sharedOIDCCache = &oidcCache{}
+
+// NewOIDCClient crea un nuevo cliente OIDC
+func NewOIDCClient(cfg config.Config) *OIDCClient {
+	return &OIDCClient{cfg: cfg, httpClient: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}}
+}
+
+// Enabled indica si el SSO está configurado
+func (c *OIDCClient) Enabled() bool {
+	return c.cfg.OIDCIssuerURL != "" && c.cfg.OIDCClientID != ""
+}
+
+// Discover obtiene (y cachea) el documento .well-known/openid-configuration
+func (c *OIDCClient) Discover(ctx context.Context) (*OIDCProviderMetadata, error) {
+	sharedOIDCCache.mu.Lock()
+	defer sharedOIDCCache.mu.Unlock()
+
+	if sharedOIDCCache.metadata != nil && sharedOIDCCache.metadata.Issuer == c.cfg.OIDCIssuerURL && time.Since(sharedOIDCCache.fetchedAt) < oidcCacheTTL {
+		return sharedOIDCCache.metadata, nil
+	}
+
+	var metadata OIDCProviderMetadata
+	if err := c.getJSON(ctx, "discovery", c.cfg.OIDCIssuerURL+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("error en discovery OIDC: %v", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != c.cfg.OIDCIssuerURL {
+		return nil, fmt.Errorf("el issuer del discovery (%s) no coincide con el configurado", metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("documento de discovery OIDC incompleto")
+	}
+
+	sharedOIDCCache.metadata = &metadata
+	sharedOIDCCache.keys = nil
+	sharedOIDCCache.fetchedAt = time.Now()
+	return &metadata, nil
+}
+
+// AuthCodeURL construye la URL de autorización con state, nonce y el challenge PKCE (S256)
+func (c *OIDCClient) AuthCodeURL(metadata *OIDCProviderMetadata, state, nonce, codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.cfg.OIDCClientID)
+	params.Set("redirect_uri", c.cfg.OIDCRedirectURL)
+	params.Set("scope", strings.Join(c.cfg.OIDCScopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	params.Set("code_challenge_method", "S256")
+	if len(c.cfg.OIDCAllowedDomains) == 1 {
+		// Sugerencia para Google Workspace; el dominio se valida igualmente en el ID token
+		params.Set("hd", c.cfg.OIDCAllowedDomains[0])
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange canjea el código de autorización y devuelve el ID token
+func (c *OIDCClient) Exchange(ctx context.Context, metadata *OIDCProviderMetadata, code, codeVerifier string) (_ string, err error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.OIDCRedirectURL)
+	form.Set("client_id", c.cfg.OIDCClientID)
+	form.Set("code_verifier", codeVerifier)
+	if c.cfg.OIDCClientSecret != "" {
+		form.Set("client_secret", c.cfg.OIDCClientSecret)
+	}
+
+	ctx, end := startExternalCall(ctx, "oidc", "token")
+	defer func() { end(err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error llamando al token endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint respondió %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", fmt.Errorf("respuesta del token endpoint inválida: %v", err)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("el proveedor no devolvió id_token")
+	}
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken valida firma (JWKS), issuer, audiencia, caducidad y nonce del ID token
+func (c *OIDCClient) VerifyIDToken(ctx context.Context, metadata *OIDCProviderMetadata, rawIDToken, nonce string) (*OIDCIdentity, error) {
+	token, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.publicKey(ctx, metadata, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(c.cfg.OIDCClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID token inválido: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("ID token inválido")
+	}
+	if claimNonce, _ := claims["nonce"].(string); nonce == "" || claimNonce != nonce {
+		return nil, errors.New("nonce del ID token no coincide")
+	}
+	// Con varias audiencias, azp debe ser nuestro client_id
+	if aud, isList := claims["aud"].([]interface{}); isList && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != c.cfg.OIDCClientID {
+			return nil, errors.New("azp del ID token no coincide")
+		}
+	}
+
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.HostedDomain, _ = claims["hd"].(string)
+	identity.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+	return identity, nil
+}
+
+// EmailDomainAllowed comprueba el dominio del email (y el claim hd si existe) contra los permitidos
+func (c *OIDCClient) EmailDomainAllowed(identity *OIDCIdentity) bool {
+	if len(c.cfg.OIDCAllowedDomains) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(identity.Email, "@")
+	for _, allowed := range c.cfg.OIDCAllowedDomains {
+		if strings.EqualFold(domain, allowed) && (identity.HostedDomain == "" || strings.EqualFold(identity.HostedDomain, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicKey devuelve la clave RSA del kid indicado, recargando el JWKS si no se conoce
+func (c *OIDCClient) publicKey(ctx context.Context, metadata *OIDCProviderMetadata, kid string) (*rsa.PublicKey, error) {
+	sharedOIDCCache.mu.Lock()
+	defer sharedOIDCCache.mu.Unlock()
+
+	if key, ok := sharedOIDCCache.keys[kid]; ok && time.Since(sharedOIDCCache.keysAt) < oidcCacheTTL {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.getJSON(ctx, "jwks", metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("error obteniendo JWKS: %v", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	sharedOIDCCache.keys = keys
+	sharedOIDCCache.keysAt = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("clave %q no encontrada en el JWKS", kid)
+	}
+	return key, nil
+}
+
+func (c *OIDCClient) getJSON(ctx context.Context, operation, endpoint string, v interface{}) (err error) {
+	ctx, end := startExternalCall(ctx, "oidc", operation)
+	defer func() { end(err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s respondió %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"opo_admin_server/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash de contraseñas admitidos
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+// Parámetros de argon2id (recomendación OWASP: 19 MiB, 2 iteraciones, 1 hilo)
+const (
+	argon2Memory  = 19 * 1024
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// PasswordPolicy aplica las reglas de contraseñas de administradores y gestiona su hash
+type PasswordPolicy struct {
+	cfg    config.Config
+	common map[string]struct{}
+}
+
+// NewPasswordPolicy crea la política a partir de la configuración
+func NewPasswordPolicy(cfg config.Config) *PasswordPolicy {
+	return &PasswordPolicy{cfg: cfg, common: loadBreachList(cfg.PasswordBreachListFile)}
+}
+
+// Description devuelve los requisitos en texto para mostrarlos en formularios
+func (p *PasswordPolicy) Description() string {
+	desc := fmt.Sprintf("Mínimo %d caracteres", p.cfg.PasswordMinLength)
+	if p.cfg.PasswordMinClasses > 1 {
+		desc += fmt.Sprintf(" y al menos %d tipos de carácter (minúsculas, mayúsculas, números, símbolos)", p.cfg.PasswordMinClasses)
+	}
+	return desc + "."
+}
+
+// Validate comprueba longitud, tipos de carácter, lista de contraseñas comunes y que no
+// contenga el email del administrador
+func (p *PasswordPolicy) Validate(password, email string) error {
+	if utf8.RuneCountInString(password) < p.cfg.PasswordMinLength {
+		return fmt.Errorf("la contraseña debe tener al menos %d caracteres", p.cfg.PasswordMinLength)
+	}
+	if len(password) > 72 && p.cfg.PasswordHashAlgorithm == PasswordAlgorithmBcrypt {
+		return errors.New("la contraseña no puede superar los 72 bytes")
+	}
+
+	if classes := passwordClasses(password); classes < p.cfg.PasswordMinClasses {
+		return fmt.Errorf("la contraseña debe combinar al menos %d tipos de carácter (minúsculas, mayúsculas, números, símbolos)", p.cfg.PasswordMinClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if _, found := p.common[lower]; found {
+		return errors.New("la contraseña es demasiado común; elige otra")
+	}
+	if local, _, ok := strings.Cut(strings.ToLower(email), "@"); ok && len(local) >= 4 && strings.Contains(lower, local) {
+		return errors.New("la contraseña no puede contener tu email")
+	}
+
+	return nil
+}
+
+// ReusedIn indica si la contraseña coincide con alguno de los hashes anteriores
+func (p *PasswordPolicy) ReusedIn(password string, hashes []string) bool {
+	for _, h := range hashes {
+		if h != "" && p.Verify(h, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// History devuelve el historial actualizado tras sustituir currentHash por una nueva contraseña
+func (p *PasswordPolicy) History(currentHash string, history []string) []string {
+	if p.cfg.PasswordHistorySize <= 0 {
+		return []string{}
+	}
+	updated := history
+	if currentHash != "" {
+		updated = append([]string{currentHash}, history...)
+	}
+	if len(updated) > p.cfg.PasswordHistorySize {
+		updated = updated[:p.cfg.PasswordHistorySize]
+	}
+	return updated
+}
+
+// Hash genera el hash con el algoritmo configurado
+func (p *PasswordPolicy) Hash(password string) (string, error) {
+	if p.cfg.PasswordHashAlgorithm == PasswordAlgorithmArgon2id {
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.cfg.PasswordBcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify comprueba la contraseña contra un hash bcrypt o argon2id
+func (p *PasswordPolicy) Verify(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(candidate, key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash indica si el hash se generó con otro algoritmo o parámetros distintos de los configurados
+func (p *PasswordPolicy) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if p.cfg.PasswordHashAlgorithm != PasswordAlgorithmArgon2id {
+			return true
+		}
+		params, _, _, err := parseArgon2Hash(hash)
+		return err != nil || params.memory != argon2Memory || params.time != argon2Time || params.threads != argon2Threads
+	}
+
+	if p.cfg.PasswordHashAlgorithm != PasswordAlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != p.cfg.PasswordBcryptCost
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// parseArgon2Hash decodifica un hash en formato PHC ($argon2id$v=19$m=..,t=..,p=..$salt$key)
+func parseArgon2Hash(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("hash argon2id inválido")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+// passwordClasses cuenta los tipos de carácter presentes (minúsculas, mayúsculas, números, símbolos)
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+var (
+	breachListsMu sync.Mutex
+	breachLists   = map[string]map[string]struct{}{}
+)
+
+// loadBreachList lee (una sola vez por ruta) el fichero de contraseñas comunes, una por línea
+func loadBreachList(path string) map[string]struct{} {
+	if path == "" {
+		return nil
+	}
+
+	breachListsMu.Lock()
+	defer breachListsMu.Unlock()
+
+	if list, ok := breachLists[path]; ok {
+		return list
+	}
+
+	list := map[string]struct{}{}
+	file, err := os.Open(path)
+	if err != nil {
+		slog.Warn("⚠️ No se pudo abrir la lista de contraseñas comunes", "path", path, "error", err)
+		breachLists[path] = list
+		return list
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Warn("⚠️ Error leyendo la lista de contraseñas comunes", "path", path, "error", err)
+	}
+
+	slog.Info("🔐 Lista de contraseñas comunes cargada", "entries", len(list), "path", path)
+	breachLists[path] = list
+	return list
+}
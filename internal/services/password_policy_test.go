@@ -0,0 +1,227 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opo_admin_server/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testPasswordPolicy(t *testing.T, algorithm string, bcryptCost int) *PasswordPolicy {
+	t.Helper()
+	list := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(list, []byte("# comentario\nPassword123!\n\nQwerty-2024\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return NewPasswordPolicy(config.Config{
+		PasswordMinLength:      10,
+		PasswordMinClasses:     3,
+		PasswordBreachListFile: list,
+		PasswordHistorySize:    3,
+		PasswordHashAlgorithm:  algorithm,
+		PasswordBcryptCost:     bcryptCost,
+	})
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	bcryptPolicy := testPasswordPolicy(t, PasswordAlgorithmBcrypt, bcrypt.MinCost)
+	argonPolicy := testPasswordPolicy(t, PasswordAlgorithmArgon2id, bcrypt.MinCost)
+	long := strings.Repeat("Ab1-", 19) // 76 bytes
+
+	tests := []struct {
+		name     string
+		policy   *PasswordPolicy
+		password string
+		email    string
+		wantErr  string
+	}{
+		{"válida", bcryptPolicy, "Tr3s-Clases", "admin@example.com", ""},
+		{"corta", bcryptPolicy, "Ab1-short", "", "al menos 10 caracteres"},
+		{"longitud en caracteres, no en bytes", bcryptPolicy, "Ñandú-Añ1", "", "al menos 10 caracteres"},
+		{"dos tipos de carácter", bcryptPolicy, "solominusculas1", "", "al menos 3 tipos"},
+		{"común (sin distinguir mayúsculas)", bcryptPolicy, "PASSWORD123!", "", "demasiado común"},
+		{"común, otra línea de la lista", bcryptPolicy, "qwerty-2024", "", "demasiado común"},
+		{"contiene el email", bcryptPolicy, "Mi-Admin.Pepe1", "pepe@example.com", "contener tu email"},
+		{"email corto no se comprueba", bcryptPolicy, "Mi-Admin.Ana1", "ana@example.com", ""},
+		{"más de 72 bytes con bcrypt", bcryptPolicy, long, "", "72 bytes"},
+		{"más de 72 bytes con argon2id", argonPolicy, long, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password, tt.email)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, se esperaba que contuviera %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordClasses(t *testing.T) {
+	tests := map[string]int{
+		"abc":     1,
+		"abcDEF":  2,
+		"abc123":  2,
+		"aB3":     3,
+		"aB3!":    4,
+		"ñÑ9 ":    4,
+		"1234567": 1,
+	}
+	for password, want := range tests {
+		if got := passwordClasses(password); got != want {
+			t.Errorf("passwordClasses(%q) = %d, se esperaba %d", password, got, want)
+		}
+	}
+}
+
+func TestPasswordHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{PasswordAlgorithmBcrypt, PasswordAlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			policy := testPasswordPolicy(t, algorithm, bcrypt.MinCost)
+			hash, err := policy.Hash("Tr3s-Clases")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if algorithm == PasswordAlgorithmArgon2id && !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+				t.Errorf("hash argon2id con formato inesperado: %s", hash)
+			}
+			if !policy.Verify(hash, "Tr3s-Clases") {
+				t.Error("la contraseña correcta no se verifica")
+			}
+			if policy.Verify(hash, "Tr3s-clases") {
+				t.Error("se verifica una contraseña incorrecta")
+			}
+			if policy.NeedsRehash(hash) {
+				t.Error("un hash recién generado no debería necesitar rehash")
+			}
+
+			again, err := policy.Hash("Tr3s-Clases")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == hash {
+				t.Error("dos hashes de la misma contraseña deberían usar sales distintas")
+			}
+		})
+	}
+}
+
+func TestPasswordVerifyInvalidHashes(t *testing.T) {
+	policy := testPasswordPolicy(t, PasswordAlgorithmArgon2id, bcrypt.MinCost)
+	for _, hash := range []string{
+		"",
+		"texto-plano",
+		"$argon2id$v=19$m=19456,t=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$no base64!$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$no base64!",
+	} {
+		if policy.Verify(hash, "cualquiera") {
+			t.Errorf("hash %q no válido verificado", hash)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	bcryptLow := testPasswordPolicy(t, PasswordAlgorithmBcrypt, bcrypt.MinCost)
+	bcryptHigh := testPasswordPolicy(t, PasswordAlgorithmBcrypt, bcrypt.MinCost+1)
+	argon := testPasswordPolicy(t, PasswordAlgorithmArgon2id, bcrypt.MinCost)
+
+	bcryptHash, err := bcryptLow.Hash("Tr3s-Clases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := argon.Hash("Tr3s-Clases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Mismo formato con parámetros antiguos (menos memoria)
+	weakArgonHash := strings.Replace(argonHash, "m=19456,", "m=4096,", 1)
+
+	tests := []struct {
+		name   string
+		policy *PasswordPolicy
+		hash   string
+		want   bool
+	}{
+		{"bcrypt con el coste configurado", bcryptLow, bcryptHash, false},
+		{"bcrypt con otro coste", bcryptHigh, bcryptHash, true},
+		{"bcrypt con argon2id configurado", argon, bcryptHash, true},
+		{"argon2id con argon2id configurado", argon, argonHash, false},
+		{"argon2id con otros parámetros", argon, weakArgonHash, true},
+		{"argon2id con bcrypt configurado", bcryptLow, argonHash, true},
+		{"hash no válido", bcryptLow, "texto-plano", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+
+	// Tras el cambio de algoritmo los hashes antiguos siguen siendo válidos hasta el rehash
+	if !argon.Verify(bcryptHash, "Tr3s-Clases") || !bcryptLow.Verify(argonHash, "Tr3s-Clases") {
+		t.Error("un hash del otro algoritmo debe seguir verificándose")
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	policy := testPasswordPolicy(t, PasswordAlgorithmBcrypt, bcrypt.MinCost)
+
+	tests := []struct {
+		name    string
+		current string
+		history []string
+		want    []string
+	}{
+		{"primera contraseña", "", nil, nil},
+		{"añade la actual al principio", "h1", []string{"h0"}, []string{"h1", "h0"}},
+		{"recorta al tamaño configurado", "h3", []string{"h2", "h1", "h0"}, []string{"h3", "h2", "h1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.History(tt.current, tt.history)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("History() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+
+	policy.cfg.PasswordHistorySize = 0
+	if got := policy.History("h1", []string{"h0"}); len(got) != 0 {
+		t.Errorf("sin historial configurado se esperaba una lista vacía, se obtuvo %v", got)
+	}
+}
+
+func TestPasswordReusedIn(t *testing.T) {
+	policy := testPasswordPolicy(t, PasswordAlgorithmBcrypt, bcrypt.MinCost)
+	old, err := policy.Hash("Vieja-Clave1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon := testPasswordPolicy(t, PasswordAlgorithmArgon2id, bcrypt.MinCost)
+	older, err := argon.Hash("Mas-Vieja-Clave2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []string{"", old, older}
+
+	if !policy.ReusedIn("Vieja-Clave1", history) {
+		t.Error("no se detecta la reutilización de una contraseña bcrypt")
+	}
+	if !policy.ReusedIn("Mas-Vieja-Clave2", history) {
+		t.Error("no se detecta la reutilización de una contraseña argon2id")
+	}
+	if policy.ReusedIn("Nueva-Clave3", history) {
+		t.Error("contraseña nueva marcada como reutilizada")
+	}
+}
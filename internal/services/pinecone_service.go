@@ -7,19 +7,31 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	PineconeBaseURL = "https://api.pinecone.io"
+
+	// PineconeAPIVersion es la versión de la API de Pinecone con la que se envían las peticiones
+	PineconeAPIVersion = "2024-07"
 )
 
+// pineconeHosts guarda el host de datos de cada índice ya resuelto con la API de control
+var pineconeHosts sync.Map
+
 // PineconeClient maneja la comunicación con Pinecone
 type PineconeClient struct {
 	APIKey      string
-	BaseURL     string
+	BaseURL     string // API de control (describe index)
+	Host        string // Host de datos del índice (https://{index}-{proyecto}.svc.{región}.pinecone.io); vacío = se resuelve con BaseURL
 	Environment string
 	IndexName   string
 	HTTPClient  *http.Client
@@ -31,10 +43,20 @@ func NewPineconeClient(apiKey, indexName string) *PineconeClient {
 		APIKey:     apiKey,
 		BaseURL:    PineconeBaseURL,
 		IndexName:  indexName,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: &http.Client{Timeout: 30 * time.Second, Transport: tracing.Transport(nil)},
 	}
 }
 
+// PineconeStatusError es una respuesta de error de Pinecone
+type PineconeStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *PineconeStatusError) Error() string {
+	return fmt.Sprintf("error de Pinecone (status %d): %s", e.StatusCode, e.Body)
+}
+
 // UpsertRequest representa una solicitud de upsert a Pinecone
 type UpsertRequest struct {
 	Vectors   []PineconeVector `json:"vectors"`
@@ -53,8 +75,8 @@ type UpsertResponse struct {
 	UpsertedCount int `json:"upsertedCount"`
 }
 
-// StoreVectors almacena vectores en Pinecone
-func (pc *PineconeClient) StoreVectors(vectors []domain.Vector, namespace string) error {
+// Upsert almacena vectores en Pinecone
+func (pc *PineconeClient) Upsert(ctx context.Context, namespace string, vectors []domain.Vector) (err error) {
 	if len(vectors) == 0 {
 		return fmt.Errorf("no hay vectores para almacenar")
 	}
@@ -69,42 +91,11 @@ func (pc *PineconeClient) StoreVectors(vectors []domain.Vector, namespace string
 		}
 	}
 
-	reqBody := UpsertRequest{
-		Vectors:   pineconeVectors,
-		Namespace: namespace,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("error al serializar datos: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/vectors/upsert", pc.getIndexURL())
-	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(jsonData))
-	if err != nil {
-		return fmt.Errorf("error al crear request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Api-Key", pc.APIKey)
-
-	resp, err := pc.HTTPClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error al realizar request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("error de Pinecone (status %d): %s", resp.StatusCode, string(body))
-	}
+	ctx, end := startExternalCall(ctx, "pinecone", "upsert", attribute.Int("vectors", len(vectors)), attribute.String("namespace", namespace))
+	defer func() { end(err) }()
 
 	var upsertResp UpsertResponse
-	if err := json.NewDecoder(resp.Body).Decode(&upsertResp); err != nil {
-		return fmt.Errorf("error al decodificar respuesta: %w", err)
-	}
-
-	return nil
+	return pc.post(ctx, "/vectors/upsert", UpsertRequest{Vectors: pineconeVectors, Namespace: namespace}, &upsertResp)
 }
 
 // QueryRequest representa una solicitud de consulta a Pinecone
@@ -112,6 +103,7 @@ type QueryRequest struct {
 	Vector          []float32              `json:"vector"`
 	TopK            int                    `json:"topK"`
 	IncludeMetadata bool                   `json:"includeMetadata"`
+	IncludeValues   bool                   `json:"includeValues"`
 	Namespace       string                 `json:"namespace,omitempty"`
 	Filter          map[string]interface{} `json:"filter,omitempty"`
 }
@@ -126,82 +118,235 @@ type QueryResponse struct {
 	} `json:"matches"`
 }
 
-// QueryVectors consulta vectores similares en Pinecone
-func (pc *PineconeClient) QueryVectors(queryVector []float32, topK int, namespace string) ([]domain.Vector, error) {
-	if len(queryVector) == 0 {
+// Query consulta vectores similares en Pinecone
+func (pc *PineconeClient) Query(ctx context.Context, namespace string, query VectorQuery) (_ []domain.Vector, err error) {
+	if len(query.Vector) == 0 {
 		return nil, fmt.Errorf("vector de consulta vacío")
 	}
 
-	reqBody := QueryRequest{
-		Vector:          queryVector,
-		TopK:            topK,
+	ctx, end := startExternalCall(ctx, "pinecone", "query", attribute.Int("top_k", query.TopK), attribute.String("namespace", namespace))
+	defer func() { end(err) }()
+
+	var queryResp QueryResponse
+	if err := pc.post(ctx, "/query", QueryRequest{
+		Vector:          query.Vector,
+		TopK:            query.TopK,
 		IncludeMetadata: true,
+		IncludeValues:   query.IncludeValues,
 		Namespace:       namespace,
+		Filter:          query.Filter,
+	}, &queryResp); err != nil {
+		return nil, err
+	}
+
+	// Convertir respuesta a domain.Vector
+	vectors := make([]domain.Vector, len(queryResp.Matches))
+	for i, match := range queryResp.Matches {
+		vectors[i] = vectorFromMetadata(domain.Vector{
+			ID:       match.ID,
+			Values:   match.Values,
+			Metadata: match.Metadata,
+			Score:    match.Score,
+		})
+	}
+
+	return vectors, nil
+}
+
+// DeleteIDs borra vectores concretos de un namespace
+func (pc *PineconeClient) DeleteIDs(ctx context.Context, namespace string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return pc.delete(ctx, namespace, map[string]interface{}{"ids": ids, "namespace": namespace})
+}
+
+// DeleteByFilter borra los vectores cuya metadata cumple el filtro. Los índices serverless de
+// Pinecone no lo admiten: en ellos se borra por namespace o por id
+func (pc *PineconeClient) DeleteByFilter(ctx context.Context, namespace string, filter map[string]interface{}) error {
+	if len(filter) == 0 {
+		return fmt.Errorf("filtro vacío (usa DeleteNamespace para borrar todo el namespace)")
 	}
+	return pc.delete(ctx, namespace, map[string]interface{}{"filter": filter, "namespace": namespace})
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// DeleteNamespace borra todos los vectores de un namespace (p. ej. los chunks de un documento).
+// Un namespace que no existe se considera ya borrado
+func (pc *PineconeClient) DeleteNamespace(ctx context.Context, namespace string) error {
+	return pc.delete(ctx, namespace, map[string]interface{}{"deleteAll": true, "namespace": namespace})
+}
+
+func (pc *PineconeClient) delete(ctx context.Context, namespace string, body map[string]interface{}) (err error) {
+	ctx, end := startExternalCall(ctx, "pinecone", "delete", attribute.String("namespace", namespace))
+	defer func() { end(err) }()
+
+	err = pc.post(ctx, "/vectors/delete", body, nil)
+	if statusErr, ok := err.(*PineconeStatusError); ok && statusErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Stats devuelve la dimensión del índice y los vectores de cada namespace
+func (pc *PineconeClient) Stats(ctx context.Context) (_ *VectorStoreStats, err error) {
+	ctx, end := startExternalCall(ctx, "pinecone", "describe_index_stats")
+	defer func() { end(err) }()
+
+	var statsResp struct {
+		Dimension        int `json:"dimension"`
+		TotalVectorCount int `json:"totalVectorCount"`
+		Namespaces       map[string]struct {
+			VectorCount int `json:"vectorCount"`
+		} `json:"namespaces"`
+	}
+	if err := pc.post(ctx, "/describe_index_stats", map[string]interface{}{}, &statsResp); err != nil {
+		return nil, err
+	}
+
+	stats := &VectorStoreStats{
+		Backend:          VectorStorePinecone,
+		Dimension:        statsResp.Dimension,
+		TotalVectorCount: statsResp.TotalVectorCount,
+		Namespaces:       make(map[string]int, len(statsResp.Namespaces)),
+	}
+	for name, ns := range statsResp.Namespaces {
+		stats.Namespaces[name] = ns.VectorCount
+	}
+	return stats, nil
+}
+
+// Ping comprueba que la API key es válida y que el índice existe (describe index)
+func (pc *PineconeClient) Ping(ctx context.Context) (err error) {
+	ctx, end := startExternalCall(ctx, "pinecone", "describe_index")
+	defer func() { end(err) }()
+
+	host, err := pc.describeIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if pc.Host == "" {
+		pineconeHosts.Store(pc.hostCacheKey(), host)
+	}
+	return nil
+}
+
+// StoreVectors almacena vectores en Pinecone
+func (pc *PineconeClient) StoreVectors(ctx context.Context, vectors []domain.Vector, namespace string) error {
+	return pc.Upsert(ctx, namespace, vectors)
+}
+
+// QueryVectors consulta vectores similares en Pinecone
+func (pc *PineconeClient) QueryVectors(ctx context.Context, queryVector []float32, topK int, namespace string) ([]domain.Vector, error) {
+	return pc.Query(ctx, namespace, VectorQuery{Vector: queryVector, TopK: topK})
+}
+
+// post envía una petición al host de datos del índice y decodifica la respuesta en out (si no es nil)
+func (pc *PineconeClient) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	indexURL, err := pc.getIndexURL(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error al serializar datos: %w", err)
+		return err
 	}
 
-	url := fmt.Sprintf("%s/query", pc.getIndexURL())
-	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(jsonData))
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return nil, fmt.Errorf("error al crear request: %w", err)
+		return fmt.Errorf("error al serializar datos: %w", err)
 	}
 
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, indexURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("error al crear request: %w", err)
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Api-Key", pc.APIKey)
+	pc.setHeaders(req)
 
 	resp, err := pc.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error al realizar request: %w", err)
+		return fmt.Errorf("error al realizar request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error de Pinecone (status %d): %s", resp.StatusCode, string(body))
+		respBody, _ := io.ReadAll(resp.Body)
+		return &PineconeStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error al decodificar respuesta: %w", err)
 	}
+	return nil
+}
 
-	var queryResp QueryResponse
-	if err := json.NewDecoder(resp.Body).Decode(&queryResp); err != nil {
-		return nil, fmt.Errorf("error al decodificar respuesta: %w", err)
+func (pc *PineconeClient) setHeaders(req *http.Request) {
+	req.Header.Set("Api-Key", pc.APIKey)
+	req.Header.Set("X-Pinecone-API-Version", PineconeAPIVersion)
+}
+
+// getIndexURL obtiene la URL del host de datos del índice. Cada índice tiene su propio host
+// (https://{index}-{proyecto}.svc.{región}.pinecone.io); si no se ha configurado se pide a la
+// API de control y se guarda para las siguientes peticiones
+func (pc *PineconeClient) getIndexURL(ctx context.Context) (string, error) {
+	if pc.Host != "" {
+		return strings.TrimSuffix(pc.Host, "/"), nil
+	}
+	if host, ok := pineconeHosts.Load(pc.hostCacheKey()); ok {
+		return host.(string), nil
 	}
 
-	// Convertir respuesta a domain.Vector
-	vectors := make([]domain.Vector, len(queryResp.Matches))
-	for i, match := range queryResp.Matches {
-		vectors[i] = domain.Vector{
-			ID:       match.ID,
-			Values:   match.Values,
-			Metadata: match.Metadata,
-		}
+	host, err := pc.describeIndex(ctx)
+	if err != nil {
+		return "", err
 	}
+	pineconeHosts.Store(pc.hostCacheKey(), host)
+	return host, nil
+}
 
-	return vectors, nil
+// describeIndex consulta el índice en la API de control y devuelve la URL de su host de datos
+func (pc *PineconeClient) describeIndex(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/indexes/%s", pc.BaseURL, pc.IndexName), nil)
+	if err != nil {
+		return "", err
+	}
+	pc.setHeaders(req)
+
+	resp, err := pc.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error al realizar request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Pinecone respondió %d para el índice %s", resp.StatusCode, pc.IndexName)
+	}
+
+	var index struct {
+		Host string `json:"host"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return "", fmt.Errorf("error al decodificar respuesta: %w", err)
+	}
+	if index.Host == "" {
+		return "", fmt.Errorf("Pinecone no devolvió el host del índice %s", pc.IndexName)
+	}
+	if !strings.Contains(index.Host, "://") {
+		index.Host = "https://" + index.Host
+	}
+	return strings.TrimSuffix(index.Host, "/"), nil
 }
 
-// getIndexURL obtiene la URL del índice de Pinecone
-// Nota: Pinecone requiere que el índice se configure con la URL completa
-// Por defecto, usamos: https://api.pinecone.io/indexes/{index-name}
-// Si necesitas usar un índice específico, deberás configurar la URL completa
-func (pc *PineconeClient) getIndexURL() string {
-	// Para Pinecone Serverless, la URL es: https://{index-name}-{project-id}.svc.{environment}.pinecone.io
-	// Para Pinecone Pods, la URL es: https://{index-name}-{project-id}.svc.{environment}.pinecone.io
-	// Por ahora, usamos la API estándar que requiere configuración del índice
-	return fmt.Sprintf("%s/indexes/%s", pc.BaseURL, pc.IndexName)
+func (pc *PineconeClient) hostCacheKey() string {
+	return pc.BaseURL + "|" + pc.IndexName
 }
 
 // StoreVectors almacena vectores en Pinecone (función de conveniencia)
-func StoreVectors(vectors []domain.Vector, namespace, apiKey, indexName string) error {
+func StoreVectors(ctx context.Context, vectors []domain.Vector, namespace, apiKey, indexName string) error {
 	client := NewPineconeClient(apiKey, indexName)
-	return client.StoreVectors(vectors, namespace)
+	return client.StoreVectors(ctx, vectors, namespace)
 }
 
 // QueryVectors consulta vectores en Pinecone (función de conveniencia)
-func QueryVectors(queryVector []float32, topK int, namespace, apiKey, indexName string) ([]domain.Vector, error) {
+func QueryVectors(ctx context.Context, queryVector []float32, topK int, namespace, apiKey, indexName string) ([]domain.Vector, error) {
 	client := NewPineconeClient(apiKey, indexName)
-	return client.QueryVectors(queryVector, topK, namespace)
+	return client.QueryVectors(ctx, queryVector, topK, namespace)
 }
-
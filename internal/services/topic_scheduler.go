@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/metrics"
+	"opo_admin_server/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TopicScheduler publica y despublica topics según publishAt/unpublishAt
+type TopicScheduler struct {
+	cfg      config.Config
+	interval time.Duration
+}
+
+// NewTopicScheduler crea una nueva instancia del scheduler de topics
+func NewTopicScheduler(cfg config.Config) *TopicScheduler {
+	return &TopicScheduler{cfg: cfg, interval: cfg.TopicSchedulerInterval}
+}
+
+// Start ejecuta el scheduler periódicamente hasta que se cancele el contexto. Una ejecución
+// en curso no se interrumpe: se termina antes de salir
+func (s *TopicScheduler) Start(ctx context.Context) {
+	logging.Printf(ctx, "⏰ Scheduler de topics iniciado (intervalo: %s)", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(context.WithoutCancel(ctx)); err != nil {
+			logging.Printf(ctx, "❌ Scheduler de topics - Error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logging.Printf(ctx, "⏰ Scheduler de topics detenido")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce aplica las publicaciones y despublicaciones vencidas.
+// Las actualizaciones son idempotentes: solo afectan a topics aún no procesados,
+// por lo que varias instancias pueden ejecutarlo a la vez sin conflicto.
+func (s *TopicScheduler) RunOnce(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "topic_scheduler.run", trace.SpanKindInternal)
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(s.cfg.DBURL).SetMonitor(tracing.MongoMonitor(metrics.MongoMonitor())))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	col := client.Database(s.cfg.DBName).Collection("topics_uuid_map")
+	now := time.Now()
+
+	// Publicar: publishAt vencido y todavía no publicado por el scheduler
+	published, err := col.UpdateMany(ctx, bson.M{
+		"publishAt":   bson.M{"$lte": now},
+		"publishedAt": nil,
+	}, bson.M{
+		"$set": bson.M{
+			"status":      domain.TopicStatusActive,
+			"enabled":     true,
+			"publishedAt": now,
+			"updatedAt":   now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Despublicar: unpublishAt vencido y todavía no despublicado por el scheduler
+	unpublished, err := col.UpdateMany(ctx, bson.M{
+		"unpublishAt":   bson.M{"$lte": now},
+		"unpublishedAt": nil,
+	}, bson.M{
+		"$set": bson.M{
+			"status":        domain.TopicStatusHidden,
+			"enabled":       false,
+			"unpublishedAt": now,
+			"updatedAt":     now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if published.ModifiedCount > 0 || unpublished.ModifiedCount > 0 {
+		logging.Printf(ctx, "✅ Scheduler de topics - %d publicados, %d despublicados", published.ModifiedCount, unpublished.ModifiedCount)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secreto de los vectores de prueba de RFC 6238 (apéndice B, SHA1): "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Los vectores de RFC 6238 son de 8 dígitos; con 6 dígitos el código son sus 6 últimas cifras
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, tt := range rfc6238Vectors {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("T=%d: código %s, se esperaba %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPRFC6238(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		at := time.Unix(tt.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, at)
+		if !ok {
+			t.Errorf("T=%d: código %s rechazado", tt.unix, tt.code)
+			continue
+		}
+		if step != tt.unix/totpPeriod {
+			t.Errorf("T=%d: paso %d, se esperaba %d", tt.unix, step, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	// 1111111109 y 1111111111 están en pasos consecutivos (37037036 y 37037037)
+	code := "081804"
+	codeStep := int64(1111111109 / totpPeriod)
+	stepStart := func(step int64) time.Time { return time.Unix(step*totpPeriod, 0) }
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantOK   bool
+		wantStep int64
+	}{
+		{"mismo paso", stepStart(codeStep), true, codeStep},
+		{"último segundo del paso", stepStart(codeStep + 1).Add(-time.Second), true, codeStep},
+		{"un paso después (reloj del servidor adelantado)", stepStart(codeStep + 1), true, codeStep},
+		{"un paso antes (reloj del móvil adelantado)", stepStart(codeStep - 1), true, codeStep},
+		{"dos pasos después", stepStart(codeStep + 2), false, 0},
+		{"dos pasos antes", stepStart(codeStep - 2), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, code, tt.at)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = (%d, %v), se esperaba (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInput(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		wantOK bool
+	}{
+		{"con espacios", rfc6238Secret, " 005 924 ", true},
+		{"secreto en minúsculas", strings.ToLower(rfc6238Secret), "005924", true},
+		{"código incorrecto", rfc6238Secret, "005925", false},
+		{"código de 8 dígitos", rfc6238Secret, "89005924", false},
+		{"código corto", rfc6238Secret, "05924", false},
+		{"vacío", rfc6238Secret, "", false},
+		{"secreto no válido", "no-es-base32!", "005924", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok != tt.wantOK {
+				t.Errorf("ValidateTOTP() = %v, se esperaba %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPAfterReplay(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	current := int64(1111111111 / totpPeriod)
+
+	// Primer uso: se acepta y se guarda el paso
+	step, ok := ValidateTOTPAfter(rfc6238Secret, "050471", at, 0)
+	if !ok || step != current {
+		t.Fatalf("primer uso: (%d, %v), se esperaba (%d, true)", step, ok, current)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		lastStep int64
+		wantOK   bool
+	}{
+		{"mismo código otra vez", "050471", at, current, false},
+		{"mismo código en el paso siguiente", "050471", at.Add(totpPeriod * time.Second), current, false},
+		{"código del paso anterior tras usar el actual", "081804", at, current, false},
+		{"código anterior si aún no se había usado ninguno", "081804", at, current - 2, true},
+		{"código del paso siguiente", totpCode([]byte("12345678901234567890"), current+1), at, current, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTPAfter(rfc6238Secret, tt.code, tt.at, tt.lastStep); ok != tt.wantOK {
+				t.Errorf("ValidateTOTPAfter() = %v, se esperaba %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secreto %q no es base32 de 160 bits: %v", secret, err)
+	}
+
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, time.Now()); !ok {
+		t.Errorf("el código actual del secreto generado no se acepta")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("OPO Admin", "admin@example.com", rfc6238Secret)
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("URI %s no es otpauth://totp", uri)
+	}
+	if parsed.Path != "/OPO Admin:admin@example.com" {
+		t.Errorf("etiqueta %q", parsed.Path)
+	}
+	query := parsed.Query()
+	for key, want := range map[string]string{"secret": rfc6238Secret, "issuer": "OPO Admin", "digits": "6", "period": "30", "algorithm": "SHA1"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, se esperaba %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"opo_admin_server/internal/config"
+	"opo_admin_server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Backends de vector store (VECTOR_STORE)
+const (
+	VectorStorePinecone = "pinecone"
+	VectorStoreMongo    = "mongo"
+)
+
+// VectorStore guarda y consulta los vectores (chunks con su embedding) de los documentos.
+// Cada documento usa su propio namespace (ver DocumentNamespace)
+type VectorStore interface {
+	// Upsert crea o reemplaza vectores por id
+	Upsert(ctx context.Context, namespace string, vectors []domain.Vector) error
+	// Query devuelve los TopK vectores más similares (coseno), de mayor a menor Score
+	Query(ctx context.Context, namespace string, query VectorQuery) ([]domain.Vector, error)
+	// DeleteIDs borra vectores concretos
+	DeleteIDs(ctx context.Context, namespace string, ids []string) error
+	// DeleteByFilter borra los vectores cuya metadata cumple el filtro
+	DeleteByFilter(ctx context.Context, namespace string, filter map[string]interface{}) error
+	// DeleteNamespace borra todos los vectores de un namespace. Uno que no existe ya está borrado
+	DeleteNamespace(ctx context.Context, namespace string) error
+	// Stats devuelve la dimensión y el número de vectores por namespace
+	Stats(ctx context.Context) (*VectorStoreStats, error)
+	// Ping comprueba que el backend responde
+	Ping(ctx context.Context) error
+}
+
+// VectorQuery es una búsqueda por similitud
+type VectorQuery struct {
+	Vector        []float32
+	TopK          int
+	Filter        map[string]interface{} // Filtro de metadata con la sintaxis de Pinecone ($eq, $in, $and...)
+	IncludeValues bool                   // Devolver también los valores de cada vector
+}
+
+// VectorStoreStats resume el contenido del vector store
+type VectorStoreStats struct {
+	Backend          string         `json:"backend"`
+	Dimension        int            `json:"dimension"`
+	TotalVectorCount int            `json:"totalVectorCount"`
+	Namespaces       map[string]int `json:"namespaces"` // Vectores por namespace
+}
+
+// VectorStoreConfigError indica por qué no se puede usar el vector store configurado (nil si se puede)
+func VectorStoreConfigError(cfg config.Config) error {
+	switch cfg.VectorStore {
+	case VectorStorePinecone:
+		if cfg.PineconeAPIKey == "" {
+			return fmt.Errorf("PINECONE_API_KEY no configurada")
+		}
+	case VectorStoreMongo:
+	default:
+		return fmt.Errorf("VECTOR_STORE no válido %q", cfg.VectorStore)
+	}
+	return nil
+}
+
+// NewVectorStore crea el vector store configurado en VECTOR_STORE. db solo se usa con el backend
+// mongo y debe seguir conectada mientras se use el vector store
+func NewVectorStore(cfg config.Config, db *mongo.Database) (VectorStore, error) {
+	if err := VectorStoreConfigError(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.VectorStore == VectorStoreMongo {
+		if db == nil {
+			return nil, fmt.Errorf("el vector store mongo necesita una conexión a MongoDB")
+		}
+		return NewMongoVectorStore(db.Collection(cfg.VectorCollection)), nil
+	}
+
+	client := NewPineconeClient(cfg.PineconeAPIKey, cfg.PineconeIndex)
+	client.Host = cfg.PineconeIndexHost
+	return client, nil
+}
+
+// vectorFromMetadata completa los campos del vector que se guardan en su metadata
+func vectorFromMetadata(v domain.Vector) domain.Vector {
+	if text, ok := v.Metadata["text"].(string); ok {
+		v.Text = text
+	}
+	if documentID, ok := v.Metadata["documentId"].(string); ok {
+		v.DocumentID = documentID
+	}
+	switch index := v.Metadata["chunkIndex"].(type) {
+	case float64:
+		v.ChunkIndex = int(index)
+	case int:
+		v.ChunkIndex = index
+	case int32:
+		v.ChunkIndex = int(index)
+	case int64:
+		v.ChunkIndex = int(index)
+	}
+	return v
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MongoVectorStore guarda los vectores en una colección de MongoDB y busca por similitud
+// recorriendo todo el namespace (búsqueda exacta). Pensado para desarrollo y pruebas sin Pinecone:
+// con muchos vectores cada consulta es lenta
+type MongoVectorStore struct {
+	col *mongo.Collection
+}
+
+// mongoVector es el documento guardado por cada vector
+type mongoVector struct {
+	ID         string                 `bson:"_id"` // namespace/id
+	Namespace  string                 `bson:"namespace"`
+	VectorID   string                 `bson:"vectorId"`
+	Values     []float32              `bson:"values"`
+	Metadata   map[string]interface{} `bson:"metadata"`
+	DocumentID string                 `bson:"documentId,omitempty"`
+	ChunkIndex int                    `bson:"chunkIndex"`
+	Text       string                 `bson:"text,omitempty"`
+	UpdatedAt  time.Time              `bson:"updatedAt"`
+}
+
+// Operadores de filtro de metadata admitidos (los mismos que Pinecone)
+var vectorFilterOperators = map[string]bool{
+	"$eq": true, "$ne": true, "$gt": true, "$gte": true, "$lt": true, "$lte": true,
+	"$in": true, "$nin": true, "$exists": true,
+}
+
+// NewMongoVectorStore crea un vector store sobre la colección indicada
+func NewMongoVectorStore(col *mongo.Collection) *MongoVectorStore {
+	return &MongoVectorStore{col: col}
+}
+
+// Upsert crea o reemplaza vectores por id
+func (s *MongoVectorStore) Upsert(ctx context.Context, namespace string, vectors []domain.Vector) (err error) {
+	if len(vectors) == 0 {
+		return fmt.Errorf("no hay vectores para almacenar")
+	}
+
+	ctx, end := startExternalCall(ctx, "vectorstore_mongo", "upsert", attribute.Int("vectors", len(vectors)), attribute.String("namespace", namespace))
+	defer func() { end(err) }()
+
+	now := time.Now()
+	models := make([]mongo.WriteModel, len(vectors))
+	for i, v := range vectors {
+		doc := mongoVector{
+			ID:         mongoVectorID(namespace, v.ID),
+			Namespace:  namespace,
+			VectorID:   v.ID,
+			Values:     v.Values,
+			Metadata:   v.Metadata,
+			DocumentID: v.DocumentID,
+			ChunkIndex: v.ChunkIndex,
+			Text:       v.Text,
+			UpdatedAt:  now,
+		}
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": doc.ID}).SetReplacement(doc).SetUpsert(true)
+	}
+	_, err = s.col.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Query calcula la similitud coseno con todos los vectores del namespace que cumplen el filtro
+func (s *MongoVectorStore) Query(ctx context.Context, namespace string, query VectorQuery) (_ []domain.Vector, err error) {
+	if len(query.Vector) == 0 {
+		return nil, fmt.Errorf("vector de consulta vacío")
+	}
+	if query.TopK < 1 {
+		return nil, fmt.Errorf("topK debe ser mayor que 0")
+	}
+
+	ctx, end := startExternalCall(ctx, "vectorstore_mongo", "query", attribute.Int("top_k", query.TopK), attribute.String("namespace", namespace))
+	defer func() { end(err) }()
+
+	filter, err := mongoVectorFilter(namespace, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := s.col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	queryNorm := vectorNorm(query.Vector)
+	top := newTopVectors(query.TopK)
+	skipped := 0
+	for cur.Next(ctx) {
+		var doc mongoVector
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		match, ok := scoreVector(query, queryNorm, doc)
+		if !ok {
+			skipped++
+			continue
+		}
+		top.add(match)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		logging.Printf(ctx, "⚠️ Vector store - %d vectores del namespace %s ignorados por tener una dimensión distinta de %d", skipped, namespace, len(query.Vector))
+	}
+	return top.sorted(), nil
+}
+
+// scoreVector calcula la similitud de un vector con la consulta. Devuelve false si tiene otra
+// dimensión (vectores de otro modelo de embeddings, que no son comparables)
+func scoreVector(query VectorQuery, queryNorm float64, doc mongoVector) (domain.Vector, bool) {
+	if len(doc.Values) != len(query.Vector) {
+		return domain.Vector{}, false
+	}
+	match := doc.vector()
+	match.Score = cosineSimilarity(query.Vector, queryNorm, doc.Values)
+	if !query.IncludeValues {
+		match.Values = nil
+	}
+	return match, true
+}
+
+// topVectors conserva los k vectores con mayor puntuación en un montículo de mínimos, sin
+// guardar todos los candidatos. Con la misma puntuación gana el que llegó antes
+type topVectors struct {
+	k     int
+	items []rankedVector
+	seq   int
+}
+
+type rankedVector struct {
+	vector domain.Vector
+	seq    int
+}
+
+func newTopVectors(k int) *topVectors {
+	return &topVectors{k: k, items: make([]rankedVector, 0, min(k, 1024))}
+}
+
+func (t *topVectors) Len() int      { return len(t.items) }
+func (t *topVectors) Swap(i, j int) { t.items[i], t.items[j] = t.items[j], t.items[i] }
+func (t *topVectors) Push(x any)    { t.items = append(t.items, x.(rankedVector)) }
+func (t *topVectors) Pop() any {
+	last := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return last
+}
+
+// Less ordena de peor a mejor: la raíz es el primer candidato a descartar
+func (t *topVectors) Less(i, j int) bool {
+	a, b := t.items[i], t.items[j]
+	if a.vector.Score != b.vector.Score {
+		return a.vector.Score < b.vector.Score
+	}
+	return a.seq > b.seq
+}
+
+func (t *topVectors) add(v domain.Vector) {
+	item := rankedVector{vector: v, seq: t.seq}
+	t.seq++
+	if len(t.items) < t.k {
+		heap.Push(t, item)
+		return
+	}
+	if v.Score > t.items[0].vector.Score {
+		t.items[0] = item
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted devuelve los vectores de mayor a menor puntuación
+func (t *topVectors) sorted() []domain.Vector {
+	result := make([]domain.Vector, len(t.items))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(t).(rankedVector).vector
+	}
+	return result
+}
+
+// DeleteIDs borra vectores concretos de un namespace
+func (s *MongoVectorStore) DeleteIDs(ctx context.Context, namespace string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	docIDs := make([]string, len(ids))
+	for i, id := range ids {
+		docIDs[i] = mongoVectorID(namespace, id)
+	}
+	return s.delete(ctx, namespace, bson.M{"_id": bson.M{"$in": docIDs}})
+}
+
+// DeleteByFilter borra los vectores del namespace cuya metadata cumple el filtro
+func (s *MongoVectorStore) DeleteByFilter(ctx context.Context, namespace string, filter map[string]interface{}) error {
+	if len(filter) == 0 {
+		return fmt.Errorf("filtro vacío (usa DeleteNamespace para borrar todo el namespace)")
+	}
+	mongoFilter, err := mongoVectorFilter(namespace, filter)
+	if err != nil {
+		return err
+	}
+	return s.delete(ctx, namespace, mongoFilter)
+}
+
+// DeleteNamespace borra todos los vectores de un namespace
+func (s *MongoVectorStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	return s.delete(ctx, namespace, bson.M{"namespace": namespace})
+}
+
+func (s *MongoVectorStore) delete(ctx context.Context, namespace string, filter bson.M) (err error) {
+	ctx, end := startExternalCall(ctx, "vectorstore_mongo", "delete", attribute.String("namespace", namespace))
+	defer func() { end(err) }()
+
+	_, err = s.col.DeleteMany(ctx, filter)
+	return err
+}
+
+// Stats cuenta los vectores de cada namespace
+func (s *MongoVectorStore) Stats(ctx context.Context) (_ *VectorStoreStats, err error) {
+	ctx, end := startExternalCall(ctx, "vectorstore_mongo", "stats")
+	defer func() { end(err) }()
+
+	cur, err := s.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$namespace",
+			"count":     bson.M{"$sum": 1},
+			"dimension": bson.M{"$max": bson.M{"$size": "$values"}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Namespace string `bson:"_id"`
+		Count     int    `bson:"count"`
+		Dimension int    `bson:"dimension"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	stats := &VectorStoreStats{Backend: VectorStoreMongo, Namespaces: make(map[string]int, len(groups))}
+	for _, group := range groups {
+		stats.Namespaces[group.Namespace] = group.Count
+		stats.TotalVectorCount += group.Count
+		stats.Dimension = max(stats.Dimension, group.Dimension)
+	}
+	return stats, nil
+}
+
+// Ping comprueba que MongoDB responde
+func (s *MongoVectorStore) Ping(ctx context.Context) error {
+	return s.col.Database().Client().Ping(ctx, nil)
+}
+
+func (doc mongoVector) vector() domain.Vector {
+	return domain.Vector{
+		ID:         doc.VectorID,
+		Values:     doc.Values,
+		Metadata:   doc.Metadata,
+		DocumentID: doc.DocumentID,
+		ChunkIndex: doc.ChunkIndex,
+		Text:       doc.Text,
+	}
+}
+
+func mongoVectorID(namespace, id string) string {
+	return namespace + "/" + id
+}
+
+// mongoVectorFilter traduce un filtro de metadata con la sintaxis de Pinecone a un filtro de MongoDB
+func mongoVectorFilter(namespace string, filter map[string]interface{}) (bson.M, error) {
+	metadataFilter, err := translateVectorFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	metadataFilter["namespace"] = namespace
+	return metadataFilter, nil
+}
+
+func translateVectorFilter(filter map[string]interface{}) (bson.M, error) {
+	result := bson.M{}
+	for key, value := range filter {
+		switch {
+		case key == "$and" || key == "$or":
+			clauses, ok := value.([]interface{})
+			if !ok {
+				if typed, isMaps := value.([]map[string]interface{}); isMaps {
+					for _, clause := range typed {
+						clauses = append(clauses, clause)
+					}
+					ok = true
+				}
+			}
+			if !ok || len(clauses) == 0 {
+				return nil, fmt.Errorf("filtro no válido: %s necesita una lista de condiciones", key)
+			}
+			translated := make(bson.A, len(clauses))
+			for i, clause := range clauses {
+				clauseMap, ok := clause.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("filtro no válido: cada condición de %s debe ser un objeto", key)
+				}
+				t, err := translateVectorFilter(clauseMap)
+				if err != nil {
+					return nil, err
+				}
+				translated[i] = t
+			}
+			result[key] = translated
+		case strings.HasPrefix(key, "$"):
+			return nil, fmt.Errorf("filtro no válido: operador %s no admitido", key)
+		default:
+			if conditions, ok := value.(map[string]interface{}); ok {
+				for op := range conditions {
+					if !vectorFilterOperators[op] {
+						return nil, fmt.Errorf("filtro no válido: operador %s no admitido en %s", op, key)
+					}
+				}
+			}
+			result["metadata."+key] = value
+		}
+	}
+	return result, nil
+}
+
+func vectorNorm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+// cosineSimilarity calcula el coseno entre la consulta (con su norma ya calculada) y un vector
+func cosineSimilarity(query []float32, queryNorm float64, v []float32) float64 {
+	var dot float64
+	for i := range query {
+		dot += float64(query[i]) * float64(v[i])
+	}
+	norm := vectorNorm(v)
+	if queryNorm == 0 || norm == 0 {
+		return 0
+	}
+	return dot / (queryNorm * norm)
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"opo_admin_server/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTranslateVectorFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  map[string]interface{}
+		want    bson.M
+		wantErr bool
+	}{
+		{
+			name:   "vacío",
+			filter: map[string]interface{}{},
+			want:   bson.M{},
+		},
+		{
+			name:   "igualdad directa",
+			filter: map[string]interface{}{"documentId": "doc-1"},
+			want:   bson.M{"metadata.documentId": "doc-1"},
+		},
+		{
+			name:   "operadores admitidos",
+			filter: map[string]interface{}{"page": map[string]interface{}{"$gte": 2, "$lt": 10}},
+			want:   bson.M{"metadata.page": map[string]interface{}{"$gte": 2, "$lt": 10}},
+		},
+		{
+			name: "$and y $or anidados",
+			filter: map[string]interface{}{
+				"$or": []interface{}{
+					map[string]interface{}{"type": "pdf"},
+					map[string]interface{}{"$and": []interface{}{
+						map[string]interface{}{"type": "docx"},
+						map[string]interface{}{"page": map[string]interface{}{"$in": []interface{}{1, 2}}},
+					}},
+				},
+			},
+			want: bson.M{"$or": bson.A{
+				bson.M{"metadata.type": "pdf"},
+				bson.M{"$and": bson.A{
+					bson.M{"metadata.type": "docx"},
+					bson.M{"metadata.page": map[string]interface{}{"$in": []interface{}{1, 2}}},
+				}},
+			}},
+		},
+		{
+			name: "$and con []map",
+			filter: map[string]interface{}{
+				"$and": []map[string]interface{}{{"a": 1}, {"b": 2}},
+			},
+			want: bson.M{"$and": bson.A{bson.M{"metadata.a": 1}, bson.M{"metadata.b": 2}}},
+		},
+		{
+			name:    "operador raíz no admitido",
+			filter:  map[string]interface{}{"$where": "true"},
+			wantErr: true,
+		},
+		{
+			name:    "operador de campo no admitido",
+			filter:  map[string]interface{}{"text": map[string]interface{}{"$regex": ".*"}},
+			wantErr: true,
+		},
+		{
+			name:    "$or sin lista",
+			filter:  map[string]interface{}{"$or": map[string]interface{}{"a": 1}},
+			wantErr: true,
+		},
+		{
+			name:    "$and vacío",
+			filter:  map[string]interface{}{"$and": []interface{}{}},
+			wantErr: true,
+		},
+		{
+			name:    "condición que no es un objeto",
+			filter:  map[string]interface{}{"$and": []interface{}{"a"}},
+			wantErr: true,
+		},
+		{
+			name: "operador no admitido dentro de $or",
+			filter: map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"$expr": 1}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := translateVectorFilter(tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("se esperaba error y se obtuvo %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("translateVectorFilter() = %#v, se esperaba %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoVectorFilterNamespace(t *testing.T) {
+	got, err := mongoVectorFilter("ns", map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	want := bson.M{"namespace": "ns", "metadata.a": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mongoVectorFilter() = %#v, se esperaba %#v", got, want)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name  string
+		query []float32
+		v     []float32
+		want  float64
+	}{
+		{"idénticos", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"misma dirección", []float32{1, 0}, []float32{5, 0}, 1},
+		{"ortogonales", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opuestos", []float32{1, 1}, []float32{-1, -1}, -1},
+		{"vector nulo", []float32{1, 1}, []float32{0, 0}, 0},
+		{"consulta nula", []float32{0, 0}, []float32{1, 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cosineSimilarity(tt.query, vectorNorm(tt.query), tt.v)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// rankMongoVectors reproduce el recorrido de Query sobre los documentos ya leídos
+func rankMongoVectors(query VectorQuery, docs []mongoVector) ([]domain.Vector, int) {
+	queryNorm := vectorNorm(query.Vector)
+	top := newTopVectors(query.TopK)
+	skipped := 0
+	for _, doc := range docs {
+		match, ok := scoreVector(query, queryNorm, doc)
+		if !ok {
+			skipped++
+			continue
+		}
+		top.add(match)
+	}
+	return top.sorted(), skipped
+}
+
+func TestQueryRanking(t *testing.T) {
+	docs := []mongoVector{
+		{VectorID: "ortogonal", Values: []float32{0, 1}},
+		{VectorID: "exacto", Values: []float32{1, 0}},
+		{VectorID: "cercano", Values: []float32{1, 0.2}},
+		{VectorID: "opuesto", Values: []float32{-1, 0}},
+		{VectorID: "otra-dimension", Values: []float32{1, 0, 0}},
+		{VectorID: "exacto-2", Values: []float32{2, 0}},
+		{VectorID: "lejano", Values: []float32{1, 1}},
+	}
+
+	tests := []struct {
+		name        string
+		topK        int
+		docs        []mongoVector
+		wantIDs     []string
+		wantSkipped int
+	}{
+		{"todos ordenados", 10, docs, []string{"exacto", "exacto-2", "cercano", "lejano", "ortogonal", "opuesto"}, 1},
+		{"topK corta", 3, docs, []string{"exacto", "exacto-2", "cercano"}, 1},
+		{"topK 1 con empate gana el primero", 1, docs, []string{"exacto"}, 1},
+		{"sin documentos", 5, nil, []string{}, 0},
+		{"solo dimensiones distintas", 5, docs[4:5], []string{}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := rankMongoVectors(VectorQuery{Vector: []float32{1, 0}, TopK: tt.topK}, tt.docs)
+			ids := make([]string, len(got))
+			for i, v := range got {
+				ids[i] = v.ID
+				if v.Values != nil {
+					t.Errorf("%s: se devolvieron los valores sin IncludeValues", v.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("orden = %v, se esperaba %v", ids, tt.wantIDs)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("ignorados = %d, se esperaban %d", skipped, tt.wantSkipped)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].Score > got[i-1].Score {
+					t.Errorf("puntuaciones no ordenadas: %v > %v", got[i].Score, got[i-1].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryRankingIncludeValues(t *testing.T) {
+	docs := []mongoVector{{VectorID: "a", Values: []float32{1, 0}}}
+	got, _ := rankMongoVectors(VectorQuery{Vector: []float32{1, 0}, TopK: 1, IncludeValues: true}, docs)
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Values, []float32{1, 0}) {
+		t.Errorf("se esperaban los valores del vector, se obtuvo %+v", got)
+	}
+}
+
+func TestTopVectorsMatchesFullSort(t *testing.T) {
+	scores := []float64{0.3, 0.9, 0.1, 0.9, 0.5, 0.7, 0.2, 0.7, 0.8, 0.4}
+	want := map[int][]string{
+		1:  {"1"},
+		4:  {"1", "3", "8", "5"},
+		10: {"1", "3", "8", "5", "7", "4", "9", "0", "6", "2"},
+	}
+	for k, wantIDs := range want {
+		top := newTopVectors(k)
+		for i, score := range scores {
+			top.add(domain.Vector{ID: string(rune('0' + i)), Score: score})
+		}
+		got := top.sorted()
+		ids := make([]string, len(got))
+		for i, v := range got {
+			ids[i] = v.ID
+		}
+		if !reflect.DeepEqual(ids, wantIDs) {
+			t.Errorf("k=%d: orden = %v, se esperaba %v", k, ids, wantIDs)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"opo_admin_server/internal/domain"
+	"opo_admin_server/internal/logging"
+	"opo_admin_server/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// vectorUpsertBatch es el número de vectores por petición de upsert (Pinecone limita el tamaño)
+const vectorUpsertBatch = 100
+
+// DocumentNamespace devuelve el namespace del vector store con los chunks de un documento
+func DocumentNamespace(documentID string) string {
+	return fmt.Sprintf("document-%s", documentID)
+}
+
+// vectorize divide el texto del documento en chunks, genera sus embeddings y los guarda en el vector store
+func (q *JobQueue) vectorize(ctx context.Context, db *mongo.Database, job *domain.Job) (*domain.ProcessVectorResponse, error) {
+	jobs := db.Collection(JobsCollection)
+	documents := db.Collection("documents")
+
+	dbCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var document domain.Document
+	if err := documents.FindOne(dbCtx, bson.M{"_id": job.DocumentID}).Decode(&document); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, permanent(fmt.Errorf("documento no encontrado"))
+		}
+		return nil, err
+	}
+	if document.Status == "deleting" {
+		return nil, permanent(fmt.Errorf("el documento se está borrando"))
+	}
+	if document.Text == "" {
+		return nil, permanent(fmt.Errorf("el documento no tiene texto para procesar"))
+	}
+	store, err := NewVectorStore(q.cfg, db)
+	if err != nil {
+		return nil, permanent(err)
+	}
+
+	embeddingConfig := q.jobAPIKeys(job)
+	if embeddingConfig.EmbeddingModel != "huggingface" && embeddingConfig.OpenAIAPIKey == "" {
+		return nil, permanent(fmt.Errorf("API key de OpenAI no disponible (el servidor se reinició y no hay OPENAI_API_KEY): vuelve a lanzar el procesamiento"))
+	}
+
+	started, err := documents.UpdateOne(dbCtx, bson.M{"_id": job.DocumentID, "status": bson.M{"$ne": "deleting"}}, bson.M{
+		"$set":   bson.M{"status": "processing", "jobId": job.ID, "updatedAt": time.Now()},
+		"$unset": bson.M{"error": ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if started.MatchedCount == 0 {
+		return nil, permanent(fmt.Errorf("el documento se ha borrado"))
+	}
+
+	// Dividir texto en chunks
+	if err := reportProgress(ctx, jobs, job.ID, domain.JobProgress{Stage: "chunking"}); err != nil {
+		return nil, err
+	}
+	_, chunkSpan := tracing.Start(ctx, "ia_works.chunk_text", trace.SpanKindInternal,
+		attribute.String("document.id", job.DocumentID), attribute.Int("text.length", len(document.Text)),
+		attribute.String("chunk.strategy", embeddingConfig.ChunkingStrategy))
+	chunks, err := ChunkText(document.Text, embeddingConfig)
+	chunkSpan.SetAttributes(attribute.Int("chunk.count", len(chunks)))
+	tracing.End(chunkSpan, err)
+	if err != nil {
+		return nil, permanent(fmt.Errorf("error al dividir texto: %w", err))
+	}
+
+	// Generar embeddings
+	embeddings, err := GenerateEmbeddingsWithProgress(ctx, chunks, embeddingConfig, func(done int) error {
+		return reportProgress(ctx, jobs, job.ID, domain.JobProgress{Stage: "embedding", Done: done, Total: len(chunks)})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Preparar vectores para el vector store
+	vectors := make([]domain.Vector, len(chunks))
+	for i, chunk := range chunks {
+		metadata := map[string]interface{}{
+			"documentId": job.DocumentID,
+			"fileName":   document.FileName,
+			"chunkIndex": i,
+			"text":       chunk,
+			"createdAt":  time.Now().Format(time.RFC3339),
+		}
+		// Agregar metadata personalizada si existe
+		for k, v := range embeddingConfig.Metadata {
+			metadata[k] = v
+		}
+
+		vectors[i] = domain.Vector{
+			ID:         fmt.Sprintf("%s-chunk-%d", job.DocumentID, i),
+			Values:     embeddings[i],
+			Metadata:   metadata,
+			DocumentID: job.DocumentID,
+			ChunkIndex: i,
+			Text:       chunk,
+		}
+	}
+
+	// Guardar en el vector store por lotes. Antes se borran los vectores de un procesamiento
+	// anterior para que no queden chunks sobrantes si el texto ha cambiado
+	namespace := DocumentNamespace(job.DocumentID)
+	if err := store.DeleteNamespace(ctx, namespace); err != nil {
+		return nil, fmt.Errorf("error al borrar los vectores anteriores: %w", err)
+	}
+	for first := 0; first < len(vectors); first += vectorUpsertBatch {
+		last := min(first+vectorUpsertBatch, len(vectors))
+		if err := store.Upsert(ctx, namespace, vectors[first:last]); err != nil {
+			return nil, fmt.Errorf("error al guardar en el vector store: %w", err)
+		}
+		if err := reportProgress(ctx, jobs, job.ID, domain.JobProgress{Stage: "storing", Done: last, Total: len(vectors)}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Actualizar estado del documento
+	updateCtx, cancelUpdate := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancelUpdate()
+	if _, err := documents.UpdateOne(updateCtx, bson.M{"_id": job.DocumentID, "status": bson.M{"$ne": "deleting"}}, bson.M{
+		"$set": bson.M{"status": "processed", "chunksCount": len(chunks), "updatedAt": time.Now()},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &domain.ProcessVectorResponse{
+		JobID:       job.ID,
+		VectorID:    fmt.Sprintf("vector-%s", job.DocumentID),
+		Status:      "processed",
+		ChunksCount: len(chunks),
+	}, nil
+}
+
+// markDocumentNotProcessed refleja en el documento que su vectorización ha fallado o se ha cancelado
+func markDocumentNotProcessed(ctx context.Context, db *mongo.Database, job *domain.Job, status string, jobErr error) {
+	set := bson.M{"status": "error", "updatedAt": time.Now()}
+	if status == domain.JobStatusCanceled {
+		set["status"] = "uploaded"
+	}
+	if jobErr != nil {
+		set["error"] = jobErr.Error()
+	}
+	// Solo si el documento no se ha vuelto a procesar con otro trabajo ni se está borrando
+	db.Collection("documents").UpdateOne(ctx, bson.M{"_id": job.DocumentID, "jobId": job.ID, "status": bson.M{"$ne": "deleting"}}, bson.M{"$set": set})
+}
+
+// removeVectorsOfDeletedDocument vuelve a borrar el namespace si el documento se ha borrado (o
+// se está borrando) mientras el trabajo se ejecutaba: el trabajo pudo guardar vectores después
+// de que AdminIAWorksDocumentDelete vaciara el namespace
+func (q *JobQueue) removeVectorsOfDeletedDocument(ctx context.Context, db *mongo.Database, job *domain.Job) {
+	err := db.Collection("documents").FindOne(ctx, bson.M{"_id": job.DocumentID, "status": bson.M{"$ne": "deleting"}},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		logging.Printf(ctx, "⚠️ Trabajo %s - No se pudo comprobar si el documento %s sigue existiendo: %v", job.ID, job.DocumentID, err)
+		return
+	}
+
+	store, err := NewVectorStore(q.cfg, db)
+	if err == nil {
+		err = store.DeleteNamespace(ctx, DocumentNamespace(job.DocumentID))
+	}
+	if err != nil {
+		logging.Printf(ctx, "❌ Trabajo %s - Error borrando los vectores del documento eliminado %s: %v", job.ID, job.DocumentID, err)
+		return
+	}
+	logging.Printf(ctx, "🗑️ Trabajo %s - Vectores del documento eliminado %s borrados", job.ID, job.DocumentID)
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MongoMonitor devuelve un monitor de comandos que abre un span por cada comando enviado a
+// MongoDB (hijo del span del contexto de la operación) y después delega en next (puede ser nil).
+// Usa los mismos atributos que otelmongo, pero permite encadenar el monitor de métricas.
+func MongoMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	var spans sync.Map
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if Enabled() {
+				attrs := []attribute.KeyValue{
+					attribute.String("db.system", "mongodb"),
+					attribute.String("db.name", e.DatabaseName),
+					attribute.String("db.operation", e.CommandName),
+				}
+				if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+					attrs = append(attrs, attribute.String("db.mongodb.collection", collection))
+				}
+				_, span := Start(ctx, "mongodb."+e.CommandName, trace.SpanKindClient, attrs...)
+				spans.Store(e.RequestID, span)
+			}
+			if next != nil && next.Started != nil {
+				next.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				span.(trace.Span).End()
+			}
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				span.(trace.Span).SetStatus(codes.Error, e.Failure)
+				span.(trace.Span).End()
+			}
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, e)
+			}
+		},
+	}
+}
@@ -0,0 +1,124 @@
+// Package tracing configura OpenTelemetry: proveedor de trazas con exportador OTLP/HTTP o
+// stdout, propagación W3C (traceparent) y el tracer con el que el servidor abre sus spans.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"opo_admin_server/internal/config"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifica los spans propios del servidor
+const instrumentationName = "opo_admin_server"
+
+var enabled atomic.Bool
+
+// Setup configura el exportador según OTEL_TRACES_EXPORTER (none, otlp o stdout). Devuelve la
+// función que envía los spans pendientes al apagar el servidor.
+func Setup(cfg config.Config) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("⚠️ Trazas - Error de OpenTelemetry", "error", err)
+	}))
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		slog.Error("❌ Trazas - No se pudo crear el exportador, trazas desactivadas", "exporter", cfg.OTelTracesExporter, "error", err)
+		return func(context.Context) error { return nil }
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+
+	// El muestreo por defecto (parentbased_always_on, configurable con OTEL_TRACES_SAMPLER)
+	// respeta el flag sampled del traceparent recibido
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.OTelServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	enabled.Store(true)
+
+	return provider.Shutdown
+}
+
+// newExporter crea el exportador configurado (nil si las trazas están desactivadas)
+func newExporter(cfg config.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.OTelTracesExporter {
+	case "otlp":
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpointURL(strings.TrimRight(cfg.OTelExporterEndpoint, "/") + "/v1/traces"),
+		}
+		if headers := parseHeaders(cfg.OTelExporterHeaders); len(headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(headers))
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case "stdout", "console":
+		return stdouttrace.New()
+	}
+	return nil, nil
+}
+
+// parseHeaders convierte las cabeceras "clave=valor" de OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(list []string) map[string]string {
+	headers := map[string]string{}
+	for _, h := range list {
+		if key, value, ok := strings.Cut(h, "="); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+// Enabled indica si hay un exportador configurado
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start abre un span hijo del span del contexto (o raíz si no hay) y lo guarda en el contexto
+// devuelto. Con las trazas desactivadas el span no registra nada.
+func Start(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// End marca el span como fallido si err no es nil y lo cierra
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID devuelve el trace id del span activo en hexadecimal (vacío si no hay)
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Transport instrumenta las llamadas HTTP salientes: abre un span de cliente por petición y
+// envía la cabecera traceparent al servicio externo. base nil usa http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -60,6 +60,7 @@ func main() {
 		"email":     email,
 		"password":  string(hash),
 		"appId":     appId,
+		"role":      "superadmin",
 		"lastLogin": now,
 		"createdAt": now,
 		"updatedAt": now,
@@ -68,8 +69,8 @@ func main() {
 	// Insertar en la colección user
 	collection := client.Database(cfg.DBName).Collection("user")
 
-	// Eliminar usuario existente si existe
-	_, err = collection.DeleteMany(ctx, bson.M{})
+	// Eliminar el administrador con el mismo email si existe (el resto se conserva)
+	_, err = collection.DeleteMany(ctx, bson.M{"email": email})
 	if err != nil {
 		log.Printf("Advertencia: Error eliminando usuario existente: %v", err)
 	}
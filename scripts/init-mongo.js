@@ -45,6 +45,42 @@ db.topics_uuid_map.createIndex({ "area": 1 });
 db.topics_uuid_map.createIndex({ "enabled": 1 });
 db.topics_uuid_map.createIndex({ "rootId": 1 });
 db.topics_uuid_map.createIndex({ "area": 1, "enabled": 1 });
+db.topics_uuid_map.createIndex({ "area": 1, "status": 1 });
+db.admin_sessions.createIndex({ "tokenHash": 1 }, { unique: true });
+db.admin_sessions.createIndex({ "userId": 1 });
+db.admin_sessions.createIndex({ "familyId": 1 });
+db.admin_sessions.createIndex({ "expiresAt": 1 }, { expireAfterSeconds: 0 });
+db.admin_login_challenges.createIndex({ "expiresAt": 1 }, { expireAfterSeconds: 0 });
+db.login_attempts.createIndex({ "expiresAt": 1 }, { expireAfterSeconds: 0 });
+db.api_keys.createIndex({ "keyHash": 1 }, { unique: true });
+db.oidc_states.createIndex({ "expiresAt": 1 }, { expireAfterSeconds: 0 });
+db.audit_log.createIndex({ "timestamp": -1 });
+db.audit_log.createIndex({ "actorId": 1, "timestamp": -1 });
+db.audit_log.createIndex({ "actorEmail": 1, "timestamp": -1 });
+db.audit_log.createIndex({ "entityType": 1, "entityId": 1, "timestamp": -1 });
+db.jobs.createIndex({ "status": 1, "runAfter": 1 });
+db.jobs.createIndex({ "documentId": 1, "status": 1 });
+// Un solo trabajo pendiente o en curso por documento (requiere MongoDB 6.0+ por el $in)
+db.jobs.createIndex(
+  { "documentId": 1 },
+  { unique: true, name: "documentId_active_unique", partialFilterExpression: { "status": { "$in": ["queued", "running"] } } }
+);
+db.jobs.createIndex({ "createdAt": -1 });
+// Los trabajos terminados se borran a los 30 días
+db.jobs.createIndex({ "finishedAt": 1 }, { expireAfterSeconds: 2592000 });
+db.documents.createIndex({ "createdAt": -1 });
+db.documents.createIndex({ "status": 1, "createdAt": -1 });
+db.documents.createIndex({ "fileType": 1, "createdAt": -1 });
+// Vector store local (VECTOR_STORE=mongo)
+db.vectors.createIndex({ "namespace": 1 });
+
+// Una base de datos nueva no tiene topics que migrar: se registra la migración de status como
+// aplicada (es la que comprueba /readyz) para no tener que ejecutar migrate-topic-status
+db.migrations.updateOne(
+  { "_id": "topic-status-v1" },
+  { "$setOnInsert": { "appliedAt": new Date(), "inverted": false } },
+  { upsert: true }
+);
 
 print('✅ Base de datos inicializada correctamente');
 print('📊 Colecciones creadas: user, topics_uuid_map, apps');
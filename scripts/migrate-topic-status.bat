@@ -0,0 +1,27 @@
+@echo off
+echo ========================================
+echo   Migrar topics al campo 'status'
+echo ========================================
+echo.
+echo Este script asignara 'status' (active/hidden)
+echo a todos los topics que no lo tengan, a partir
+echo del campo 'enabled'.
+echo.
+echo Usa --inverted si tus datos siguen la logica
+echo invertida (enabled: false = habilitado).
+echo.
+echo Presiona Ctrl+C para cancelar o
+pause
+
+echo.
+echo Ejecutando script...
+echo.
+
+cd /d "%~dp0\.."
+node scripts/migrate-topic-status.js %*
+
+echo.
+echo ========================================
+echo   Script completado
+echo ========================================
+pause
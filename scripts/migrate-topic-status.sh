@@ -0,0 +1,29 @@
+#!/bin/bash
+
+echo "========================================"
+echo "  Migrar topics al campo 'status'"
+echo "========================================"
+echo ""
+echo "Este script asignara 'status' (active/hidden)"
+echo "a todos los topics que no lo tengan, a partir"
+echo "del campo 'enabled'."
+echo ""
+echo "Usa --inverted si tus datos siguen la logica"
+echo "invertida (enabled: false = habilitado)."
+echo ""
+read -p "Presiona Enter para continuar o Ctrl+C para cancelar..."
+
+echo ""
+echo "Ejecutando script..."
+echo ""
+
+# Cambiar al directorio raíz del proyecto
+cd "$(dirname "$0")/.."
+
+# Ejecutar el script de Node.js
+node scripts/migrate-topic-status.js "$@"
+
+echo ""
+echo "========================================"
+echo "  Script completado"
+echo "========================================"